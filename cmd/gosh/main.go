@@ -8,14 +8,14 @@ import (
 )
 
 func main() {
-	app, err := app.NewApp()
+	gosh, err := app.NewApp()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := app.Run(os.Args[1:]); err != nil {
+	if err := gosh.Run(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(app.ExitCode(err))
 	}
 }
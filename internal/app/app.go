@@ -1,10 +1,22 @@
 package app
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gosh/internal/auth"
@@ -17,6 +29,9 @@ import (
 	"github.com/mattn/go-isatty"
 )
 
+// ExitNoMatch is the exit status when --filter matches nothing
+const ExitNoMatch = 4
+
 // App is the main application
 type App struct {
 	workspace *config.Workspace
@@ -40,6 +55,10 @@ func NewApp() (*App, error) {
 		return nil, err
 	}
 
+	if err := workspace.CheckDefaultEnvironment(global.DefaultEnvironment); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; ignoring it\n", err)
+	}
+
 	// Create storage manager
 	storageMgr := storage.NewManager(workspace.Root)
 
@@ -49,6 +68,17 @@ func NewApp() (*App, error) {
 		return nil, err
 	}
 
+	// User-level presets apply in every workspace
+	configDir, err := config.GlobalConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	globalAuth := auth.NewGlobalManager(configDir)
+	if err := globalAuth.Load(); err != nil {
+		return nil, err
+	}
+	authMgr.SetGlobal(globalAuth)
+
 	// Check if stdout is a TTY
 	isTTY := isTerminal(os.Stdout)
 
@@ -76,6 +106,8 @@ func (a *App) Run(args []string) error {
 		return a.executeRecall(v)
 	case *cli.AuthCommand:
 		return a.handleAuthCommand(v)
+	case *cli.EnvCommand:
+		return a.handleEnvCommand(v)
 	case string:
 		switch v {
 		case "version":
@@ -97,6 +129,11 @@ func (a *App) Run(args []string) error {
 
 // executeRequest executes an HTTP request
 func (a *App) executeRequest(req *cli.ParsedRequest) error {
+	// Merge the selected environment into the workspace variables
+	if err := a.selectEnvironment(req.Env); err != nil {
+		return err
+	}
+
 	// Apply default headers from workspace config
 	if a.workspace.Config != nil && len(a.workspace.Config.DefaultHeaders) > 0 {
 		// CLI headers override config defaults
@@ -107,23 +144,23 @@ func (a *App) executeRequest(req *cli.ParsedRequest) error {
 		}
 	}
 
-	// Check for stdin body
-	if req.HasStdinBody || !isTerminal(os.Stdin) {
-		// Read from stdin
-		stdinData, err := io.ReadAll(os.Stdin)
-		if err == nil && len(stdinData) > 0 {
-			req.Body = string(stdinData)
-			req.HasStdinBody = true
-		}
-	}
-
-	// Resolve environment variables in all parts
+	// Resolve environment variables in all parts; the body is resolved once
+	// stdin has been read
 	req.URL = a.substituteEnvVars(req.URL)
 	req.Headers = a.substituteEnvVarsInMap(req.Headers)
-	req.Body = a.substituteEnvVars(req.Body)
+	for i := range req.Items {
+		req.Items[i].Value = a.substituteEnvVars(req.Items[i].Value)
+	}
+
+	// Resolve relative paths against the workspace baseUrl.
+	// Saved calls keep the relative path so they work across environments.
+	requestURL, err := request.JoinURL(a.substituteEnvVars(a.workspace.BaseURL()), req.URL)
+	if err != nil {
+		return err
+	}
 
 	// Resolve template variables in URL
-	tmpl := request.NewTemplate(req.URL)
+	tmpl := request.NewTemplate(requestURL)
 	pathVars := tmpl.ExtractPathVars()
 
 	resolvedPathVars := make(map[string]string)
@@ -136,7 +173,6 @@ func (a *App) executeRequest(req *cli.ParsedRequest) error {
 				val = cliVal
 			} else if !req.NoInteractive {
 				// Prompt for missing path variables
-				var err error
 				val, err = ui.PromptForVariable(varName)
 				if err != nil {
 					return err
@@ -168,47 +204,83 @@ func (a *App) executeRequest(req *cli.ParsedRequest) error {
 		}
 	}
 
-	// Build request
-	httpReq := &request.Request{
-		Method:      req.Method,
-		URL:         resolvedURL,
-		Headers:     req.Headers,
-		QueryParams: req.QueryParams,
-		Body:        req.Body,
-		Timeout:     timeout,
+	// If dry run, just save. The auth preset is saved by name and never
+	// unlocked or resolved.
+	if req.Dry {
+		if req.Save == "" {
+			return fmt.Errorf("--dry requires --save to specify a name")
+		}
+		a.readStdinBody(req)
+		req.Body = a.substituteEnvVars(req.Body)
+		return a.saveCall(req)
 	}
 
-	// Apply authentication if provided
-	if req.Auth != "" {
-		authPreset, err := a.authMgr.Get(req.Auth)
-		if err != nil {
+	// Apply authentication if provided, or the workspace default for the
+	// host. Encrypted presets are unlocked before stdin is read, so a
+	// passphrase prompt can't consume the body.
+	var authPreset *auth.AuthPreset
+	var defaultRule *config.AuthRule
+	authName := req.Auth
+	if authName == "" && !req.NoAuth {
+		if u, err := url.Parse(resolvedURL); err == nil {
+			if defaultRule = a.workspace.DefaultAuthRule(u.Host); defaultRule != nil {
+				authName = defaultRule.Auth
+			}
+		}
+	}
+	if authName != "" {
+		if err := a.unlockPreset(authName); err != nil {
+			return err
+		}
+		if defaultRule != nil {
+			if err := a.confirmDefaultAuth(defaultRule, resolvedURL); err != nil {
+				return err
+			}
+		}
+		if authPreset, err = a.authMgr.Get(authName); err != nil {
 			return fmt.Errorf("authentication failed: %w", err)
 		}
-		httpReq.Auth = authPreset
 	}
 
-	// If dry run, just save
-	if req.Dry {
-		if req.Save == "" {
-			return fmt.Errorf("--dry requires --save to specify a name")
-		}
-		savedCall := storage.NewSavedCall(
-			req.Save,
-			req.Method,
-			req.URL,
-			req.Headers,
-			req.QueryParams,
-			req.Body,
-		)
-		if err := a.storage.Save(savedCall); err != nil {
-			return err
+	a.readStdinBody(req)
+	req.Body = a.substituteEnvVars(req.Body)
+
+	// Build request
+	httpReq := &request.Request{
+		Method:       req.Method,
+		URL:          resolvedURL,
+		Headers:      req.Headers,
+		QueryParams:  req.QueryParams,
+		Body:         req.Body,
+		Items:        req.Items,
+		Form:         req.Form,
+		Multipart:    req.Multipart,
+		Timeout:      timeout,
+		NoFollow:     req.NoFollow,
+		MaxRedirects: req.MaxRedirects,
+		Auth:         authPreset,
+	}
+
+	httpReq.TLS = a.tlsOptions(req)
+	httpReq.Proxy = a.proxyOptions(req)
+	if httpReq.Retry, err = a.retryPolicy(req); err != nil {
+		return err
+	}
+
+	// Dump the exchange on stderr so stdout stays pipeable
+	if req.Verbose {
+		var redactor *output.Redactor
+		if !req.ShowSecrets {
+			redactor = output.NewRedactor(httpReq.Auth)
 		}
-		fmt.Printf("Saved call: %s\n", req.Save)
-		return nil
+		httpReq.Wire = output.NewWireDumper(os.Stderr, redactor, httpReq.Multipart)
 	}
 
 	// Execute request
 	executor := request.NewExecutor(timeout)
+	if req.Download || req.Stream || (acceptsStream(httpReq.Headers) && !needsFullBody(req)) {
+		return a.executeStreaming(executor, httpReq, req)
+	}
 	resp, err := executor.Execute(httpReq)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
@@ -216,28 +288,184 @@ func (a *App) executeRequest(req *cli.ParsedRequest) error {
 
 	// Save if requested
 	if req.Save != "" {
-		savedCall := storage.NewSavedCall(
-			req.Save,
-			req.Method,
-			req.URL,
-			req.Headers,
-			req.QueryParams,
-			req.Body,
-		)
-		if err := a.storage.Save(savedCall); err != nil {
+		if err := a.saveCall(req); err != nil {
 			return err
 		}
-		fmt.Printf("Saved call: %s\n", req.Save)
 	}
 
 	// Format and output response
-	formatter := output.NewFormatter(a.isTTY)
-	output := formatter.FormatResponse(resp, req.Info)
+	formatter := a.newFormatter()
+	if !req.ShowSecrets {
+		formatter.SetRedactor(output.NewRedactor(httpReq.Auth))
+	}
+	if req.Filter != "" {
+		filter, err := output.ParseFilter(req.Filter)
+		if err != nil {
+			return err
+		}
+		results, err := formatter.FilterResponse(resp, req.Format, filter)
+		if err != nil {
+			return err
+		}
+		fmt.Print(results)
+		return nil
+	}
+
+	output, err := formatter.Render(resp, req.Format, req.Info)
+	if err != nil {
+		return err
+	}
 	fmt.Print(output)
 
 	return nil
 }
 
+// saveCall saves the request under req.Save
+func (a *App) saveCall(req *cli.ParsedRequest) error {
+	savedCall := storage.NewSavedCall(
+		req.Save,
+		req.Method,
+		req.URL,
+		req.Headers,
+		req.QueryParams,
+		req.Body,
+	)
+	for _, item := range req.Items {
+		savedCall.Items = append(savedCall.Items, item.String())
+	}
+	savedCall.Form = req.Form
+	savedCall.Multipart = req.Multipart
+	savedCall.Auth = req.Auth
+
+	if err := a.storage.Save(savedCall); err != nil {
+		return err
+	}
+
+	// Keep stdout clean for machine-readable formats
+	out := os.Stdout
+	if req.Format != "" && req.Format != output.FormatText {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Saved call: %s\n", req.Save)
+	return nil
+}
+
+// executeStreaming sends the request and writes the body to a file or to
+// stdout as it arrives, instead of buffering it in memory
+func (a *App) executeStreaming(executor *request.Executor, httpReq *request.Request, req *cli.ParsedRequest) error {
+	// Copy headers so the Range header isn't saved with the call
+	headers := make(map[string]string, len(httpReq.Headers)+1)
+	for key, val := range httpReq.Headers {
+		headers[key] = val
+	}
+	httpReq.Headers = headers
+
+	var offset int64
+	if req.Continue {
+		var err error
+		offset, err = output.ResumeOffset(req.Output)
+		if err != nil {
+			return err
+		}
+		if offset > 0 {
+			headers["Range"] = fmt.Sprintf("bytes=%d-", offset)
+		}
+	}
+
+	resp, body, err := executor.Open(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer body.Close()
+
+	// Save if requested
+	if req.Save != "" {
+		if err := a.saveCall(req); err != nil {
+			return err
+		}
+	}
+
+	formatter := a.newFormatter()
+	if !req.Download {
+		switch req.Format {
+		case output.FormatRaw:
+			return formatter.StreamBody(os.Stdout, body)
+		case output.FormatHeaders:
+			fmt.Print(formatter.FormatHeaders(resp))
+			return nil
+		case output.FormatBody:
+		default:
+			fmt.Print(formatter.FormatResponse(resp, req.Info))
+			fmt.Println()
+		}
+
+		switch {
+		case output.IsEventStream(resp.Headers):
+			events := request.NewEventStream(executor, httpReq, body)
+			defer events.Close()
+			return formatter.StreamEvents(os.Stdout, events)
+		case output.IsNDJSON(resp.Headers):
+			return formatter.StreamNDJSON(os.Stdout, body)
+		default:
+			return formatter.StreamBody(os.Stdout, body)
+		}
+	}
+
+	// Error responses are shown rather than saved to the file
+	if resp.StatusCode >= 400 && !(resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0) {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		resp.Body = data
+		resp.Size = len(data)
+		fmt.Print(formatter.FormatResponse(resp, req.Info))
+		return nil
+	}
+
+	fmt.Print(formatter.FormatResponse(resp, req.Info))
+
+	// The server rejects a range starting at the end of a complete file
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		fmt.Printf("Already downloaded: %s\n", req.Output)
+		return nil
+	}
+
+	path := req.Output
+	if path == "" {
+		path = output.AvailableFilename(output.DownloadFilename(resp.Headers, httpReq.URL))
+	}
+
+	resume := offset > 0 && resp.StatusCode == http.StatusPartialContent
+	if offset > 0 && !resume {
+		fmt.Println("Server does not support resuming; restarting download")
+		offset = 0
+	}
+
+	total := int64(-1)
+	if length, err := strconv.ParseInt(http.Header(resp.Headers).Get("Content-Length"), 10, 64); err == nil {
+		total = offset + length
+	}
+
+	var progress *ui.Progress
+	var progressWriter io.Writer
+	if isTerminal(os.Stderr) {
+		progress = ui.StartProgress(os.Stderr, filepath.Base(path), total, offset)
+		progressWriter = progress
+	}
+
+	written, err := output.WriteDownload(path, body, resume, progressWriter)
+	if progress != nil {
+		progress.Finish()
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Downloaded %s to %s\n", ui.FormatBytes(offset+written), path)
+	return nil
+}
+
 // executeRecall executes a saved call
 func (a *App) executeRecall(opts *cli.RecallOptions) error {
 	// Load saved call
@@ -246,21 +474,58 @@ func (a *App) executeRecall(opts *cli.RecallOptions) error {
 		return err
 	}
 
-	// Apply overrides
-	for _, val := range opts.ParameterOverride {
-		savedCall.Body = val // Simplified; should handle proper merging
-	}
-	for key, val := range opts.Headers {
-		savedCall.Headers[key] = val
-	}
-
 	// Create a ParsedRequest from saved call
 	req := &cli.ParsedRequest{
 		Method:      savedCall.Method,
 		URL:         savedCall.URL,
-		Headers:     savedCall.Headers,
-		QueryParams: savedCall.QueryParams,
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
 		Body:        savedCall.Body,
+		PathParams:  make(map[string]string),
+		Form:        savedCall.Form,
+		Multipart:   savedCall.Multipart,
+		Auth:        savedCall.Auth,
+		Env:         opts.Env,
+		Info:        opts.Info,
+		Filter:      opts.Filter,
+		Verbose:     opts.Verbose,
+		ShowSecrets: opts.ShowSecrets,
+	}
+	for key, val := range savedCall.Headers {
+		req.Headers[key] = val
+	}
+	for key, val := range savedCall.QueryParams {
+		req.QueryParams[key] = val
+	}
+	for _, raw := range savedCall.Items {
+		item, ok := request.ParseItem(raw)
+		if !ok {
+			return fmt.Errorf("invalid item in saved call %s: %s", savedCall.Name, raw)
+		}
+		req.Items = append(req.Items, item)
+	}
+
+	// Apply overrides; later items win over saved ones
+	pathVars := make(map[string]bool)
+	for _, name := range request.NewTemplate(savedCall.URL).ExtractPathVars() {
+		pathVars[name] = true
+	}
+	for key, val := range opts.ParameterOverride {
+		if pathVars[key] {
+			req.PathParams[key] = val
+		} else {
+			req.Items = append(req.Items, request.Item{Type: request.ItemData, Key: key, Value: val})
+		}
+	}
+	for key, val := range opts.PathParams {
+		req.PathParams[key] = val
+	}
+	for key, val := range opts.QueryParams {
+		req.QueryParams[key] = val
+	}
+	req.Items = append(req.Items, opts.Items...)
+	for key, val := range opts.Headers {
+		req.Headers[key] = val
 	}
 
 	return a.executeRequest(req)
@@ -314,37 +579,212 @@ Commands:
   gosh recall <name>      Execute a saved call
   gosh list              List all saved calls
   gosh delete <name>     Delete a saved call
+  gosh env list          List configured environments
+  gosh env show [name]   Show variables for an environment
+  gosh env use <name>    Set the active environment
 
 Options:
   -H KEY:VALUE           Add a header
   -d DATA                Request body data
+  -f, --form             Send data items as form fields
+  --multipart            Send data items as multipart/form-data
   --save NAME            Save the request
   --dry                  Parse without executing
   --info                 Show full response info
+  -v, --verbose          Print each request and response header on stderr,
+                         with credentials redacted
+  --show-secrets         Don't redact credentials in --verbose or HAR output
   --no-interactive       Don't prompt for variables
   --env ENVIRONMENT      Use specific environment
-  --format FORMAT        Output format (json|raw)
+  --format FORMAT        Output format: text (default), raw, body, headers,
+                         json or har
+  --download             Save the response body to a file
+  -o, --output FILE      File to download to (implies --download)
+  --continue             Resume a partial download (requires --output)
+  --stream               Print the response body as it arrives; server-sent
+                         events and NDJSON are pretty-printed per record
+  --filter EXPR          Print only the parts of a JSON response matching a
+                         JSONPath ($.items[*].id) or jq (.items[].id) expression
+
+Request Items:
+  name=value             JSON string field (nested: user[address][city]=X, tags[]=a)
+  name:=json             Raw JSON field (age:=42, admin:=true)
+  name=@file             JSON string field read from a file
+  name:=@file            Raw JSON field read from a file
+  name@file              Embed a file's contents (file upload with --multipart;
+                         set the part type with name@file;type=image/png)
+  name==value            Query parameter
+  {name}=value           Path variable (name=value also works when the URL has {name})
 
 Examples:
   gosh get https://api.example.com/users
   gosh post https://api.example.com/users -d '{"name":"John"}' -H Authorization:"Bearer xyz"
   gosh get https://api.example.com/users/{userId}
+  gosh get /users/{userId}  (resolved against baseUrl in .gosh.yaml)
+  gosh post https://api.example.com/users name=John age:=30 tags[]=admin
   gosh recall my-request userId=42
 `
 	fmt.Print(help)
 	return nil
 }
 
+// newFormatter creates a response formatter using the global theme. Colors
+// are disabled when stdout isn't a terminal or NO_COLOR is set.
+func (a *App) newFormatter() *output.Formatter {
+	formatter := output.NewFormatter(a.isTTY && os.Getenv("NO_COLOR") == "")
+	if a.global != nil {
+		theme, err := output.LookupTheme(a.global.Theme)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			formatter.SetTheme(theme)
+		}
+	}
+	return formatter
+}
+
+// acceptsStream reports whether the Accept header asks for server-sent
+// events or NDJSON, which are streamed without --stream
+func acceptsStream(headers map[string]string) bool {
+	for key, val := range headers {
+		if strings.EqualFold(key, "Accept") && request.IsStreamContentType(val) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsFullBody reports whether the output needs the whole body, so the
+// response can't be streamed
+func needsFullBody(req *cli.ParsedRequest) bool {
+	return req.Filter != "" || req.Format == output.FormatJSON || req.Format == output.FormatHAR
+}
+
+// ExitCode returns the process exit status for an error returned by Run
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, output.ErrNoMatch):
+		return ExitNoMatch
+	default:
+		return 1
+	}
+}
+
 // isTerminal checks if a file is a terminal
 func isTerminal(f *os.File) bool {
 	return isatty.IsTerminal(f.Fd())
 }
 
+// tlsOptions merges the workspace TLS settings for the selected environment
+// with the request's flags. Workspace paths are relative to its root.
+func (a *App) tlsOptions(req *cli.ParsedRequest) *request.TLSOptions {
+	ws := a.workspace.TLS()
+	workspacePath := func(path string) string {
+		path = a.substituteEnvVars(path)
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(a.workspace.Root, path)
+	}
+
+	opts := &request.TLSOptions{
+		CertFile:     workspacePath(ws.CertFile),
+		KeyFile:      workspacePath(ws.KeyFile),
+		CertPassword: a.substituteEnvVars(ws.CertPassword),
+		Insecure:     ws.Insecure || req.Insecure,
+		MinVersion:   ws.MinVersion,
+		ServerName:   ws.ServerName,
+	}
+	for _, file := range ws.CAFiles {
+		opts.CAFiles = append(opts.CAFiles, workspacePath(file))
+	}
+
+	if req.CertFile != "" {
+		opts.CertFile = req.CertFile
+		opts.KeyFile = req.KeyFile
+		opts.CertPassword = req.CertPassword
+	}
+	opts.CAFiles = append(opts.CAFiles, req.CACerts...)
+	if req.TLSMin != "" {
+		opts.MinVersion = req.TLSMin
+	}
+	if req.SNI != "" {
+		opts.ServerName = req.SNI
+	}
+	return opts
+}
+
+// proxyOptions merges the workspace proxy settings for the selected
+// environment with the request's flags. It returns nil when neither sets
+// anything, so the proxy environment variables apply.
+func (a *App) proxyOptions(req *cli.ParsedRequest) *request.ProxyOptions {
+	ws := a.workspace.Proxy()
+	opts := &request.ProxyOptions{
+		URL:     a.substituteEnvVars(ws.URL),
+		NoProxy: append(append([]string{}, ws.NoProxy...), req.NoProxy...),
+	}
+	if req.Proxy != "" {
+		opts.URL = req.Proxy
+	}
+	if opts.URL == "" && len(opts.NoProxy) == 0 {
+		return nil
+	}
+	return opts
+}
+
+// retryPolicy combines the workspace retry block with --retry. It returns
+// nil when requests are sent once.
+func (a *App) retryPolicy(req *cli.ParsedRequest) (*request.RetryPolicy, error) {
+	if req.NoRetry {
+		return nil, nil
+	}
+	var ws config.RetryConfig
+	if a.workspace.Config != nil && a.workspace.Config.Retry != nil {
+		ws = *a.workspace.Config.Retry
+	}
+	if req.Retry > 0 {
+		ws.Attempts = req.Retry
+	}
+	if ws.Attempts <= 0 {
+		return nil, nil
+	}
+
+	policy := &request.RetryPolicy{
+		Retries:           ws.Attempts,
+		Statuses:          ws.Statuses,
+		SkipNetworkErrors: ws.NetworkErrors != nil && !*ws.NetworkErrors,
+		Methods:           ws.Methods,
+	}
+	var err error
+	if ws.Backoff != "" {
+		if policy.Backoff, err = time.ParseDuration(ws.Backoff); err != nil {
+			return nil, fmt.Errorf("invalid retry backoff in .gosh.yaml: %s", ws.Backoff)
+		}
+	}
+	if ws.MaxBackoff != "" {
+		if policy.MaxBackoff, err = time.ParseDuration(ws.MaxBackoff); err != nil {
+			return nil, fmt.Errorf("invalid retry maxBackoff in .gosh.yaml: %s", ws.MaxBackoff)
+		}
+	}
+	return policy, nil
+}
+
 // substituteEnvVars substitutes environment variables in a string
 func (a *App) substituteEnvVars(text string) string {
 	re := regexp.MustCompile(`\$\{([^}]+)\}`)
 	return re.ReplaceAllStringFunc(text, func(match string) string {
 		varName := match[2 : len(match)-1] // Extract variable name from ${...}
+		// ${env:NAME} opts in to the process environment; plain names only
+		// come from .gosh.yaml and .env, so a workspace can't read secrets
+		// such as AWS_SECRET_ACCESS_KEY by name
+		if name, ok := strings.CutPrefix(varName, "env:"); ok {
+			if val, ok := os.LookupEnv(name); ok {
+				return val
+			}
+			return match
+		}
 		if val, ok := a.workspace.Env[varName]; ok {
 			return val
 		}
@@ -364,16 +804,43 @@ func (a *App) substituteEnvVarsInMap(m map[string]string) map[string]string {
 
 // handleAuthCommand handles authentication preset management
 func (a *App) handleAuthCommand(cmd *cli.AuthCommand) error {
+	// --global manages the user-level presets
+	mgr := a.authMgr
+	scope := ""
+	if cmd.Global {
+		mgr = a.authMgr.Global()
+		scope = " (global)"
+	}
+
+	switch cmd.Subcommand {
+	case "list", "login", "logout":
+		// These see workspace and global presets together
+	default:
+		if err := a.unlockAuth(mgr); err != nil {
+			return err
+		}
+	}
+
 	switch cmd.Subcommand {
 	case "list":
-		presets := a.authMgr.List()
+		if err := a.unlockAuth(a.authMgr); err != nil {
+			return err
+		}
+		if err := a.unlockAuth(a.authMgr.Global()); err != nil {
+			return err
+		}
+		presets := a.authMgr.Presets()
 		if len(presets) == 0 {
 			fmt.Println("No authentication presets configured.")
 			return nil
 		}
 		fmt.Println("Authentication Presets:")
-		for name, preset := range presets {
-			fmt.Printf("  %s (%s)\n", name, preset.Type)
+		for _, info := range presets {
+			source := info.Source
+			if info.Overridden {
+				source += ", overridden by workspace"
+			}
+			fmt.Printf("  %s (%s) [%s]\n", info.Name, info.Preset.Type, source)
 		}
 		return nil
 
@@ -387,6 +854,27 @@ func (a *App) handleAuthCommand(cmd *cli.AuthCommand) error {
 			Type: cmd.Type,
 		}
 
+		// FIELD-command=CMD sources any field from a credential helper, and
+		// FIELD-env=VAR from an environment variable
+		for key, value := range cmd.Flags {
+			key = strings.ReplaceAll(key, "_", "-")
+			if field, ok := strings.CutSuffix(key, "-command"); ok && field != "" {
+				if err := preset.SetCommand(field, value); err != nil {
+					return err
+				}
+			} else if field, ok := strings.CutSuffix(key, "-env"); ok && field != "" {
+				if err := preset.SetEnv(field, value); err != nil {
+					return err
+				}
+			}
+		}
+		preset.CommandTTL = flagValue(cmd.Flags, "command-ttl", "command_ttl")
+		if preset.CommandTTL != "" {
+			if _, err := time.ParseDuration(preset.CommandTTL); err != nil {
+				return fmt.Errorf("invalid command-ttl: %s", preset.CommandTTL)
+			}
+		}
+
 		// Parse auth type specific flags
 		switch cmd.Type {
 		case "basic":
@@ -400,7 +888,7 @@ func (a *App) handleAuthCommand(cmd *cli.AuthCommand) error {
 			} else if p, ok := cmd.Flags["p"]; ok {
 				preset.Password = p
 			}
-			if preset.Username == "" {
+			if preset.Username == "" && !preset.HasSource("username") {
 				return fmt.Errorf("basic auth requires --username or -u")
 			}
 
@@ -410,7 +898,7 @@ func (a *App) handleAuthCommand(cmd *cli.AuthCommand) error {
 			} else if t, ok := cmd.Flags["t"]; ok {
 				preset.Token = t
 			}
-			if preset.Token == "" {
+			if preset.Token == "" && !preset.HasSource("token") {
 				return fmt.Errorf("bearer auth requires --token or -t")
 			}
 
@@ -431,31 +919,452 @@ func (a *App) handleAuthCommand(cmd *cli.AuthCommand) error {
 			if preset.Header == "" {
 				return fmt.Errorf("custom auth requires --header or -h")
 			}
-			if preset.Value == "" {
+			if preset.Value == "" && !preset.HasSource("value") {
 				return fmt.Errorf("custom auth requires --value or -v")
 			}
 
+		case "oauth2":
+			preset.AuthURL = flagValue(cmd.Flags, "auth-url", "authUrl", "auth_url")
+			preset.TokenURL = flagValue(cmd.Flags, "token-url", "tokenUrl", "token_url")
+			preset.RedirectURL = flagValue(cmd.Flags, "redirect-url", "redirectUrl", "redirect_url")
+			preset.ClientID = flagValue(cmd.Flags, "client-id", "clientId", "client_id")
+			preset.ClientSecret = flagValue(cmd.Flags, "client-secret", "clientSecret", "client_secret")
+			preset.Scope = flagValue(cmd.Flags, "scope")
+			preset.Grant = flagValue(cmd.Flags, "grant")
+			preset.RefreshToken = flagValue(cmd.Flags, "refresh-token", "refreshToken", "refresh_token")
+			preset.ClientAuth = flagValue(cmd.Flags, "client-auth", "clientAuth", "client_auth")
+			preset.Username = flagValue(cmd.Flags, "username", "u")
+			preset.Password = flagValue(cmd.Flags, "password", "p")
+			if preset.TokenURL == "" {
+				return fmt.Errorf("oauth2 auth requires token-url")
+			}
+			switch preset.Grant {
+			case "", auth.GrantClientCredentials, auth.GrantPassword, auth.GrantRefreshToken:
+			case auth.GrantAuthorizationCode:
+				if preset.AuthURL == "" {
+					return fmt.Errorf("oauth2 authorization_code grant requires auth-url")
+				}
+			default:
+				return fmt.Errorf("unsupported oauth2 grant: %s (expected client_credentials, password, refresh_token or authorization_code)", preset.Grant)
+			}
+
+		case "digest":
+			preset.Username = flagValue(cmd.Flags, "username", "u")
+			preset.Password = flagValue(cmd.Flags, "password", "p")
+			if preset.Username == "" && !preset.HasSource("username") {
+				return fmt.Errorf("digest auth requires --username or -u")
+			}
+
+		case "hmac":
+			preset.Secret = flagValue(cmd.Flags, "secret")
+			preset.Algorithm = flagValue(cmd.Flags, "algorithm")
+			preset.Encoding = flagValue(cmd.Flags, "encoding")
+			preset.Template = flagValue(cmd.Flags, "template")
+			preset.Header = flagValue(cmd.Flags, "header", "h")
+			preset.Prefix = flagValue(cmd.Flags, "prefix")
+			preset.TimestampHeader = flagValue(cmd.Flags, "timestamp-header", "timestampHeader", "timestamp_header")
+			preset.TimestampFormat = flagValue(cmd.Flags, "timestamp-format", "timestampFormat", "timestamp_format")
+			if preset.Secret == "" && !preset.HasSource("secret") {
+				return fmt.Errorf("hmac auth requires secret")
+			}
+
+		case "jwt":
+			preset.Secret = flagValue(cmd.Flags, "secret")
+			preset.Algorithm = flagValue(cmd.Flags, "algorithm", "alg")
+			preset.KeyFile = flagValue(cmd.Flags, "key-file", "keyFile", "key_file")
+			preset.KeyID = flagValue(cmd.Flags, "key-id", "keyId", "key_id", "kid")
+			preset.Lifetime = flagValue(cmd.Flags, "lifetime")
+			for key, value := range cmd.Flags {
+				name, ok := strings.CutPrefix(key, "claim.")
+				if !ok || name == "" {
+					continue
+				}
+				if preset.Claims == nil {
+					preset.Claims = make(map[string]interface{})
+				}
+				// JSON literals keep their type, e.g. claim.admin=true
+				var parsed interface{}
+				if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+					preset.Claims[name] = parsed
+				} else {
+					preset.Claims[name] = value
+				}
+			}
+			if preset.Secret == "" && preset.KeyFile == "" && !preset.HasSource("secret") {
+				return fmt.Errorf("jwt auth requires secret or key-file")
+			}
+			if preset.Lifetime != "" {
+				if _, err := time.ParseDuration(preset.Lifetime); err != nil {
+					return fmt.Errorf("invalid jwt lifetime: %s", preset.Lifetime)
+				}
+			}
+
+		case "mtls":
+			preset.CertFile = flagValue(cmd.Flags, "cert-file", "certFile", "cert_file", "cert")
+			preset.KeyFile = flagValue(cmd.Flags, "key-file", "keyFile", "key_file", "key")
+			preset.Password = flagValue(cmd.Flags, "password", "p")
+			preset.CAFile = flagValue(cmd.Flags, "ca-file", "caFile", "ca_file", "cacert")
+			if preset.CertFile == "" {
+				return fmt.Errorf("mtls auth requires cert-file")
+			}
+
+		case "aws-sigv4":
+			preset.AccessKey = flagValue(cmd.Flags, "access-key", "accessKey", "access_key")
+			preset.SecretKey = flagValue(cmd.Flags, "secret-key", "secretKey", "secret_key")
+			preset.SessionToken = flagValue(cmd.Flags, "session-token", "sessionToken", "session_token")
+			preset.Region = flagValue(cmd.Flags, "region")
+			preset.Service = flagValue(cmd.Flags, "service")
+			if (preset.AccessKey == "" && !preset.HasSource("access_key")) || (preset.SecretKey == "" && !preset.HasSource("secret_key")) {
+				return fmt.Errorf("aws-sigv4 auth requires access-key and secret-key")
+			}
+			if preset.Region == "" || preset.Service == "" {
+				return fmt.Errorf("aws-sigv4 auth requires region and service")
+			}
+
 		default:
 			return fmt.Errorf("unknown auth type: %s", cmd.Type)
 		}
 
-		if err := a.authMgr.Add(preset); err != nil {
+		if err := mgr.Add(preset); err != nil {
 			return err
 		}
-		fmt.Printf("Added auth preset: %s\n", cmd.Name)
+		fmt.Printf("Added auth preset: %s%s\n", cmd.Name, scope)
 		return nil
 
 	case "remove":
 		if cmd.Name == "" {
 			return fmt.Errorf("auth remove requires a preset name")
 		}
-		if err := a.authMgr.Remove(cmd.Name); err != nil {
+		if err := mgr.Remove(cmd.Name); err != nil {
 			return err
 		}
-		fmt.Printf("Removed auth preset: %s\n", cmd.Name)
+		fmt.Printf("Removed auth preset: %s%s\n", cmd.Name, scope)
+		return nil
+
+	case "login":
+		if err := a.unlockPreset(cmd.Name); err != nil {
+			return err
+		}
+		// The token request goes through the workspace proxy and TLS settings
+		if err := a.selectEnvironment(""); err != nil {
+			return err
+		}
+		noFlags := &cli.ParsedRequest{}
+		transport, err := request.NewTransport(a.tlsOptions(noFlags), a.proxyOptions(noFlags), nil)
+		if err != nil {
+			return err
+		}
+		token, err := a.authMgr.Login(cmd.Name, auth.LoginOptions{
+			Transport: request.TokenTransport(transport),
+			OpenURL: func(authorizeURL string) error {
+				fmt.Fprintf(os.Stderr, "Open this URL in your browser to log in:\n\n  %s\n\n", authorizeURL)
+				// Printing the URL is enough when no browser can be launched
+				_ = openBrowser(authorizeURL)
+				fmt.Fprintln(os.Stderr, "Waiting for the authorization callback...")
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if token.ExpiresAt.IsZero() {
+			fmt.Printf("Logged in: %s\n", cmd.Name)
+		} else {
+			fmt.Printf("Logged in: %s (token expires %s)\n", cmd.Name, token.ExpiresAt.Local().Format(time.RFC1123))
+		}
+		return nil
+
+	case "logout":
+		if err := a.unlockPreset(cmd.Name); err != nil {
+			return err
+		}
+		if err := a.authMgr.Logout(cmd.Name); err != nil {
+			return err
+		}
+		fmt.Printf("Logged out: %s\n", cmd.Name)
+		return nil
+
+	case "lock":
+		passphrase, err := newAuthPassphrase()
+		if err != nil {
+			return err
+		}
+		if err := mgr.Encrypt(passphrase); err != nil {
+			return err
+		}
+		fmt.Printf("Auth presets and tokens are now encrypted%s.\n", scope)
+		return nil
+
+	case "unlock":
+		if !mgr.Encrypted() {
+			fmt.Printf("Auth presets are not encrypted%s.\n", scope)
+			return nil
+		}
+		if err := mgr.Decrypt(); err != nil {
+			return err
+		}
+		fmt.Printf("Auth presets and tokens are now stored in plaintext%s.\n", scope)
 		return nil
 
 	default:
 		return fmt.Errorf("unknown auth subcommand: %s", cmd.Subcommand)
 	}
 }
+
+// readStdinBody uses piped stdin as the request body
+func (a *App) readStdinBody(req *cli.ParsedRequest) {
+	if !req.HasStdinBody && isTerminal(os.Stdin) {
+		return
+	}
+	stdinData, err := io.ReadAll(os.Stdin)
+	if err == nil && len(stdinData) > 0 {
+		req.Body = string(stdinData)
+		req.HasStdinBody = true
+	}
+}
+
+// confirmDefaultAuth asks before a preset chosen by defaultAuth rather
+// than --auth runs credential helpers or reads environment variables, since
+// .gosh.yaml and the workspace presets may come from a cloned repository.
+// The answer is kept until the sources or the rule's host pattern change.
+func (a *App) confirmDefaultAuth(rule *config.AuthRule, target string) error {
+	preset := a.authMgr.Lookup(rule.Auth)
+	if preset == nil || len(preset.Commands)+len(preset.Env) == 0 {
+		return nil
+	}
+
+	var sources []string
+	for field, command := range preset.Commands {
+		sources = append(sources, fmt.Sprintf("%s: runs %s", field, command))
+	}
+	for field, variable := range preset.Env {
+		sources = append(sources, fmt.Sprintf("%s: reads $%s", field, variable))
+	}
+	sort.Strings(sources)
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s", a.workspace.Root, rule.Auth, rule.Host, preset.CommandTTL)
+	for _, source := range sources {
+		fmt.Fprintf(hash, "\x00%s", source)
+	}
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	trusted, err := config.IsTrusted(key)
+	if err != nil || trusted {
+		return err
+	}
+
+	refused := fmt.Errorf("default auth preset %s runs commands or reads environment variables; use --auth %s to allow it, or --no-auth", rule.Auth, rule.Auth)
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return refused
+	}
+	fmt.Fprintf(os.Stderr, "defaultAuth in .gosh.yaml selects auth preset %s for %s, which uses:\n", rule.Auth, target)
+	for _, source := range sources {
+		fmt.Fprintf(os.Stderr, "  %s\n", source)
+	}
+	ok, err := ui.Confirm("Allow this now and for later requests? [y/N] ")
+	if err != nil {
+		return fmt.Errorf("failed to read answer: %w", err)
+	}
+	if !ok {
+		return refused
+	}
+	return config.Trust(key)
+}
+
+// unlockAuth decrypts encrypted auth presets, reading the passphrase from
+// GOSH_AUTH_PASSPHRASE or a prompt
+func (a *App) unlockAuth(mgr *auth.Manager) error {
+	if mgr == nil || !mgr.Locked() {
+		return nil
+	}
+
+	prompt := "Auth passphrase: "
+	if mgr == a.authMgr.Global() {
+		prompt = "Global auth passphrase: "
+	}
+	passphrase, ok := os.LookupEnv("GOSH_AUTH_PASSPHRASE")
+	if !ok {
+		// Without a terminal the prompt would read piped input or go unseen
+		if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+			return fmt.Errorf("auth presets are encrypted; set GOSH_AUTH_PASSPHRASE to unlock them without a terminal")
+		}
+		var err error
+		if passphrase, err = ui.PromptPassword(prompt); err != nil {
+			return fmt.Errorf("failed to read passphrase: %w", err)
+		}
+	}
+	if err := mgr.Unlock(passphrase); err != nil {
+		return fmt.Errorf("failed to unlock auth presets: %w", err)
+	}
+	return nil
+}
+
+// unlockPreset unlocks the workspace presets, and the global ones too when
+// the workspace doesn't define the preset
+func (a *App) unlockPreset(name string) error {
+	if err := a.unlockAuth(a.authMgr); err != nil {
+		return err
+	}
+	if a.authMgr.Has(name) {
+		return nil
+	}
+	return a.unlockAuth(a.authMgr.Global())
+}
+
+// newAuthPassphrase reads a new passphrase from GOSH_AUTH_PASSPHRASE, or
+// prompts for it twice
+func newAuthPassphrase() (string, error) {
+	if passphrase, ok := os.LookupEnv("GOSH_AUTH_PASSPHRASE"); ok {
+		return passphrase, nil
+	}
+
+	passphrase, err := ui.PromptPassword("New passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	confirm, err := ui.PromptPassword("Confirm passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if passphrase != confirm {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// openBrowser opens a URL with the platform's default handler
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
+// flagValue returns the first auth flag set under any of the given names
+func flagValue(flags map[string]string, names ...string) string {
+	for _, name := range names {
+		if value, ok := flags[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// activeEnvironment returns the environment to use for a request.
+// An explicit --env wins, then the environment chosen with `gosh env use`,
+// then the global defaultEnvironment if this workspace defines it. A
+// persisted environment that no longer exists is cleared with a warning.
+func (a *App) activeEnvironment(name string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+
+	active, err := config.LoadActiveEnvironment(a.workspace.Root)
+	if err != nil {
+		return "", err
+	}
+	if active != "" {
+		if a.workspace.HasEnvironment(active) {
+			return active, nil
+		}
+		// The environment was removed from .gosh.yaml after `gosh env use`
+		fmt.Fprintf(os.Stderr, "Warning: active environment %s is no longer defined in .gosh.yaml; falling back to the default\n", active)
+		if err := config.ClearActiveEnvironment(a.workspace.Root); err != nil {
+			return "", err
+		}
+	}
+
+	if a.global != nil && a.workspace.HasEnvironment(a.global.DefaultEnvironment) {
+		return a.global.DefaultEnvironment, nil
+	}
+
+	return "", nil
+}
+
+// selectEnvironment merges the active environment into the workspace variables
+func (a *App) selectEnvironment(name string) error {
+	env, err := a.activeEnvironment(name)
+	if err != nil {
+		return err
+	}
+	if env == "" {
+		return nil
+	}
+	return a.workspace.SelectEnvironment(env)
+}
+
+// handleEnvCommand handles environment inspection and selection
+func (a *App) handleEnvCommand(cmd *cli.EnvCommand) error {
+	switch cmd.Subcommand {
+	case "list":
+		names := a.workspace.EnvironmentNames()
+		if len(names) == 0 {
+			fmt.Println("No environments configured.")
+			return nil
+		}
+		active, err := a.activeEnvironment("")
+		if err != nil {
+			return err
+		}
+		fmt.Println("Environments:")
+		for _, name := range names {
+			if name == active {
+				fmt.Printf("  %s (active)\n", name)
+			} else {
+				fmt.Printf("  %s\n", name)
+			}
+		}
+		return nil
+
+	case "show":
+		name := cmd.Name
+		if name == "" {
+			var err error
+			name, err = a.activeEnvironment("")
+			if err != nil {
+				return err
+			}
+		}
+		env, err := a.workspace.ResolveEnvironment(name)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			fmt.Println("Environment: (none)")
+		} else {
+			fmt.Printf("Environment: %s\n", name)
+		}
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %s=%s\n", key, env[key])
+		}
+		return nil
+
+	case "use":
+		if cmd.Name == "" {
+			return fmt.Errorf("env use requires an environment name")
+		}
+		if !a.workspace.HasEnvironment(cmd.Name) {
+			return fmt.Errorf("unknown environment: %s", cmd.Name)
+		}
+		if err := config.SaveActiveEnvironment(a.workspace.Root, cmd.Name); err != nil {
+			return err
+		}
+		fmt.Printf("Using environment: %s\n", cmd.Name)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown env subcommand: %s", cmd.Subcommand)
+	}
+}
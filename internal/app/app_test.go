@@ -2,14 +2,21 @@ package app
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gosh/internal/auth"
 	"github.com/gosh/internal/cli"
 	"github.com/gosh/internal/config"
+	"github.com/gosh/internal/request"
 	"github.com/gosh/internal/storage"
 )
 
@@ -158,6 +165,27 @@ func TestSubstituteEnvVars(t *testing.T) {
 	}
 }
 
+// TestSubstituteEnvVarsProcessEnv tests that the process environment is
+// only read with ${env:NAME}
+func TestSubstituteEnvVarsProcessEnv(t *testing.T) {
+	t.Setenv("GOSH_TEST_SECRET", "from-shell")
+	app := &App{
+		workspace: &config.Workspace{Root: "/tmp", Env: map[string]string{"TOKEN": "from-env-file"}},
+	}
+
+	tests := map[string]string{
+		"${GOSH_TEST_SECRET}":              "${GOSH_TEST_SECRET}",
+		"${env:GOSH_TEST_SECRET}":          "from-shell",
+		"${env:GOSH_TEST_UNSET_SECRET}":    "${env:GOSH_TEST_UNSET_SECRET}",
+		"${TOKEN}/${env:GOSH_TEST_SECRET}": "from-env-file/from-shell",
+	}
+	for text, expected := range tests {
+		if got := app.substituteEnvVars(text); got != expected {
+			t.Errorf("%s: got %q, want %q", text, got, expected)
+		}
+	}
+}
+
 // TestSubstituteEnvVarsInMap tests environment variable substitution in maps
 func TestSubstituteEnvVarsInMap(t *testing.T) {
 	app := &App{
@@ -362,6 +390,210 @@ func TestExecuteRequestWithInvalidAuth(t *testing.T) {
 	}
 }
 
+// TestExecuteRequestLockedAuthWithoutTerminal tests that encrypted presets
+// fail fast without a terminal, before stdin is read for the body
+func TestExecuteRequestLockedAuthWithoutTerminal(t *testing.T) {
+	tmpDir := t.TempDir()
+	locked := auth.NewManager(tmpDir)
+	if err := locked.Add(&auth.AuthPreset{Name: "api", Type: "bearer", Token: "xyz"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := locked.Encrypt("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	authMgr := auth.NewManager(tmpDir)
+	if err := authMgr.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   authMgr,
+	}
+
+	r, w, _ := os.Pipe()
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+	os.Stdin = r
+	_, _ = w.WriteString("body")
+	w.Close()
+
+	t.Setenv("GOSH_AUTH_PASSPHRASE", "")
+	os.Unsetenv("GOSH_AUTH_PASSPHRASE")
+	req := &cli.ParsedRequest{
+		Method:  "POST",
+		URL:     "https://api.example.com/users",
+		Headers: make(map[string]string),
+		Auth:    "api",
+	}
+	err := app.executeRequest(req)
+	if err == nil || !strings.Contains(err.Error(), "set GOSH_AUTH_PASSPHRASE") {
+		t.Fatalf("expected an error asking for GOSH_AUTH_PASSPHRASE, got %v", err)
+	}
+	if body, _ := io.ReadAll(r); string(body) != "body" {
+		t.Errorf("expected stdin to be left unread, got %q left", body)
+	}
+}
+
+// TestExecuteRequestDryRunSkipsAuth tests that --dry --save saves the
+// preset name without unlocking or resolving it
+func TestExecuteRequestDryRunSkipsAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+	locked := auth.NewManager(tmpDir)
+	preset := &auth.AuthPreset{Name: "api", Type: "bearer"}
+	if err := preset.SetCommand("token", "touch ran && echo x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := locked.Add(preset); err != nil {
+		t.Fatal(err)
+	}
+	if err := locked.Encrypt("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	authMgr := auth.NewManager(tmpDir)
+	if err := authMgr.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   authMgr,
+	}
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     "https://api.example.com/users",
+		Headers: make(map[string]string),
+		Auth:    "api",
+		Dry:     true,
+		Save:    "users",
+	}
+	captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	saved, err := app.storage.Load("users")
+	if err != nil {
+		t.Fatalf("expected saved call, got error %v", err)
+	}
+	if saved.Auth != "api" {
+		t.Errorf("expected the preset name to be saved, got %q", saved.Auth)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "ran")); !os.IsNotExist(err) {
+		t.Error("expected the credential helper not to run")
+	}
+}
+
+// TestExecuteRequestDefaultAuthHelpers tests that a defaultAuth preset's
+// credential helpers need --auth without a terminal to confirm them
+func TestExecuteRequestDefaultAuthHelpers(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	authMgr := auth.NewManager(tmpDir)
+	preset := &auth.AuthPreset{Name: "api", Type: "bearer"}
+	if err := preset.SetCommand("token", "touch ran && echo helper-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := authMgr.Add(preset); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{
+		workspace: &config.Workspace{
+			Root:   tmpDir,
+			Env:    map[string]string{},
+			Config: &config.WorkspaceConfig{DefaultAuth: config.AuthRules{{Auth: "api"}}},
+		},
+		global:  &config.GlobalConfig{},
+		storage: storage.NewManager(tmpDir),
+		authMgr: authMgr,
+	}
+	newRequest := func(authName string) *cli.ParsedRequest {
+		return &cli.ParsedRequest{
+			Method:      "GET",
+			URL:         server.URL,
+			Headers:     make(map[string]string),
+			QueryParams: make(map[string]string),
+			Auth:        authName,
+		}
+	}
+
+	err := app.executeRequest(newRequest(""))
+	if err == nil || !strings.Contains(err.Error(), "use --auth api") {
+		t.Fatalf("expected an error asking for --auth, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "ran")); !os.IsNotExist(err) {
+		t.Error("expected the credential helper not to run")
+	}
+
+	captureOutput(func() {
+		if err := app.executeRequest(newRequest("api")); err != nil {
+			t.Errorf("expected no error with --auth, got %v", err)
+		}
+	})
+	if gotAuth != "Bearer helper-token" {
+		t.Errorf("expected the helper's token, got %q", gotAuth)
+	}
+}
+
+// TestExecuteRequestDefaultAuthEnv tests that a defaultAuth preset reading
+// environment variables also needs confirming
+func TestExecuteRequestDefaultAuthEnv(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GOSH_TEST_TOKEN", "local-secret")
+	tmpDir := t.TempDir()
+	authMgr := auth.NewManager(tmpDir)
+	preset := &auth.AuthPreset{Name: "api", Type: "bearer"}
+	if err := preset.SetEnv("token", "GOSH_TEST_TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	if err := authMgr.Add(preset); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{
+		workspace: &config.Workspace{
+			Root:   tmpDir,
+			Env:    map[string]string{},
+			Config: &config.WorkspaceConfig{DefaultAuth: config.AuthRules{{Auth: "api", Host: "127.0.0.1:*"}}},
+		},
+		global:  &config.GlobalConfig{},
+		storage: storage.NewManager(tmpDir),
+		authMgr: authMgr,
+	}
+	req := &cli.ParsedRequest{
+		Method:      "GET",
+		URL:         server.URL,
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+	}
+
+	err := app.executeRequest(req)
+	if err == nil || !strings.Contains(err.Error(), "use --auth api") {
+		t.Fatalf("expected an error asking for --auth, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no request to be sent, got %d", requests)
+	}
+}
+
 // TestHandleAuthCommandList tests listing auth presets
 func TestHandleAuthCommandList(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -449,6 +681,40 @@ func TestHandleAuthCommandAddBearer(t *testing.T) {
 	}
 }
 
+// TestHandleAuthCommandAddSecretSources tests FIELD-env and that literal
+// values are stored as given
+func TestHandleAuthCommandAddSecretSources(t *testing.T) {
+	t.Setenv("GOSH_TEST_USER", "admin")
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	cmd := &cli.AuthCommand{
+		Subcommand: "add",
+		Type:       "basic",
+		Name:       "admin",
+		Flags: map[string]string{
+			"username-env": "GOSH_TEST_USER",
+			"password":     "!not-a-command",
+		},
+	}
+	if err := app.handleAuthCommand(cmd); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	preset, err := app.authMgr.Get("admin")
+	if err != nil {
+		t.Fatalf("expected preset to exist, got error %v", err)
+	}
+	if preset.Username != "admin" || preset.Password != "!not-a-command" {
+		t.Errorf("expected the username from the environment and a literal password, got %q/%q", preset.Username, preset.Password)
+	}
+}
+
 // TestHandleAuthCommandAddBasic tests adding basic auth preset
 func TestHandleAuthCommandAddBasic(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -1744,3 +2010,678 @@ func TestExecuteRequestWithBodyData(t *testing.T) {
 		t.Errorf("expected body %q, got %q", bodyData, call.Body)
 	}
 }
+
+// TestExecuteRequestWithEnvironment tests that --env merges the named environment
+func TestExecuteRequestWithEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{
+			Root: tmpDir,
+			Config: &config.WorkspaceConfig{
+				Environments: map[string]map[string]string{
+					"staging": {"API_HOST": "staging.example.com"},
+				},
+			},
+			Env: map[string]string{"API_HOST": "localhost"},
+		},
+		global:  &config.GlobalConfig{},
+		storage: storage.NewManager(tmpDir),
+		authMgr: auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     "https://${API_HOST}/users",
+		Headers: make(map[string]string),
+		Env:     "staging",
+		Dry:     true,
+		Save:    "test",
+	}
+
+	if err := app.executeRequest(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	saved, err := app.storage.Load("test")
+	if err != nil {
+		t.Fatalf("expected saved call, got error %v", err)
+	}
+	if saved.URL != "https://staging.example.com/users" {
+		t.Errorf("substituted URL: got %q, want 'https://staging.example.com/users'", saved.URL)
+	}
+}
+
+// TestExecuteRequestWithUnknownEnvironment tests that an unknown --env fails
+func TestExecuteRequestWithUnknownEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     "https://api.example.com/users",
+		Headers: make(map[string]string),
+		Env:     "missing",
+		Dry:     true,
+		Save:    "test",
+	}
+
+	if err := app.executeRequest(req); err == nil {
+		t.Fatal("expected error for unknown environment, got nil")
+	}
+}
+
+// TestActiveEnvironmentPrecedence tests flag, persisted and global default ordering
+func TestActiveEnvironmentPrecedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{
+			Root: tmpDir,
+			Config: &config.WorkspaceConfig{
+				Environments: map[string]map[string]string{
+					"dev":     {},
+					"staging": {},
+					"prod":    {},
+				},
+			},
+		},
+		global:  &config.GlobalConfig{DefaultEnvironment: "dev"},
+		storage: storage.NewManager(tmpDir),
+		authMgr: auth.NewManager(tmpDir),
+	}
+
+	if env, _ := app.activeEnvironment(""); env != "dev" {
+		t.Errorf("expected global default dev, got %q", env)
+	}
+
+	if err := app.Run([]string{"env", "use", "staging"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if env, _ := app.activeEnvironment(""); env != "staging" {
+		t.Errorf("expected persisted staging, got %q", env)
+	}
+
+	if env, _ := app.activeEnvironment("prod"); env != "prod" {
+		t.Errorf("expected flag prod, got %q", env)
+	}
+}
+
+// TestActiveEnvironmentStale tests falling back when the persisted
+// environment was removed from .gosh.yaml
+func TestActiveEnvironmentStale(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{
+			Root: tmpDir,
+			Config: &config.WorkspaceConfig{
+				Environments: map[string]map[string]string{"dev": {}},
+			},
+		},
+		global:  &config.GlobalConfig{DefaultEnvironment: "dev"},
+		storage: storage.NewManager(tmpDir),
+		authMgr: auth.NewManager(tmpDir),
+	}
+	if err := config.SaveActiveEnvironment(tmpDir, "removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := app.activeEnvironment("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if env != "dev" {
+		t.Errorf("expected the global default dev, got %q", env)
+	}
+	if active, _ := config.LoadActiveEnvironment(tmpDir); active != "" {
+		t.Errorf("expected the stale environment to be cleared, got %q", active)
+	}
+}
+
+// TestHandleEnvCommandUseUnknown tests selecting an environment that doesn't exist
+func TestHandleEnvCommandUseUnknown(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	err := app.handleEnvCommand(&cli.EnvCommand{Subcommand: "use", Name: "missing"})
+	if err == nil {
+		t.Fatal("expected error for unknown environment, got nil")
+	}
+}
+
+// TestHandleEnvCommandShowOutput tests printing an environment's variables
+func TestHandleEnvCommandShowOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{
+			Root: tmpDir,
+			Config: &config.WorkspaceConfig{
+				Environments: map[string]map[string]string{
+					"prod": {"API_BASE": "https://api.example.com"},
+				},
+			},
+			Env: map[string]string{"API_TOKEN": "abc"},
+		},
+		global:  &config.GlobalConfig{},
+		storage: storage.NewManager(tmpDir),
+		authMgr: auth.NewManager(tmpDir),
+	}
+
+	output := captureOutput(func() {
+		_ = app.handleEnvCommand(&cli.EnvCommand{Subcommand: "show", Name: "prod"})
+	})
+
+	if !strings.Contains(output, "Environment: prod") {
+		t.Errorf("expected environment name in output, got %q", output)
+	}
+	if !strings.Contains(output, "API_BASE=https://api.example.com") || !strings.Contains(output, "API_TOKEN=abc") {
+		t.Errorf("expected merged variables in output, got %q", output)
+	}
+}
+
+// TestExecuteRequestWithRelativeURL tests resolving a relative path against baseUrl
+func TestExecuteRequestWithRelativeURL(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{
+			Root: tmpDir,
+			Config: &config.WorkspaceConfig{
+				BaseURL:  "https://unused.example.com",
+				BaseURLs: map[string]string{"local": server.URL + "/v1"},
+				Environments: map[string]map[string]string{
+					"local": {},
+				},
+			},
+			Env: map[string]string{},
+		},
+		global:  &config.GlobalConfig{},
+		storage: storage.NewManager(tmpDir),
+		authMgr: auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:        "GET",
+		URL:           "/users/{id}",
+		Headers:       make(map[string]string),
+		PathParams:    map[string]string{"id": "42"},
+		Env:           "local",
+		Save:          "get-user",
+		NoInteractive: true,
+	}
+
+	captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	if gotPath != "/v1/users/42" {
+		t.Errorf("request path: got %q, want '/v1/users/42'", gotPath)
+	}
+
+	saved, err := app.storage.Load("get-user")
+	if err != nil {
+		t.Fatalf("expected saved call, got error %v", err)
+	}
+	if saved.URL != "/users/{id}" {
+		t.Errorf("saved URL: got %q, want '/users/{id}'", saved.URL)
+	}
+}
+
+// TestExecuteRequestWithRelativeURLNoBase tests a relative path without baseUrl
+func TestExecuteRequestWithRelativeURLNoBase(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     "/users",
+		Headers: make(map[string]string),
+	}
+
+	if err := app.executeRequest(req); err == nil {
+		t.Fatal("expected error for relative URL without baseUrl, got nil")
+	}
+}
+
+// TestSaveAndRecallWithItems tests that items are saved and overridden on recall
+func TestSaveAndRecallWithItems(t *testing.T) {
+	var gotPath, gotBody, gotContentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath = r.URL.Path
+		gotBody = string(body)
+		gotContentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:      "PUT",
+		URL:         server.URL + "/users/{id}",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		Items: []request.Item{
+			{Type: request.ItemData, Key: "name", Value: "John"},
+			{Type: request.ItemRawJSON, Key: "age", Value: "30"},
+		},
+		PathParams: map[string]string{"id": "1"},
+		Dry:        true,
+		Save:       "update-user",
+	}
+	captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	saved, err := app.storage.Load("update-user")
+	if err != nil {
+		t.Fatalf("expected saved call, got error %v", err)
+	}
+	if len(saved.Items) != 2 || saved.Items[0] != "name=John" || saved.Items[1] != "age:=30" {
+		t.Errorf("saved items: got %v", saved.Items)
+	}
+
+	// Legacy name=value overrides set path variables when the URL has {name}
+	opts := &cli.RecallOptions{
+		Name:              "update-user",
+		ParameterOverride: map[string]string{"id": "42", "name": "Jane"},
+		Headers:           make(map[string]string),
+	}
+	captureOutput(func() {
+		if err := app.executeRecall(opts); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	if gotPath != "/users/42" {
+		t.Errorf("request path: got %q, want '/users/42'", gotPath)
+	}
+	if gotBody != `{"age":30,"name":"Jane"}` {
+		t.Errorf("request body: got %s", gotBody)
+	}
+	if gotContentType != "application/json" {
+		t.Errorf("Content-Type: got %q", gotContentType)
+	}
+}
+
+// TestRecallMergesItemsIntoSavedBody tests overriding fields of a saved raw JSON body
+func TestRecallMergesItemsIntoSavedBody(t *testing.T) {
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	storageMgr := storage.NewManager(tmpDir)
+	call := storage.NewSavedCall("create", "POST", server.URL+"/users", map[string]string{}, nil, `{"name":"John","role":"user"}`)
+	if err := storageMgr.Save(call); err != nil {
+		t.Fatalf("failed to save call: %v", err)
+	}
+
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storageMgr,
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	opts := &cli.RecallOptions{
+		Name:              "create",
+		ParameterOverride: map[string]string{"role": "admin"},
+		Headers:           make(map[string]string),
+	}
+	captureOutput(func() {
+		if err := app.executeRecall(opts); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	if gotBody != `{"name":"John","role":"admin"}` {
+		t.Errorf("request body: got %s", gotBody)
+	}
+}
+
+// TestExecuteRequestDownloadResume tests downloading to a file and resuming with Range
+func TestExecuteRequestDownloadResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	var gotRange string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		http.ServeContent(w, r, "data.bin", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	outPath := filepath.Join(tmpDir, "data.bin")
+	if err := os.WriteFile(outPath, []byte(content[:300]), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:   "GET",
+		URL:      server.URL + "/data.bin",
+		Headers:  make(map[string]string),
+		Download: true,
+		Output:   outPath,
+		Continue: true,
+		Save:     "get-data",
+	}
+
+	out := captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	if gotRange != "bytes=300-" {
+		t.Errorf("Range header: got %q, want 'bytes=300-'", gotRange)
+	}
+	data, _ := os.ReadFile(outPath)
+	if string(data) != content {
+		t.Errorf("downloaded file has %d bytes, want %d", len(data), len(content))
+	}
+	if !strings.Contains(out, "Downloaded") {
+		t.Errorf("expected download summary, got %q", out)
+	}
+
+	saved, err := app.storage.Load("get-data")
+	if err != nil {
+		t.Fatalf("expected saved call, got error %v", err)
+	}
+	if _, ok := saved.Headers["Range"]; ok {
+		t.Error("Range header should not be saved with the call")
+	}
+}
+
+// TestExecuteRequestStream tests printing a streamed body
+func TestExecuteRequestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("chunk\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: make(map[string]string),
+		Stream:  true,
+	}
+
+	out := captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	if strings.Count(out, "chunk") != 3 {
+		t.Errorf("expected 3 chunks in output, got %q", out)
+	}
+}
+
+// TestExecuteRequestEventStream tests that an Accept: text/event-stream
+// request renders events without --stream
+func TestExecuteRequestEventStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= 2; i++ {
+			fmt.Fprintf(w, "id: %d\nevent: tick\ndata: {\"n\":%d}\n\n", i, i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: map[string]string{"Accept": "text/event-stream"},
+	}
+
+	out := captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "id: 2\nevent: tick\n{\n  \"n\": 2\n}") {
+		t.Errorf("expected pretty-printed events, got %q", out)
+	}
+}
+
+// TestNewFormatterNoColor tests that NO_COLOR disables colors on a TTY
+func TestNewFormatterNoColor(t *testing.T) {
+	app := &App{global: &config.GlobalConfig{Theme: "mono"}, isTTY: true}
+	resp := &request.Response{
+		StatusCode: 200,
+		Headers:    map[string][]string{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"a":1}`),
+	}
+
+	if out := app.newFormatter().FormatResponse(resp, false); !strings.Contains(out, "\033[") {
+		t.Errorf("expected colored output on a TTY, got %q", out)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if out := app.newFormatter().FormatResponse(resp, false); strings.Contains(out, "\033[") {
+		t.Errorf("expected no colors with NO_COLOR, got %q", out)
+	}
+}
+
+// TestExecuteRequestFormatJSON tests that --format json writes only the envelope to stdout
+func TestExecuteRequestFormatJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":7}`))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: make(map[string]string),
+		Format:  "json",
+		Save:    "get-json",
+	}
+
+	out := captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	var env struct {
+		Status int `json:"status"`
+		Body   struct {
+			ID int `json:"id"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("stdout is not a JSON envelope: %v\n%s", err, out)
+	}
+	if env.Status != 200 || env.Body.ID != 7 {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+}
+
+// TestExecuteRequestFilter tests filtering a response and the missing path exit code
+func TestExecuteRequestFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[{"id":1,"name":"a"},{"id":2,"name":"b"}]}`))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	app := &App{
+		workspace: &config.Workspace{Root: tmpDir, Env: map[string]string{}},
+		global:    &config.GlobalConfig{},
+		storage:   storage.NewManager(tmpDir),
+		authMgr:   auth.NewManager(tmpDir),
+	}
+
+	req := &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: make(map[string]string),
+		Filter:  ".items[].name",
+	}
+	out := captureOutput(func() {
+		if err := app.executeRequest(req); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+	if out != "a\nb\n" {
+		t.Errorf("expected one result per line, got %q", out)
+	}
+
+	req = &cli.ParsedRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: make(map[string]string),
+		Filter:  "$.missing",
+	}
+	var err error
+	captureOutput(func() {
+		err = app.executeRequest(req)
+	})
+	if ExitCode(err) != ExitNoMatch {
+		t.Errorf("expected exit code %d for a missing path, got %d (%v)", ExitNoMatch, ExitCode(err), err)
+	}
+	if ExitCode(nil) != 0 || ExitCode(fmt.Errorf("other")) != 1 {
+		t.Error("unexpected exit codes for nil and generic errors")
+	}
+}
+
+// TestRetryPolicy tests merging the workspace retry block with --retry
+func TestRetryPolicy(t *testing.T) {
+	networkErrors := false
+	app := &App{
+		workspace: &config.Workspace{
+			Config: &config.WorkspaceConfig{
+				Retry: &config.RetryConfig{
+					Attempts:      2,
+					NetworkErrors: &networkErrors,
+					Backoff:       "250ms",
+				},
+			},
+		},
+	}
+
+	policy, err := app.retryPolicy(&cli.ParsedRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy == nil || policy.Retries != 2 || !policy.SkipNetworkErrors || policy.Backoff != 250*time.Millisecond {
+		t.Errorf("unexpected policy from .gosh.yaml: %+v", policy)
+	}
+
+	if policy, _ = app.retryPolicy(&cli.ParsedRequest{Retry: 5}); policy == nil || policy.Retries != 5 {
+		t.Errorf("expected --retry to override attempts, got %+v", policy)
+	}
+	if policy, _ = app.retryPolicy(&cli.ParsedRequest{NoRetry: true}); policy != nil {
+		t.Errorf("expected --retry 0 to turn retries off, got %+v", policy)
+	}
+
+	app.workspace.Config.Retry.MaxBackoff = "soon"
+	if _, err := app.retryPolicy(&cli.ParsedRequest{}); err == nil {
+		t.Error("expected error for an invalid maxBackoff")
+	}
+}
+
+// TestProxyOptions tests merging the workspace proxy with --proxy and
+// --no-proxy
+func TestProxyOptions(t *testing.T) {
+	t.Setenv("PROXY_PASSWORD", "pw")
+	app := &App{
+		workspace: &config.Workspace{
+			Config: &config.WorkspaceConfig{
+				Proxy: &config.WorkspaceProxy{
+					ProxyConfig: config.ProxyConfig{
+						URL:     "http://user:${env:PROXY_PASSWORD}@proxy.corp:3128",
+						NoProxy: []string{"localhost"},
+					},
+				},
+			},
+		},
+	}
+
+	opts := app.proxyOptions(&cli.ParsedRequest{NoProxy: []string{".internal"}})
+	if opts == nil || opts.URL != "http://user:pw@proxy.corp:3128" || len(opts.NoProxy) != 2 {
+		t.Errorf("unexpected proxy options %+v", opts)
+	}
+
+	if opts = app.proxyOptions(&cli.ParsedRequest{Proxy: "socks5://bastion:1080"}); opts.URL != "socks5://bastion:1080" {
+		t.Errorf("expected --proxy to override the workspace, got %+v", opts)
+	}
+
+	app.workspace.Config.Proxy = nil
+	if opts = app.proxyOptions(&cli.ParsedRequest{}); opts != nil {
+		t.Errorf("expected nil without proxy settings, got %+v", opts)
+	}
+}
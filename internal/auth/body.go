@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// hashRequestBody returns the hex-encoded hash of the request body, read
+// through GetBody so the body itself is left untouched
+func hashRequestBody(req *http.Request, newHash func() hash.Hash) (string, error) {
+	hasher := newHash()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", fmt.Errorf("cannot sign a request body that can't be re-read")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		if _, err := io.Copy(hasher, body); err != nil {
+			return "", fmt.Errorf("failed to hash request body: %w", err)
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// readRequestBody returns a copy of the request body, read through GetBody
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot sign a request body that can't be re-read")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt cost parameters for new keys (N=2^15 is the recommended
+// interactive setting)
+var (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// encryptionAAD binds ciphertexts to this file format
+const encryptionAAD = "gosh-auth-v1"
+
+// ErrIncorrectPassphrase is returned when encrypted data can't be opened
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// EncryptedData is an encrypted document: a passphrase-derived scrypt key
+// and AES-256-GCM
+type EncryptedData struct {
+	KDF        string `yaml:"kdf"`
+	N          int    `yaml:"n"`
+	R          int    `yaml:"r"`
+	P          int    `yaml:"p"`
+	Salt       string `yaml:"salt"`
+	Nonce      string `yaml:"nonce"`
+	Ciphertext string `yaml:"ciphertext"`
+}
+
+// sealer encrypts and decrypts with a key derived from a passphrase
+type sealer struct {
+	key  []byte
+	salt []byte
+	n    int
+	r    int
+	p    int
+}
+
+// newSealer derives a key for a passphrase with a fresh salt
+func newSealer(passphrase string) (*sealer, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return deriveSealer(passphrase, salt, scryptN, scryptR, scryptP)
+}
+
+// openSealer derives the key for encrypted data and decrypts it
+func openSealer(passphrase string, data *EncryptedData) (*sealer, []byte, error) {
+	if data.KDF != "scrypt" {
+		return nil, nil, fmt.Errorf("unsupported key derivation: %s", data.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(data.Salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid salt: %w", err)
+	}
+
+	s, err := deriveSealer(passphrase, salt, data.N, data.R, data.P)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := s.open(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, plaintext, nil
+}
+
+// deriveSealer derives an AES-256 key with scrypt
+func deriveSealer(passphrase string, salt []byte, n, r, p int) (*sealer, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return &sealer{key: key, salt: salt, n: n, r: r, p: p}, nil
+}
+
+// seal encrypts plaintext with a fresh nonce
+func (s *sealer) seal(plaintext []byte) (*EncryptedData, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return &EncryptedData{
+		KDF:        "scrypt",
+		N:          s.n,
+		R:          s.r,
+		P:          s.p,
+		Salt:       base64.StdEncoding.EncodeToString(s.salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, []byte(encryptionAAD))),
+	}, nil
+}
+
+// open decrypts data sealed with the same key
+func (s *sealer) open(data *EncryptedData) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(data.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(data.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(encryptionAAD))
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	return plaintext, nil
+}
+
+// gcm returns an AES-GCM cipher for the key
+func (s *sealer) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withFastKDF lowers the scrypt cost so tests run quickly
+func withFastKDF(t *testing.T) {
+	scryptN = 1 << 10
+	t.Cleanup(func() { scryptN = 1 << 15 })
+}
+
+func TestSealerRoundTrip(t *testing.T) {
+	withFastKDF(t)
+	s, err := newSealer("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data, err := s.seal([]byte("secret"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(data.Ciphertext, "secret") || data.KDF != "scrypt" || data.N != 1<<10 {
+		t.Errorf("unexpected encrypted data %+v", data)
+	}
+
+	_, plaintext, err := openSealer("correct horse", data)
+	if err != nil || string(plaintext) != "secret" {
+		t.Fatalf("expected 'secret', got %q (%v)", plaintext, err)
+	}
+	if _, _, err := openSealer("wrong", data); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("expected ErrIncorrectPassphrase, got %v", err)
+	}
+	if _, err := newSealer(""); err == nil {
+		t.Error("expected error for empty passphrase")
+	}
+}
+
+func TestManagerEncrypt(t *testing.T) {
+	withFastKDF(t)
+	dir := t.TempDir()
+	m := NewManager(dir)
+	if err := m.Add(&AuthPreset{Name: "api", Type: "bearer", Token: "plain-token"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Tokens().Put("api", &Token{AccessToken: "cached-token"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Encrypt("pass"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, name := range []string{"auth.yaml", "tokens.yaml"} {
+		data, err := os.ReadFile(filepath.Join(dir, ".gosh", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "encrypted:") {
+			t.Errorf("%s not encrypted:\n%s", name, data)
+		}
+		if strings.Contains(string(data), "plain-token") || strings.Contains(string(data), "cached-token") {
+			t.Errorf("%s leaks secrets:\n%s", name, data)
+		}
+	}
+
+	// A fresh manager stays locked until unlocked
+	m2 := NewManager(dir)
+	if err := m2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !m2.Locked() || !m2.Encrypted() {
+		t.Fatal("expected manager to be locked")
+	}
+	if _, err := m2.Get("api"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if err := m2.Add(&AuthPreset{Name: "x", Type: "bearer"}); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if err := m2.Unlock("wrong"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("expected ErrIncorrectPassphrase, got %v", err)
+	}
+	if err := m2.Unlock("pass"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	preset, err := m2.Get("api")
+	if err != nil || preset.Token != "plain-token" {
+		t.Fatalf("expected decrypted preset, got %+v (%v)", preset, err)
+	}
+	token, err := m2.Tokens().Get("api")
+	if err != nil || token == nil || token.AccessToken != "cached-token" {
+		t.Fatalf("expected decrypted token, got %+v (%v)", token, err)
+	}
+
+	// Later saves stay encrypted
+	if err := m2.Add(&AuthPreset{Name: "other", Type: "bearer", Token: "other-token"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".gosh", "auth.yaml"))
+	if strings.Contains(string(data), "other-token") {
+		t.Errorf("expected encrypted save, got:\n%s", data)
+	}
+}
+
+func TestManagerDecrypt(t *testing.T) {
+	withFastKDF(t)
+	dir := t.TempDir()
+	m := NewManager(dir)
+	if err := m.Add(&AuthPreset{Name: "api", Type: "bearer", Token: "plain-token"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Encrypt("pass"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Tokens().Put("api", &Token{AccessToken: "cached-token"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Decrypt(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, name := range []string{"auth.yaml", "tokens.yaml"} {
+		data, _ := os.ReadFile(filepath.Join(dir, ".gosh", name))
+		if strings.Contains(string(data), "encrypted:") {
+			t.Errorf("expected plaintext %s, got:\n%s", name, data)
+		}
+	}
+
+	m2 := NewManager(dir)
+	if err := m2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if m2.Encrypted() {
+		t.Error("expected plaintext presets")
+	}
+	if token, _ := m2.Tokens().Get("api"); token == nil || token.AccessToken != "cached-token" {
+		t.Errorf("expected plaintext token, got %+v", token)
+	}
+}
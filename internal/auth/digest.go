@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestAlgorithms maps supported algorithms to their hash, strongest first
+var digestAlgorithms = []struct {
+	name string
+	hash func() hash.Hash
+}{
+	{"SHA-256", sha256.New},
+	{"MD5", md5.New},
+}
+
+// newCnonce is replaced in tests
+var newCnonce = func() string {
+	cnonce, err := randomString(16)
+	if err != nil {
+		return "0a4f113b"
+	}
+	return cnonce
+}
+
+// digestChallenge is a parsed "WWW-Authenticate: Digest ..." challenge
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string // e.g. "MD5", "SHA-256-sess"
+	qop       string // "auth", "auth-int" or empty for RFC 2069 servers
+	userhash  bool
+}
+
+// digestState remembers the last challenge so later requests can answer it
+// without another round trip
+type digestState struct {
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        int
+}
+
+// applyDigest authorizes the request when a challenge has been seen already.
+// The first request goes out without credentials; Respond answers the 401.
+func (p *AuthPreset) applyDigest(req *http.Request) error {
+	if p.Username == "" {
+		return fmt.Errorf("digest auth requires username")
+	}
+
+	if p.digest == nil {
+		return nil
+	}
+
+	p.digest.mu.Lock()
+	defer p.digest.mu.Unlock()
+	return p.authorizeDigest(req, p.digest.challenge)
+}
+
+// Respond answers an authentication challenge in a 401 response by
+// authorizing req, a copy of the request to send again. It reports false
+// when the preset doesn't use challenges or the challenge can't be answered.
+func (p *AuthPreset) Respond(req *http.Request, resp *http.Response) (bool, error) {
+	if resp.StatusCode != http.StatusUnauthorized || AuthType(strings.ToLower(p.Type)) != AuthTypeDigest {
+		return false, nil
+	}
+
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil {
+		return false, nil
+	}
+
+	p.digest = &digestState{challenge: challenge}
+	p.digest.mu.Lock()
+	defer p.digest.mu.Unlock()
+	if err := p.authorizeDigest(req, challenge); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// authorizeDigest sets the Authorization header for a challenge (RFC 7616
+// section 3.4). The caller holds p.digest.mu.
+func (p *AuthPreset) authorizeDigest(req *http.Request, c *digestChallenge) error {
+	newHash := digestHash(c.algorithm)
+	if newHash == nil {
+		return fmt.Errorf("unsupported digest algorithm: %s", c.algorithm)
+	}
+	h := func(parts ...string) string {
+		hasher := newHash()
+		io.WriteString(hasher, strings.Join(parts, ":"))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	p.digest.nc++
+	nc := fmt.Sprintf("%08x", p.digest.nc)
+	cnonce := newCnonce()
+	uri := req.URL.RequestURI()
+
+	ha1 := h(p.Username, c.realm, p.Password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+
+	ha2 := h(req.Method, uri)
+	if c.qop == "auth-int" {
+		bodyHash, err := hashRequestBody(req, newHash)
+		if err != nil {
+			return err
+		}
+		ha2 = h(req.Method, uri, bodyHash)
+	}
+
+	var response string
+	if c.qop == "" {
+		response = h(ha1, c.nonce, ha2)
+	} else {
+		response = h(ha1, c.nonce, nc, cnonce, c.qop, ha2)
+	}
+
+	username := p.Username
+	if c.userhash {
+		username = h(p.Username, c.realm)
+	}
+
+	params := []string{
+		"username=" + quoteString(username),
+		"realm=" + quoteString(c.realm),
+		"nonce=" + quoteString(c.nonce),
+		"uri=" + quoteString(uri),
+	}
+	if c.algorithm != "" {
+		params = append(params, "algorithm="+c.algorithm)
+	}
+	params = append(params, "response="+quoteString(response))
+	if c.opaque != "" {
+		params = append(params, "opaque="+quoteString(c.opaque))
+	}
+	if c.qop != "" {
+		params = append(params, "qop="+c.qop, "nc="+nc, "cnonce="+quoteString(cnonce))
+	}
+	if c.userhash {
+		params = append(params, "userhash=true")
+	}
+
+	req.Header.Set("Authorization", "Digest "+strings.Join(params, ", "))
+	return nil
+}
+
+// quoteString returns s as an RFC 7230 quoted-string. Only '"' and '\' are
+// escaped; other bytes, including UTF-8, are sent as they are so the server
+// hashes the same value.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// digestHash returns the hash for an algorithm name; MD5 when unset
+func digestHash(algorithm string) func() hash.Hash {
+	name := strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS")
+	if name == "" {
+		name = "MD5"
+	}
+	for _, alg := range digestAlgorithms {
+		if alg.name == name {
+			return alg.hash
+		}
+	}
+	return nil
+}
+
+// parseDigestChallenge picks the strongest supported Digest challenge from
+// WWW-Authenticate header values
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	bestRank := len(digestAlgorithms)
+
+	for _, value := range values {
+		for _, ch := range parseChallenges(value) {
+			if !strings.EqualFold(ch.scheme, "digest") || ch.params["nonce"] == "" {
+				continue
+			}
+
+			c := &digestChallenge{
+				realm:     ch.params["realm"],
+				nonce:     ch.params["nonce"],
+				opaque:    ch.params["opaque"],
+				algorithm: ch.params["algorithm"],
+				userhash:  strings.EqualFold(ch.params["userhash"], "true"),
+			}
+
+			// Prefer auth; auth-int only when it's all the server offers
+			for _, qop := range strings.Split(ch.params["qop"], ",") {
+				switch strings.TrimSpace(qop) {
+				case "auth":
+					c.qop = "auth"
+				case "auth-int":
+					if c.qop == "" {
+						c.qop = "auth-int"
+					}
+				}
+			}
+
+			rank := digestRank(c.algorithm)
+			if rank < bestRank {
+				best, bestRank = c, rank
+			}
+		}
+	}
+	return best
+}
+
+// digestRank orders algorithms by strength; unsupported ones rank last
+func digestRank(algorithm string) int {
+	name := strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS")
+	if name == "" {
+		name = "MD5"
+	}
+	for i, alg := range digestAlgorithms {
+		if alg.name == name {
+			return i
+		}
+	}
+	return len(digestAlgorithms)
+}
+
+// challenge is one auth scheme with its parameters
+type challenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseChallenges splits a WWW-Authenticate value into challenges. A value
+// may hold several, e.g. `Basic realm="a", Digest realm="b", nonce="c"`.
+func parseChallenges(value string) []challenge {
+	var challenges []challenge
+	s := value
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return challenges
+		}
+
+		name, rest := splitToken(s)
+		if name == "" {
+			return challenges
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		// A token not followed by "=" starts a new challenge
+		if !strings.HasPrefix(rest, "=") || len(challenges) == 0 {
+			if strings.HasPrefix(rest, "=") {
+				return challenges
+			}
+			challenges = append(challenges, challenge{scheme: name, params: map[string]string{}})
+			s = rest
+			continue
+		}
+
+		var val string
+		rest = strings.TrimLeft(rest[1:], " \t")
+		if strings.HasPrefix(rest, `"`) {
+			val, rest = splitQuoted(rest)
+		} else {
+			val, rest = splitToken(rest)
+		}
+		challenges[len(challenges)-1].params[strings.ToLower(name)] = val
+		s = rest
+	}
+}
+
+// splitToken splits a leading token off s
+func splitToken(s string) (string, string) {
+	i := strings.IndexAny(s, " \t,=\"")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// splitQuoted splits a leading quoted string off s, unescaping it
+func splitQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// challengeResponse builds a 401 response carrying the given challenges
+func challengeResponse(challenges ...string) *http.Response {
+	resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}}
+	for _, c := range challenges {
+		resp.Header.Add("WWW-Authenticate", c)
+	}
+	return resp
+}
+
+func withCnonce(t *testing.T, cnonce string) {
+	saved := newCnonce
+	newCnonce = func() string { return cnonce }
+	t.Cleanup(func() { newCnonce = saved })
+}
+
+// digestParam extracts a parameter from an Authorization header
+func digestParam(header, name string) string {
+	for _, ch := range parseChallenges(header) {
+		return ch.params[name]
+	}
+	return ""
+}
+
+// RFC 7616 section 3.9.1
+func TestDigestRFC7616(t *testing.T) {
+	withCnonce(t, "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ")
+	challenge := `realm="http-auth@example.org", qop="auth, auth-int", ` +
+		`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+		`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+
+	tests := []struct {
+		algorithm string
+		response  string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "http://www.example.org/dir/index.html", nil)
+		preset := &AuthPreset{Type: "digest", Username: "Mufasa", Password: "Circle of Life"}
+
+		ok, err := preset.Respond(req, challengeResponse("Digest algorithm="+tt.algorithm+", "+challenge))
+		if err != nil || !ok {
+			t.Fatalf("%s: expected challenge answered, got %v, %v", tt.algorithm, ok, err)
+		}
+
+		header := req.Header.Get("Authorization")
+		if got := digestParam(header, "response"); got != tt.response {
+			t.Errorf("%s: expected response %s, got %s", tt.algorithm, tt.response, got)
+		}
+		for _, part := range []string{`uri="/dir/index.html"`, "qop=auth,", "nc=00000001", `opaque="FQhe/`} {
+			if !strings.Contains(header, part) {
+				t.Errorf("%s: expected %s in %s", tt.algorithm, part, header)
+			}
+		}
+	}
+}
+
+// RFC 2617 section 3.5
+func TestDigestRFC2617(t *testing.T) {
+	withCnonce(t, "0a4f113b")
+	req, _ := http.NewRequest("GET", "http://www.nowhere.org/dir/index.html", nil)
+	preset := &AuthPreset{Type: "digest", Username: "Mufasa", Password: "Circle Of Life"}
+
+	ok, err := preset.Respond(req, challengeResponse(`Digest realm="testrealm@host.com", qop="auth,auth-int", `+
+		`nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`))
+	if err != nil || !ok {
+		t.Fatalf("expected challenge answered, got %v, %v", ok, err)
+	}
+	if got := digestParam(req.Header.Get("Authorization"), "response"); got != "6629fae49393a05397450978507c4ef1" {
+		t.Errorf("expected response 6629fae49393a05397450978507c4ef1, got %s", got)
+	}
+}
+
+func TestDigestSessAndAuthInt(t *testing.T) {
+	withCnonce(t, "abc")
+	req, _ := http.NewRequest("POST", "http://example.com/upload?x=1", strings.NewReader("data"))
+	preset := &AuthPreset{Type: "digest", Username: "u", Password: "p"}
+
+	ok, err := preset.Respond(req, challengeResponse(`Digest realm="r", nonce="n", qop="auth-int", algorithm=SHA-256-sess`))
+	if err != nil || !ok {
+		t.Fatalf("expected challenge answered, got %v, %v", ok, err)
+	}
+
+	header := req.Header.Get("Authorization")
+	for _, part := range []string{"algorithm=SHA-256-sess", "qop=auth-int", `uri="/upload?x=1"`} {
+		if !strings.Contains(header, part) {
+			t.Errorf("expected %s in %s", part, header)
+		}
+	}
+	// Computed independently: HA1 = H(H(u:r:p):n:abc), HA2 = H(POST:/upload?x=1:H(data))
+	if got := digestParam(header, "response"); got != "33aa51cb4f8c2a31da78a712bddb71f84b66105c5e8cd088be15279c33211fda" {
+		t.Errorf("unexpected response %s", got)
+	}
+}
+
+func TestDigestQuotedStrings(t *testing.T) {
+	withCnonce(t, "abc")
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	preset := &AuthPreset{Type: "digest", Username: "Jürgen", Password: "pw"}
+
+	ok, err := preset.Respond(req, challengeResponse("Digest realm=\"a \\\"quoted\\\"\t\\\\realm\", nonce=\"n\", qop=\"auth\""))
+	if err != nil || !ok {
+		t.Fatalf("expected challenge answered, got %v, %v", ok, err)
+	}
+	header := req.Header.Get("Authorization")
+	for _, part := range []string{`username="Jürgen"`, "realm=\"a \\\"quoted\\\"\t\\\\realm\""} {
+		if !strings.Contains(header, part) {
+			t.Errorf("expected %s in %s", part, header)
+		}
+	}
+	if got := digestParam(header, "username"); got != "Jürgen" {
+		t.Errorf("expected username Jürgen, got %q", got)
+	}
+}
+
+func TestDigestReusesChallenge(t *testing.T) {
+	withCnonce(t, "abc")
+	preset := &AuthPreset{Type: "digest", Username: "u", Password: "p"}
+
+	// No challenge seen yet: the first request goes out unauthenticated
+	req, _ := http.NewRequest("GET", "http://example.com/a", nil)
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("expected no Authorization before a challenge")
+	}
+
+	preset.Respond(req, challengeResponse(`Digest realm="r", nonce="n", qop="auth"`))
+
+	// Later requests answer the same challenge with the next nonce count
+	next, _ := http.NewRequest("GET", "http://example.com/b", nil)
+	if err := preset.Apply(next); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if nc := digestParam(next.Header.Get("Authorization"), "nc"); nc != "00000002" {
+		t.Errorf("expected nc 00000002, got %q", nc)
+	}
+}
+
+func TestDigestChallengeSelection(t *testing.T) {
+	c := parseDigestChallenge([]string{
+		`Basic realm="basic"`,
+		`Digest realm="r", nonce="md5", algorithm=MD5, qop="auth"`,
+		`Digest realm="r", nonce="sha", algorithm=SHA-256, qop="auth"`,
+		`Digest realm="r", nonce="sha512", algorithm=SHA-512-256, qop="auth"`,
+	})
+	if c == nil || c.nonce != "sha" {
+		t.Errorf("expected SHA-256 challenge, got %+v", c)
+	}
+
+	// Several challenges in one header, with an escaped quote
+	c = parseDigestChallenge([]string{`Basic realm="a, b", Digest realm="say \"hi\"", nonce="x", stale=true`})
+	if c == nil || c.realm != `say "hi"` || c.nonce != "x" || c.qop != "" {
+		t.Errorf("unexpected challenge %+v", c)
+	}
+
+	if parseDigestChallenge([]string{`Bearer realm="api"`}) != nil {
+		t.Error("expected no digest challenge")
+	}
+}
+
+func TestDigestIgnoresOtherTypes(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	preset := &AuthPreset{Type: "bearer", Token: "x"}
+
+	ok, err := preset.Respond(req, challengeResponse(`Digest realm="r", nonce="n"`))
+	if ok || err != nil {
+		t.Errorf("expected challenge ignored, got %v, %v", ok, err)
+	}
+}
+
+func TestDigestRequiresUsername(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	preset := &AuthPreset{Type: "digest"}
+	if err := preset.Apply(req); err == nil {
+		t.Error("expected error for missing username")
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newLayeredManager creates a workspace manager over a global one
+func newLayeredManager(t *testing.T) (*Manager, *Manager) {
+	t.Helper()
+	workspace := NewManager(t.TempDir())
+	global := NewGlobalManager(filepath.Join(t.TempDir(), "gosh"))
+	workspace.SetGlobal(global)
+	return workspace, global
+}
+
+func TestGlobalPresetInheritance(t *testing.T) {
+	workspace, global := newLayeredManager(t)
+	if err := global.Add(&AuthPreset{Name: "github", Type: "bearer", Token: "global-token"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := global.Add(&AuthPreset{Name: "sso", Type: "bearer", Token: "global-sso"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := workspace.Add(&AuthPreset{Name: "sso", Type: "bearer", Token: "workspace-sso"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh global manager reads the user-level file
+	reloaded := NewGlobalManager(global.root)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	workspace.SetGlobal(reloaded)
+
+	preset, err := workspace.Get("github")
+	if err != nil || preset.Token != "global-token" {
+		t.Fatalf("expected global preset, got %+v (%v)", preset, err)
+	}
+	if preset.root != global.root {
+		t.Errorf("expected global preset to resolve paths in %s, got %s", global.root, preset.root)
+	}
+	if preset, _ := workspace.Get("sso"); preset.Token != "workspace-sso" {
+		t.Errorf("expected workspace preset to win, got %q", preset.Token)
+	}
+	if _, err := workspace.Get("missing"); err == nil || err.Error() != "auth preset not found: missing" {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	if err := workspace.Remove("github"); err == nil || !strings.Contains(err.Error(), "--global") {
+		t.Errorf("expected hint to use --global, got %v", err)
+	}
+}
+
+func TestGlobalPresetSources(t *testing.T) {
+	workspace, global := newLayeredManager(t)
+	_ = global.Add(&AuthPreset{Name: "github", Type: "oauth2"})
+	_ = global.Add(&AuthPreset{Name: "sso", Type: "bearer"})
+	_ = workspace.Add(&AuthPreset{Name: "sso", Type: "basic"})
+	_ = workspace.Add(&AuthPreset{Name: "api", Type: "bearer"})
+
+	var got []string
+	for _, info := range workspace.Presets() {
+		entry := info.Name + ":" + info.Source
+		if info.Overridden {
+			entry += ":overridden"
+		}
+		got = append(got, entry)
+	}
+	expected := "api:workspace github:global sso:workspace sso:global:overridden"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestGlobalPresetTokens(t *testing.T) {
+	workspace, global := newLayeredManager(t)
+	_ = global.Add(&AuthPreset{Name: "github", Type: "oauth2", TokenURL: "https://example.com/token"})
+	if err := global.Tokens().Put("github", &Token{AccessToken: "t"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens for global presets live in the global store
+	preset, err := workspace.Get("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := preset.tokens.Get("github"); token == nil || token.AccessToken != "t" {
+		t.Errorf("expected the global token store, got %+v", token)
+	}
+
+	if err := workspace.Logout("github"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token, _ := global.Tokens().Get("github"); token != nil {
+		t.Errorf("expected logout to clear the global token, got %+v", token)
+	}
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commandSuffix marks credential helper keys such as token_command, an
+// alias for an entry under commands
+const commandSuffix = "_command"
+
+// helperCache keeps credential helper output in memory for a preset's
+// command_ttl. It is never written to disk.
+type helperCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedSecret
+}
+
+// cachedSecret is a helper's output and when it goes stale
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// newHelperCache creates an empty cache
+func newHelperCache() *helperCache {
+	return &helperCache{entries: make(map[string]cachedSecret)}
+}
+
+// run returns the cached output of a command, running it when missing or
+// stale. A zero ttl disables caching.
+func (c *helperCache) run(command, dir string, ttl time.Duration) (string, error) {
+	key := dir + "\x00" + command
+	if c != nil && ttl > 0 {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		c.mu.Unlock()
+		if ok && now().Before(entry.expires) {
+			return entry.value, nil
+		}
+	}
+
+	value, err := runSecretCommand(command, dir)
+	if err != nil {
+		return "", err
+	}
+
+	if c != nil && ttl > 0 {
+		c.mu.Lock()
+		c.entries[key] = cachedSecret{value: value, expires: now().Add(ttl)}
+		c.mu.Unlock()
+	}
+	return value, nil
+}
+
+// runSecretCommand runs a shell command in dir and returns its output
+// without the trailing newline. Stdin and stderr stay attached so password
+// managers can prompt; stderr is also quoted in the error if it fails.
+func runSecretCommand(command, dir string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("empty secret command")
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		if msg := lastLine(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command %q failed: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("command %q failed: %w", command, err)
+	}
+
+	value := strings.TrimRight(stdout.String(), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("command %q printed nothing", command)
+	}
+	return value, nil
+}
+
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// commandTTL parses the preset's command_ttl
+func (p *AuthPreset) commandTTL() (time.Duration, error) {
+	if p.CommandTTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(p.CommandTTL)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid command_ttl: %s", p.CommandTTL)
+	}
+	return ttl, nil
+}
+
+// runHelpers sets each field named in the preset's commands to the
+// command's output
+func (p *AuthPreset) runHelpers(cache *helperCache, ttl time.Duration) error {
+	for name, command := range p.Commands {
+		field := p.stringField(name)
+		if field == nil {
+			return fmt.Errorf("commands: no such field %s", name)
+		}
+
+		value, err := cache.run(command, p.root, ttl)
+		if err != nil {
+			return fmt.Errorf("credential helper for %s: %w", name, err)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// stringField finds a string field by its YAML name, ignoring case, '_'
+// and '-' (so client_secret matches clientSecret)
+func (p *AuthPreset) stringField(name string) *string {
+	i := p.fieldIndex(name)
+	if i < 0 {
+		return nil
+	}
+	return reflect.ValueOf(p).Elem().Field(i).Addr().Interface().(*string)
+}
+
+// fieldName returns the YAML name of the string field stringField finds,
+// or "" when there is none
+func (p *AuthPreset) fieldName(name string) string {
+	i := p.fieldIndex(name)
+	if i < 0 {
+		return ""
+	}
+	tag, _, _ := strings.Cut(reflect.TypeOf(p).Elem().Field(i).Tag.Get("yaml"), ",")
+	return tag
+}
+
+// fieldIndex returns the index of the string field with a YAML name, or -1
+func (p *AuthPreset) fieldIndex(name string) int {
+	normalize := strings.NewReplacer("_", "", "-", "")
+	want := strings.ToLower(normalize.Replace(name))
+	if want == "name" || want == "type" {
+		return -1
+	}
+
+	t := reflect.TypeOf(p).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type.Kind() != reflect.String {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if strings.ToLower(normalize.Replace(tag)) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+// UnmarshalYAML also accepts FIELD_command keys (e.g. token_command) as
+// credential helpers. Unlike other unknown keys, one that names no field is
+// an error rather than being ignored.
+func (p *AuthPreset) UnmarshalYAML(node *yaml.Node) error {
+	type plain AuthPreset
+	if err := node.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name, ok := strings.CutSuffix(key.Value, commandSuffix)
+		if !ok || name == "" {
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: %s must be a command", key.Line, key.Value)
+		}
+		if err := p.SetCommand(name, value.Value); err != nil {
+			return fmt.Errorf("line %d: %s: %w", key.Line, key.Value, err)
+		}
+	}
+	return nil
+}
+
+// HasCommand reports whether a field is sourced from a credential helper
+func (p *AuthPreset) HasCommand(name string) bool {
+	return hasField(p, p.Commands, name)
+}
+
+// hasField reports whether sources, keyed by field name, has the field
+func hasField(p *AuthPreset, sources map[string]string, name string) bool {
+	field := p.stringField(name)
+	for key := range sources {
+		if field != nil && p.stringField(key) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCommand sources a field from a credential helper command
+func (p *AuthPreset) SetCommand(name, command string) error {
+	key := p.fieldName(name)
+	if key == "" {
+		return fmt.Errorf("unknown auth preset field: %s", name)
+	}
+	if p.Commands == nil {
+		p.Commands = make(map[string]string)
+	}
+	p.Commands[key] = command
+	return nil
+}
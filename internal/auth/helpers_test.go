@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeAuthYAML writes a workspace auth config and loads it
+func writeAuthYAML(t *testing.T, config string) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".gosh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, ".gosh", "auth.yaml")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(dir)
+	if err := m.Load(); err != nil {
+		t.Fatalf("expected no error loading, got %v", err)
+	}
+	return m, path
+}
+
+func TestCredentialHelpers(t *testing.T) {
+	m, path := writeAuthYAML(t, `presets:
+  api:
+    name: api
+    type: bearer
+    commands:
+      token: printf 'helper-token\n'
+  oauth:
+    name: oauth
+    type: oauth2
+    tokenUrl: https://auth.example.com/token
+    clientId: app
+    commands:
+      client_secret: echo from-helper
+`)
+
+	preset, err := m.Get("api")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if preset.Token != "helper-token" {
+		t.Errorf("expected helper-token, got %q", preset.Token)
+	}
+
+	oauth, err := m.Get("oauth")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if oauth.ClientSecret != "from-helper" || oauth.ClientID != "app" {
+		t.Errorf("expected helper client secret, got %+v", oauth)
+	}
+
+	// Saving keeps the commands, never their output
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "token: helper-token") || strings.Contains(string(data), "clientSecret:") {
+		t.Errorf("resolved values were persisted:\n%s", data)
+	}
+	if !strings.Contains(string(data), "token: printf") || !strings.Contains(string(data), "client_secret: echo") {
+		t.Errorf("expected helper commands to be saved:\n%s", data)
+	}
+}
+
+func TestCredentialHelperTTL(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	// Each run appends to a file, so its length counts the runs
+	m, _ := writeAuthYAML(t, `presets:
+  cached:
+    name: cached
+    type: bearer
+    commands:
+      token: echo x >> runs; wc -c < runs
+    command_ttl: 1m
+  uncached:
+    name: uncached
+    type: bearer
+    commands:
+      token: echo y >> other; wc -c < other
+`)
+
+	token := func(name string) string {
+		preset, err := m.Get(name)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return strings.TrimSpace(preset.Token)
+	}
+
+	if first, second := token("cached"), token("cached"); first != "2" || second != "2" {
+		t.Errorf("expected one run within the TTL, got %q then %q", first, second)
+	}
+	clock = clock.Add(2 * time.Minute)
+	if third := token("cached"); third != "4" {
+		t.Errorf("expected a new run after the TTL, got %q", third)
+	}
+
+	if first, second := token("uncached"), token("uncached"); first != "2" || second != "4" {
+		t.Errorf("expected a run per use without a TTL, got %q then %q", first, second)
+	}
+}
+
+func TestCredentialHelperErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		expected string
+	}{
+		{"failing command", `commands: {token: "echo 'vault: permission denied' >&2; exit 2"}`, "credential helper for token: command \"echo 'vault: permission denied' >&2; exit 2\" failed: exit status 2: vault: permission denied"},
+		{"no output", "commands: {token: 'true'}", "printed nothing"},
+		{"unknown field", "commands: {colour: echo red}", "commands: no such field colour"},
+		{"not a field", "commands: {commands: echo x}", "commands: no such field commands"},
+		{"bad ttl", "commands: {token: echo x}\n    command_ttl: soon", "invalid command_ttl"},
+	}
+
+	for _, tt := range tests {
+		m, _ := writeAuthYAML(t, "presets:\n  api:\n    name: api\n    type: bearer\n    "+tt.preset+"\n")
+		_, err := m.Get("api")
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestCredentialHelperKeys(t *testing.T) {
+	// Keys other than commands are left alone, whatever their type
+	m, _ := writeAuthYAML(t, `presets:
+  api:
+    name: api
+    type: bearer
+    token: literal
+    retries: 3
+    extra: [a, b]
+`)
+	preset, err := m.Get("api")
+	if err != nil || preset.Token != "literal" || len(preset.Commands) != 0 {
+		t.Fatalf("expected the literal token and no commands, got %+v, %v", preset, err)
+	}
+
+	if err := preset.SetCommand("client-secret", "echo x"); err != nil {
+		t.Fatal(err)
+	}
+	if preset.Commands["clientSecret"] != "echo x" {
+		t.Errorf("expected the command under the field's YAML name, got %v", preset.Commands)
+	}
+	if !preset.HasCommand("client_secret") || preset.HasCommand("token") {
+		t.Errorf("unexpected HasCommand results for %v", preset.Commands)
+	}
+	if err := preset.SetCommand("colour", "echo red"); err == nil {
+		t.Error("expected error for an unknown field")
+	}
+}
+
+func TestCredentialHelperAliases(t *testing.T) {
+	// The FIELD_command form from the README
+	m, path := writeAuthYAML(t, `presets:
+  prod:
+    name: prod
+    type: bearer
+    token_command: printf 'helper-token\n'
+  svc:
+    name: svc
+    type: oauth2
+    tokenUrl: https://auth.example.com/oauth/token
+    clientId: my-app
+    client_secret_command: echo from-helper
+    command_ttl: 10m
+`)
+
+	prod, err := m.Get("prod")
+	if err != nil || prod.Token != "helper-token" {
+		t.Fatalf("expected the helper's token, got %+v, %v", prod, err)
+	}
+	svc, err := m.Get("svc")
+	if err != nil || svc.ClientSecret != "from-helper" || svc.CommandTTL != "10m" {
+		t.Fatalf("expected the helper's client secret, got %+v, %v", svc, err)
+	}
+
+	// Saved in the commands map
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "_command:") || !strings.Contains(string(data), "clientSecret: echo from-helper") {
+		t.Errorf("expected helpers under commands:\n%s", data)
+	}
+}
+
+func TestCredentialHelperAliasErrors(t *testing.T) {
+	tests := []struct {
+		preset   string
+		expected string
+	}{
+		{"tokn_command: echo x", "tokn_command: unknown auth preset field: tokn"},
+		{"token_command: [pass, show]", "token_command must be a command"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, ".gosh"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		config := "presets:\n  api:\n    name: api\n    type: bearer\n    " + tt.preset + "\n"
+		if err := os.WriteFile(filepath.Join(dir, ".gosh", "auth.yaml"), []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		err := NewManager(dir).Load()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.preset, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for hmac auth
+const (
+	DefaultHMACTemplate        = `{method}\n{path}\n{timestamp}\n{body_sha256}`
+	DefaultHMACHeader          = "X-Signature"
+	DefaultHMACTimestampHeader = "X-Timestamp"
+	DefaultHMACNonceHeader     = "X-Nonce"
+)
+
+// hmacAlgorithms maps algorithm names to their hash
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// templateEscaper turns escapes written in YAML single quotes or on the
+// command line into the characters they stand for
+var templateEscaper = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`)
+
+// applyHMAC signs a canonical string built from the template and sets the
+// signature, timestamp and nonce headers
+func (p *AuthPreset) applyHMAC(req *http.Request) error {
+	if p.Secret == "" {
+		return fmt.Errorf("hmac auth requires secret")
+	}
+
+	algorithm := strings.ToLower(strings.ReplaceAll(p.Algorithm, "-", ""))
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	newHash, ok := hmacAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported hmac algorithm: %s (expected sha256 or sha512)", p.Algorithm)
+	}
+
+	timestamp, err := formatTimestamp(now(), p.TimestampFormat)
+	if err != nil {
+		return err
+	}
+
+	template := p.Template
+	if template == "" {
+		template = DefaultHMACTemplate
+	}
+	nonce := ""
+	if strings.Contains(template, "{nonce}") {
+		if nonce, err = randomString(16); err != nil {
+			return err
+		}
+	}
+
+	canonical, err := expandTemplate(templateEscaper.Replace(template), req, timestamp, nonce)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(newHash, []byte(p.Secret))
+	mac.Write([]byte(canonical))
+	signature, err := encodeSignature(mac.Sum(nil), p.Encoding)
+	if err != nil {
+		return err
+	}
+
+	header := p.Header
+	if header == "" {
+		header = DefaultHMACHeader
+	}
+	timestampHeader := p.TimestampHeader
+	if timestampHeader == "" {
+		timestampHeader = DefaultHMACTimestampHeader
+	}
+
+	req.Header.Set(timestampHeader, timestamp)
+	if nonce != "" {
+		req.Header.Set(DefaultHMACNonceHeader, nonce)
+	}
+	req.Header.Set(header, p.Prefix+signature)
+	return nil
+}
+
+// expandTemplate replaces {placeholders} with parts of the request:
+// {method}, {path}, {query}, {host}, {url}, {timestamp}, {nonce}, {body},
+// {body_sha256}, {body_sha512} and {header:Name}
+func expandTemplate(template string, req *http.Request, timestamp, nonce string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(template, "{")
+		if start < 0 {
+			out.WriteString(template)
+			return out.String(), nil
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in hmac template: %s", template[start:])
+		}
+		end += start
+
+		out.WriteString(template[:start])
+		value, err := templateValue(template[start+1:end], req, timestamp, nonce)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(value)
+		template = template[end+1:]
+	}
+}
+
+// templateValue returns the value of a single placeholder
+func templateValue(name string, req *http.Request, timestamp, nonce string) (string, error) {
+	if header, ok := strings.CutPrefix(name, "header:"); ok {
+		return req.Header.Get(header), nil
+	}
+
+	switch name {
+	case "method":
+		return req.Method, nil
+	case "path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "query":
+		return req.URL.RawQuery, nil
+	case "host":
+		if req.Host != "" {
+			return req.Host, nil
+		}
+		return req.URL.Host, nil
+	case "url":
+		return req.URL.String(), nil
+	case "timestamp":
+		return timestamp, nil
+	case "nonce":
+		return nonce, nil
+	case "body":
+		body, err := readRequestBody(req)
+		return string(body), err
+	case "body_sha256":
+		return hashRequestBody(req, sha256.New)
+	case "body_sha512":
+		return hashRequestBody(req, sha512.New)
+	default:
+		return "", fmt.Errorf("unknown placeholder in hmac template: {%s}", name)
+	}
+}
+
+// formatTimestamp formats t as unix seconds (the default), unix-ms,
+// rfc3339 or an HTTP date
+func formatTimestamp(t time.Time, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unix-ms":
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	case "rfc3339":
+		return t.UTC().Format(time.RFC3339), nil
+	case "http":
+		return t.UTC().Format(http.TimeFormat), nil
+	default:
+		return "", fmt.Errorf("unknown timestamp format: %s (expected unix, unix-ms, rfc3339 or http)", format)
+	}
+}
+
+// encodeSignature encodes a MAC as hex (the default), base64 or base64url
+func encodeSignature(sum []byte, encoding string) (string, error) {
+	switch strings.ToLower(encoding) {
+	case "", "hex":
+		return hex.EncodeToString(sum), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(sum), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	default:
+		return "", fmt.Errorf("unknown signature encoding: %s (expected hex, base64 or base64url)", encoding)
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withClock(t *testing.T, clock time.Time) {
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestHMACDefaultTemplate(t *testing.T) {
+	withClock(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	req, _ := http.NewRequest("POST", "https://api.example.com/v1/orders?x=1", strings.NewReader(`{"id":1}`))
+	preset := &AuthPreset{Type: "hmac", Secret: "topsecret"}
+
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Computed independently over "POST\n/v1/orders\n1704164645\n" + sha256(body)
+	if sig := req.Header.Get("X-Signature"); sig != "31e28e78ac14909e1c69c760fcba1097374e74518c4bb5557406c9195ec96747" {
+		t.Errorf("unexpected signature %q", sig)
+	}
+	if ts := req.Header.Get("X-Timestamp"); ts != "1704164645" {
+		t.Errorf("expected timestamp 1704164645, got %q", ts)
+	}
+}
+
+func TestHMACEmptyBody(t *testing.T) {
+	withClock(t, time.Unix(1704164645, 0))
+	req, _ := http.NewRequest("GET", "https://api.example.com", nil)
+	preset := &AuthPreset{Type: "hmac", Secret: "topsecret"}
+
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if sig := req.Header.Get("X-Signature"); sig != "74035dceb67d5435753cf50d8f1ea9ec5491638b79025f905b5665ffc53f3bb2" {
+		t.Errorf("unexpected signature %q", sig)
+	}
+}
+
+func TestHMACCustomTemplate(t *testing.T) {
+	withClock(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	req, _ := http.NewRequest("POST", "https://api.example.com/v1/orders?x=1", strings.NewReader(`{"id":1}`))
+	req.Header.Set("X-Client", "abc")
+	preset := &AuthPreset{
+		Type:            "hmac",
+		Secret:          "topsecret",
+		Algorithm:       "SHA-512",
+		Encoding:        "base64",
+		Template:        `{method} {path}?{query}\n{header:X-Client}\n{body}\n{timestamp}`,
+		Header:          "Authorization",
+		Prefix:          "HMAC ",
+		TimestampHeader: "Date",
+		TimestampFormat: "rfc3339",
+	}
+
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := "HMAC hzubn1/jenM5Z236isoc9ll5k/s4L1Zc/yFHhqQmrq5ZUytlyiffHtyLUJDUxV2olQ5Z2IM+wc05BbJKT/5PDg=="
+	if auth := req.Header.Get("Authorization"); auth != expected {
+		t.Errorf("expected %q, got %q", expected, auth)
+	}
+	if date := req.Header.Get("Date"); date != "2024-01-02T03:04:05Z" {
+		t.Errorf("expected RFC 3339 timestamp, got %q", date)
+	}
+}
+
+func TestHMACNonce(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://api.example.com/", nil)
+	preset := &AuthPreset{Type: "hmac", Secret: "s", Template: `{nonce}:{timestamp}`}
+
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if req.Header.Get("X-Nonce") == "" {
+		t.Error("expected X-Nonce header")
+	}
+}
+
+func TestHMACErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset AuthPreset
+	}{
+		{"missing secret", AuthPreset{Type: "hmac"}},
+		{"unknown algorithm", AuthPreset{Type: "hmac", Secret: "s", Algorithm: "md5"}},
+		{"unknown encoding", AuthPreset{Type: "hmac", Secret: "s", Encoding: "base32"}},
+		{"unknown placeholder", AuthPreset{Type: "hmac", Secret: "s", Template: "{verb}"}},
+		{"unterminated placeholder", AuthPreset{Type: "hmac", Secret: "s", Template: "{method"}},
+		{"unknown timestamp format", AuthPreset{Type: "hmac", Secret: "s", TimestampFormat: "iso"}},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "https://api.example.com/", nil)
+		if err := tt.preset.Apply(req); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultJWTLifetime is how long minted tokens are valid
+const DefaultJWTLifetime = 5 * time.Minute
+
+// applyJWT mints a fresh token for every request and sends it as a bearer
+// token
+func (p *AuthPreset) applyJWT(req *http.Request) error {
+	token, err := p.MintJWT()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// MintJWT signs the preset's claims. iat, exp and jti are added unless the
+// claims set them, and string claims may use the placeholders {now},
+// {now+DURATION}, {now-DURATION}, {exp} and {uuid}.
+func (p *AuthPreset) MintJWT() (string, error) {
+	alg := strings.ToUpper(p.Algorithm)
+	if alg == "" {
+		alg = "HS256"
+	}
+
+	lifetime := DefaultJWTLifetime
+	if p.Lifetime != "" {
+		d, err := time.ParseDuration(p.Lifetime)
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid jwt lifetime: %s", p.Lifetime)
+		}
+		lifetime = d
+	}
+
+	issued := now()
+	claims := map[string]interface{}{}
+	for name, value := range p.Claims {
+		expanded, err := expandClaim(value, issued, lifetime)
+		if err != nil {
+			return "", fmt.Errorf("jwt claim %s: %w", name, err)
+		}
+		claims[name] = expanded
+	}
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = issued.Unix()
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = issued.Add(lifetime).Unix()
+	}
+	if _, ok := claims["jti"]; !ok {
+		jti, err := newUUID()
+		if err != nil {
+			return "", err
+		}
+		claims["jti"] = jti
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if p.KeyID != "" {
+		header["kid"] = p.KeyID
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode jwt claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	signature, err := p.signJWT(alg, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signJWT signs the JWS signing input with the preset's key
+func (p *AuthPreset) signJWT(alg string, input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+
+	switch alg {
+	case "HS256":
+		secret := []byte(p.Secret)
+		if len(secret) == 0 {
+			key, err := p.readKeyFile()
+			if err != nil {
+				return nil, err
+			}
+			secret = key
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+
+	case "RS256":
+		key, err := p.privateKey()
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("RS256 requires an RSA private key")
+		}
+		return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+
+	case "ES256":
+		key, err := p.privateKey()
+		if err != nil {
+			return nil, err
+		}
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 EC private key")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed-width r || s encoding, not ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s (expected HS256, RS256 or ES256)", alg)
+	}
+}
+
+// privateKey parses the PEM private key in the key file
+func (p *AuthPreset) privateKey() (crypto.Signer, error) {
+	data, err := p.readKeyFile()
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in jwt key file %s", p.KeyFile)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in jwt key file", block.Type)
+	}
+}
+
+// readKeyFile reads the key file, relative to the workspace
+func (p *AuthPreset) readKeyFile() ([]byte, error) {
+	if p.KeyFile == "" {
+		return nil, fmt.Errorf("jwt auth requires secret or keyFile")
+	}
+	path := p.KeyFile
+	if !filepath.IsAbs(path) && p.root != "" {
+		path = filepath.Join(p.root, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key file: %w", err)
+	}
+	return data, nil
+}
+
+// expandClaim replaces a placeholder claim value. Time placeholders become
+// numeric dates; other values are returned unchanged.
+func expandClaim(value interface{}, issued time.Time, lifetime time.Duration) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return value, nil
+	}
+
+	name := s[1 : len(s)-1]
+	switch {
+	case name == "now":
+		return issued.Unix(), nil
+	case name == "exp":
+		return issued.Add(lifetime).Unix(), nil
+	case name == "uuid":
+		return newUUID()
+	case strings.HasPrefix(name, "now+"), strings.HasPrefix(name, "now-"):
+		d, err := time.ParseDuration(name[3:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in %s", s)
+		}
+		return issued.Add(d).Unix(), nil
+	default:
+		return value, nil
+	}
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeJWT splits a token into its decoded header, claims and signature
+func decodeJWT(t *testing.T, token string) (map[string]interface{}, map[string]interface{}, []byte) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d in %q", len(parts), token)
+	}
+
+	var header, claims map[string]interface{}
+	for i, v := range []*map[string]interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("invalid base64url: %v", err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("invalid signature encoding: %v", err)
+	}
+	return header, claims, signature
+}
+
+// writePEM writes a key to a temporary PEM file
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWTHS256(t *testing.T) {
+	withClock(t, time.Unix(1700000000, 0))
+	preset := &AuthPreset{
+		Type:     "jwt",
+		Secret:   "shared-secret",
+		KeyID:    "k1",
+		Lifetime: "10m",
+		Claims: map[string]interface{}{
+			"sub":   "service-a",
+			"aud":   "api",
+			"admin": true,
+			"nbf":   "{now-30s}",
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.example.com", nil)
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	header, claims, signature := decodeJWT(t, token)
+
+	if header["alg"] != "HS256" || header["typ"] != "JWT" || header["kid"] != "k1" {
+		t.Errorf("unexpected header %v", header)
+	}
+	if claims["sub"] != "service-a" || claims["aud"] != "api" || claims["admin"] != true {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if claims["iat"] != float64(1700000000) || claims["exp"] != float64(1700000600) || claims["nbf"] != float64(1699999970) {
+		t.Errorf("unexpected time claims %v", claims)
+	}
+	if jti, _ := claims["jti"].(string); len(jti) != 36 {
+		t.Errorf("expected a UUID jti, got %v", claims["jti"])
+	}
+
+	mac := hmac.New(sha256.New, []byte("shared-secret"))
+	mac.Write([]byte(token[:strings.LastIndex(token, ".")]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		t.Error("HS256 signature does not verify")
+	}
+}
+
+func TestJWTFreshPerRequest(t *testing.T) {
+	preset := &AuthPreset{Type: "jwt", Secret: "s"}
+
+	first, _ := preset.MintJWT()
+	second, _ := preset.MintJWT()
+	if first == second {
+		t.Error("expected a new token (new jti) for each request")
+	}
+}
+
+func TestJWTTemplatedClaims(t *testing.T) {
+	withClock(t, time.Unix(1700000000, 0))
+	preset := &AuthPreset{
+		Type:   "jwt",
+		Secret: "s",
+		Claims: map[string]interface{}{
+			"iat": "{now-1m}",
+			"exp": "{now+1h}",
+			"jti": "fixed",
+			"sid": "{uuid}",
+		},
+	}
+
+	token, err := preset.MintJWT()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, claims, _ := decodeJWT(t, token)
+	if claims["iat"] != float64(1699999940) || claims["exp"] != float64(1700003600) || claims["jti"] != "fixed" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if sid, _ := claims["sid"].(string); len(sid) != 36 {
+		t.Errorf("expected a UUID, got %v", claims["sid"])
+	}
+}
+
+func TestJWTRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preset := &AuthPreset{
+		Type:      "jwt",
+		Algorithm: "RS256",
+		KeyFile:   writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+	}
+
+	token, err := preset.MintJWT()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	header, _, signature := decodeJWT(t, token)
+	if header["alg"] != "RS256" {
+		t.Errorf("expected RS256, got %v", header["alg"])
+	}
+
+	digest := sha256.Sum256([]byte(token[:strings.LastIndex(token, ".")]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("RS256 signature does not verify: %v", err)
+	}
+}
+
+func TestJWTES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Relative key paths resolve against the workspace
+	path := writePEM(t, "PRIVATE KEY", der)
+	preset := &AuthPreset{
+		Type:      "jwt",
+		Algorithm: "ES256",
+		KeyFile:   filepath.Base(path),
+		root:      filepath.Dir(path),
+	}
+
+	token, err := preset.MintJWT()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, _, signature := decodeJWT(t, token)
+	if len(signature) != 64 {
+		t.Fatalf("expected a 64-byte r || s signature, got %d bytes", len(signature))
+	}
+
+	digest := sha256.Sum256([]byte(token[:strings.LastIndex(token, ".")]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("ES256 signature does not verify")
+	}
+}
+
+func TestJWTErrors(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaFile := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	tests := []struct {
+		name   string
+		preset *AuthPreset
+	}{
+		{"no key", &AuthPreset{Type: "jwt"}},
+		{"unknown algorithm", &AuthPreset{Type: "jwt", Secret: "s", Algorithm: "none"}},
+		{"bad lifetime", &AuthPreset{Type: "jwt", Secret: "s", Lifetime: "soon"}},
+		{"missing key file", &AuthPreset{Type: "jwt", Algorithm: "RS256", KeyFile: "/nonexistent/key.pem"}},
+		{"wrong key type", &AuthPreset{Type: "jwt", Algorithm: "ES256", KeyFile: rsaFile}},
+		{"bad claim duration", &AuthPreset{Type: "jwt", Secret: "s", Claims: map[string]interface{}{"nbf": "{now+soon}"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.preset.MintJWT(); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
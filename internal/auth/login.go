@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GrantAuthorizationCode is obtained interactively with "gosh auth login"
+const GrantAuthorizationCode = "authorization_code"
+
+// DefaultLoginTimeout is how long Login waits for the browser callback
+const DefaultLoginTimeout = 5 * time.Minute
+
+// LoginOptions controls the authorization code flow
+type LoginOptions struct {
+	// OpenURL is called with the authorize URL once the callback listener
+	// is ready, e.g. to print it and launch a browser
+	OpenURL func(authorizeURL string) error
+	// Timeout bounds the wait for the callback (DefaultLoginTimeout if zero)
+	Timeout time.Duration
+	// Transport carries the token request, for proxy and TLS settings (the
+	// default transport if nil)
+	Transport http.RoundTripper
+}
+
+// callbackResult is the outcome of the redirect to the loopback listener
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// Login runs the OAuth 2.0 authorization code flow with PKCE for a preset
+// and stores the resulting token set in the workspace token cache
+func (m *Manager) Login(name string, opts LoginOptions) (*Token, error) {
+	preset, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if AuthType(strings.ToLower(preset.Type)) != AuthTypeOAuth2 {
+		return nil, fmt.Errorf("auth preset %s is not an oauth2 preset", name)
+	}
+	if preset.AuthURL == "" {
+		return nil, fmt.Errorf("oauth2 login requires authUrl")
+	}
+	if preset.TokenURL == "" {
+		return nil, fmt.Errorf("oauth2 auth requires tokenUrl")
+	}
+	if preset.ClientID == "" {
+		return nil, fmt.Errorf("oauth2 login requires clientId")
+	}
+	preset.SetTransport(opts.Transport)
+
+	listener, redirectURI, err := listenLoopback(preset.RedirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	results := make(chan callbackResult, 1)
+	server := &http.Server{
+		Handler:           callbackHandler(redirectURI.Path, state, results),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	authorizeURL, err := preset.authorizeURL(redirectURI.String(), state, pkceChallenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+	if opts.OpenURL != nil {
+		if err := opts.OpenURL(authorizeURL); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultLoginTimeout
+	}
+
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out after %v waiting for the authorization callback", timeout)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	token, err := preset.requestToken(url.Values{
+		"grant_type":    {GrantAuthorizationCode},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURI.String()},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := preset.tokens.Put(name, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Logout drops the cached token for a preset
+func (m *Manager) Logout(name string) error {
+	owner := m.owner(name)
+	if owner.Locked() {
+		return ErrLocked
+	}
+	if !owner.Has(name) {
+		return fmt.Errorf("auth preset not found: %s", name)
+	}
+	return owner.tokens.Delete(name)
+}
+
+// authorizeURL builds the authorization request (RFC 6749 section 4.1.1,
+// RFC 7636 section 4.3)
+func (p *AuthPreset) authorizeURL(redirectURI, state, challenge string) (string, error) {
+	u, err := url.Parse(p.AuthURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorize URL: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+	if p.Scope != "" {
+		query.Set("scope", p.Scope)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// listenLoopback listens on the redirect URL, or on a random port on
+// 127.0.0.1 when none is configured
+func listenLoopback(redirectURL string) (net.Listener, *url.URL, error) {
+	if redirectURL == "" {
+		redirectURL = "http://127.0.0.1:0/callback"
+	}
+
+	u, err := url.Parse(redirectURL)
+	if err != nil || u.Scheme != "http" || u.Host == "" {
+		return nil, nil, fmt.Errorf("invalid redirect URL: %s (expected http://127.0.0.1:PORT/path)", redirectURL)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "80")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start callback listener: %w", err)
+	}
+
+	// Use the port actually bound when listening on port 0
+	if u.Port() == "0" {
+		port := listener.Addr().(*net.TCPAddr).Port
+		u.Host = net.JoinHostPort(u.Hostname(), fmt.Sprint(port))
+	}
+	return listener, u, nil
+}
+
+// callbackHandler captures the authorization code, or the error, from the
+// redirect and reports it once
+func callbackHandler(path, state string, results chan<- callbackResult) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		var result callbackResult
+		switch {
+		case query.Get("state") != state:
+			result.err = errors.New("authorization callback state mismatch")
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization failed: %s", query.Get("error"))
+			if desc := query.Get("error_description"); desc != "" {
+				result.err = fmt.Errorf("authorization failed: %s: %s", query.Get("error"), desc)
+			}
+		case query.Get("code") == "":
+			result.err = errors.New("authorization callback has no code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<html><body><h1>Login failed</h1><p>%s</p></body></html>", html.EscapeString(result.err.Error()))
+		} else {
+			fmt.Fprint(w, "<html><body><h1>Login complete</h1><p>You can close this window and return to the terminal.</p></body></html>")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+}
+
+// pkceChallenge derives the S256 code challenge from a verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes, base64url-encoded
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAuthorizationServer serves /authorize, which redirects straight back
+// with a code, and /token, which checks the PKCE verifier
+func newAuthorizationServer(t *testing.T, deny bool) *httptest.Server {
+	var challenge string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("response_type") != "code" || query.Get("client_id") != "cli" {
+			t.Errorf("unexpected authorize request: %s", r.URL.RawQuery)
+		}
+		if query.Get("code_challenge_method") != "S256" {
+			t.Errorf("expected S256 challenge, got %q", query.Get("code_challenge_method"))
+		}
+		if query.Get("scope") != "openid profile" {
+			t.Errorf("expected scope, got %q", query.Get("scope"))
+		}
+		challenge = query.Get("code_challenge")
+
+		callback := url.Values{"state": {query.Get("state")}}
+		if deny {
+			callback.Set("error", "access_denied")
+		} else {
+			callback.Set("code", "auth-code")
+		}
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+callback.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("grant_type") {
+		case GrantAuthorizationCode:
+			if r.PostForm.Get("code") != "auth-code" || pkceChallenge(r.PostForm.Get("code_verifier")) != challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+			if r.PostForm.Get("client_id") != "cli" {
+				t.Errorf("expected client_id in body, got %q", r.PostForm.Get("client_id"))
+			}
+			fmt.Fprint(w, `{"access_token":"user-token","token_type":"Bearer","expires_in":60,"refresh_token":"user-refresh"}`)
+		case GrantRefreshToken:
+			if r.PostForm.Get("refresh_token") != "user-refresh" {
+				t.Errorf("unexpected refresh token %q", r.PostForm.Get("refresh_token"))
+			}
+			fmt.Fprint(w, `{"access_token":"refreshed-token","token_type":"Bearer","expires_in":3600}`)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newLoginManager(t *testing.T, server *httptest.Server) *Manager {
+	manager := NewManager(t.TempDir())
+	manager.Add(&AuthPreset{
+		Name:     "user",
+		Type:     "oauth2",
+		Grant:    GrantAuthorizationCode,
+		AuthURL:  server.URL + "/authorize",
+		TokenURL: server.URL + "/token",
+		ClientID: "cli",
+		Scope:    "openid profile",
+	})
+	return manager
+}
+
+// visit stands in for the browser
+func visit(authorizeURL string) error {
+	resp, err := http.Get(authorizeURL)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestLoginAuthorizationCode(t *testing.T) {
+	server := newAuthorizationServer(t, false)
+	manager := newLoginManager(t, server)
+
+	var opened string
+	token, err := manager.Login("user", LoginOptions{
+		OpenURL: func(u string) error {
+			opened = u
+			return visit(u)
+		},
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token.AccessToken != "user-token" {
+		t.Errorf("expected user-token, got %q", token.AccessToken)
+	}
+	if !strings.Contains(opened, "redirect_uri=http%3A%2F%2F127.0.0.1%3A") {
+		t.Errorf("expected loopback redirect URI, got %s", opened)
+	}
+
+	// Apply uses the stored token, then refreshes it once it expires
+	preset, _ := manager.Get("user")
+	if auth := applyOAuth2(t, preset); auth != "Bearer user-token" {
+		t.Errorf("expected 'Bearer user-token', got '%s'", auth)
+	}
+
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Now().Add(time.Minute) }
+	if auth := applyOAuth2(t, preset); auth != "Bearer refreshed-token" {
+		t.Errorf("expected 'Bearer refreshed-token', got '%s'", auth)
+	}
+	cached, _ := manager.Tokens().Get("user")
+	if cached.RefreshToken != "user-refresh" {
+		t.Errorf("expected refresh token kept, got %q", cached.RefreshToken)
+	}
+}
+
+func TestLoginDenied(t *testing.T) {
+	server := newAuthorizationServer(t, true)
+	manager := newLoginManager(t, server)
+
+	_, err := manager.Login("user", LoginOptions{OpenURL: visit, Timeout: 5 * time.Second})
+	if err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("expected access_denied error, got %v", err)
+	}
+}
+
+func TestLoginStateMismatch(t *testing.T) {
+	server := newAuthorizationServer(t, false)
+	manager := newLoginManager(t, server)
+
+	_, err := manager.Login("user", LoginOptions{
+		OpenURL: func(u string) error {
+			parsed, _ := url.Parse(u)
+			redirect := parsed.Query().Get("redirect_uri")
+			return visit(redirect + "?code=stolen&state=forged")
+		},
+		Timeout: 5 * time.Second,
+	})
+	if err == nil || !strings.Contains(err.Error(), "state mismatch") {
+		t.Errorf("expected state mismatch error, got %v", err)
+	}
+}
+
+func TestLoginTimeout(t *testing.T) {
+	server := newAuthorizationServer(t, false)
+	manager := newLoginManager(t, server)
+
+	_, err := manager.Login("user", LoginOptions{Timeout: 50 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestApplyWithoutLogin(t *testing.T) {
+	server := newAuthorizationServer(t, false)
+	manager := newLoginManager(t, server)
+
+	preset, _ := manager.Get("user")
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	err := preset.Apply(req)
+	if err == nil || !strings.Contains(err.Error(), "gosh auth login user") {
+		t.Errorf("expected login hint, got %v", err)
+	}
+}
+
+func TestLoginRequiresOAuth2Preset(t *testing.T) {
+	manager := NewManager(t.TempDir())
+	manager.Add(&AuthPreset{Name: "basic", Type: "basic", Username: "john"})
+
+	if _, err := manager.Login("basic", LoginOptions{}); err == nil {
+		t.Error("expected error for non-oauth2 preset")
+	}
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	challenge := pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("unexpected challenge %s", challenge)
+	}
+}
@@ -1,27 +1,87 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
 
+// ErrLocked is returned when encrypted presets are used before Unlock
+var ErrLocked = errors.New("auth presets are encrypted; unlock them with a passphrase first")
+
 // Manager handles authentication preset operations
 type Manager struct {
+	root       string
 	configPath string
 	presets    map[string]*AuthPreset
+	tokens     *TokenStore
+	helpers    *helperCache   // Credential helper output, in memory only
+	encrypted  *EncryptedData // Encrypted presets awaiting Unlock
+	sealer     *sealer        // Set while encryption at rest is on
+	global     *Manager       // User-level presets, used when the workspace has none of the name
+}
+
+// Preset sources shown by "gosh auth list"
+const (
+	SourceWorkspace = "workspace"
+	SourceGlobal    = "global"
+)
+
+// PresetInfo describes a preset and where it is defined
+type PresetInfo struct {
+	Name       string
+	Preset     *AuthPreset
+	Source     string // SourceWorkspace or SourceGlobal
+	Overridden bool   // A global preset hidden by a workspace preset of the same name
 }
 
 // NewManager creates a new auth manager for a workspace
 func NewManager(workspaceRoot string) *Manager {
 	return &Manager{
+		root:       workspaceRoot,
 		configPath: filepath.Join(workspaceRoot, ".gosh", "auth.yaml"),
 		presets:    make(map[string]*AuthPreset),
+		tokens:     NewTokenStore(workspaceRoot),
+		helpers:    newHelperCache(),
+	}
+}
+
+// NewGlobalManager creates an auth manager for user-level presets in
+// configDir/auth.yaml, usually $XDG_CONFIG_HOME/gosh. Relative paths and
+// secret commands in these presets are resolved in configDir.
+func NewGlobalManager(configDir string) *Manager {
+	return &Manager{
+		root:       configDir,
+		configPath: filepath.Join(configDir, "auth.yaml"),
+		presets:    make(map[string]*AuthPreset),
+		tokens: &TokenStore{
+			path:   filepath.Join(configDir, "tokens.yaml"),
+			tokens: make(map[string]*Token),
+		},
+		helpers: newHelperCache(),
 	}
 }
 
+// SetGlobal layers the workspace presets over user-level ones
+func (m *Manager) SetGlobal(global *Manager) {
+	m.global = global
+}
+
+// Global returns the user-level presets, or nil
+func (m *Manager) Global() *Manager {
+	return m.global
+}
+
+// Has reports whether this store defines a preset, ignoring global presets
+func (m *Manager) Has(name string) bool {
+	_, exists := m.presets[name]
+	return exists
+}
+
 // Load loads authentication presets from disk
 func (m *Manager) Load() error {
 	// If file doesn't exist, that's OK
@@ -38,6 +98,10 @@ func (m *Manager) Load() error {
 		return fmt.Errorf("failed to parse auth config: %w", err)
 	}
 
+	if config.Encrypted != nil {
+		m.encrypted = config.Encrypted
+		return nil
+	}
 	if config.Presets != nil {
 		m.presets = config.Presets
 	}
@@ -45,6 +109,73 @@ func (m *Manager) Load() error {
 	return nil
 }
 
+// Encrypted reports whether presets are stored encrypted
+func (m *Manager) Encrypted() bool {
+	return m.encrypted != nil || m.sealer != nil
+}
+
+// Locked reports whether encrypted presets still need Unlock
+func (m *Manager) Locked() bool {
+	return m.encrypted != nil
+}
+
+// Unlock decrypts loaded presets with the passphrase. They stay encrypted
+// on disk.
+func (m *Manager) Unlock(passphrase string) error {
+	if m.encrypted == nil {
+		return nil
+	}
+
+	s, plaintext, err := openSealer(passphrase, m.encrypted)
+	if err != nil {
+		return err
+	}
+
+	var config AuthConfig
+	if err := yaml.Unmarshal(plaintext, &config); err != nil {
+		return fmt.Errorf("failed to parse auth config: %w", err)
+	}
+	if config.Presets != nil {
+		m.presets = config.Presets
+	}
+	m.encrypted = nil
+	m.sealer = s
+	m.tokens.setSealer(s)
+	return nil
+}
+
+// Encrypt turns on encryption at rest with a new passphrase and rewrites
+// the presets and token cache
+func (m *Manager) Encrypt(passphrase string) error {
+	if m.Locked() {
+		return ErrLocked
+	}
+
+	s, err := newSealer(passphrase)
+	if err != nil {
+		return err
+	}
+	m.sealer = s
+	if err := m.Save(); err != nil {
+		return err
+	}
+	return m.tokens.reseal(s)
+}
+
+// Decrypt turns off encryption at rest and rewrites the presets and token
+// cache in plaintext
+func (m *Manager) Decrypt() error {
+	if m.Locked() {
+		return ErrLocked
+	}
+
+	m.sealer = nil
+	if err := m.Save(); err != nil {
+		return err
+	}
+	return m.tokens.reseal(nil)
+}
+
 // Save saves authentication presets to disk
 func (m *Manager) Save() error {
 	// Ensure directory exists
@@ -53,6 +184,11 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("failed to create auth config directory: %w", err)
 	}
 
+	// Never overwrite presets that haven't been decrypted
+	if m.Locked() {
+		return ErrLocked
+	}
+
 	config := AuthConfig{
 		Presets: m.presets,
 	}
@@ -62,6 +198,16 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("failed to marshal auth config: %w", err)
 	}
 
+	if m.sealer != nil {
+		encrypted, err := m.sealer.seal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt auth config: %w", err)
+		}
+		if data, err = yaml.Marshal(AuthConfig{Encrypted: encrypted}); err != nil {
+			return fmt.Errorf("failed to marshal auth config: %w", err)
+		}
+	}
+
 	if err := os.WriteFile(m.configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write auth config: %w", err)
 	}
@@ -69,13 +215,38 @@ func (m *Manager) Save() error {
 	return nil
 }
 
-// Get retrieves a preset by name
+// Get returns a resolved copy of a preset, falling back to global presets
 func (m *Manager) Get(name string) (*AuthPreset, error) {
+	if m.Locked() {
+		return nil, ErrLocked
+	}
 	preset, exists := m.presets[name]
 	if !exists {
+		if m.global != nil {
+			return m.global.Get(name)
+		}
 		return nil, fmt.Errorf("auth preset not found: %s", name)
 	}
-	return preset, nil
+
+	resolved := *preset
+	resolved.root = m.root
+	resolved.tokens = m.tokens
+	if err := resolved.resolveSecrets(m.helpers); err != nil {
+		return nil, fmt.Errorf("auth preset %s: %w", name, err)
+	}
+	return &resolved, nil
+}
+
+// Lookup returns a preset as stored, with its secret sources unresolved,
+// falling back to global presets. It returns nil when there is none.
+func (m *Manager) Lookup(name string) *AuthPreset {
+	if preset, exists := m.presets[name]; exists {
+		return preset
+	}
+	if m.global != nil {
+		return m.global.Lookup(name)
+	}
+	return nil
 }
 
 // Add adds or updates an authentication preset
@@ -86,6 +257,9 @@ func (m *Manager) Add(preset *AuthPreset) error {
 	if preset.Type == "" {
 		return fmt.Errorf("preset type cannot be empty")
 	}
+	if m.Locked() {
+		return ErrLocked
+	}
 
 	m.presets[preset.Name] = preset
 	return m.Save()
@@ -93,14 +267,58 @@ func (m *Manager) Add(preset *AuthPreset) error {
 
 // Remove removes an authentication preset
 func (m *Manager) Remove(name string) error {
+	if m.Locked() {
+		return ErrLocked
+	}
 	if _, exists := m.presets[name]; !exists {
+		if m.global != nil && m.global.Has(name) {
+			return fmt.Errorf("auth preset %s is global; use --global to remove it", name)
+		}
 		return fmt.Errorf("auth preset not found: %s", name)
 	}
 	delete(m.presets, name)
+	if err := m.tokens.Delete(name); err != nil {
+		return err
+	}
 	return m.Save()
 }
 
+// Tokens returns the workspace token cache
+func (m *Manager) Tokens() *TokenStore {
+	return m.tokens
+}
+
 // List returns all authentication presets
 func (m *Manager) List() map[string]*AuthPreset {
 	return m.presets
 }
+
+// Presets lists workspace and global presets sorted by name, with the
+// workspace preset first when both define a name
+func (m *Manager) Presets() []PresetInfo {
+	var infos []PresetInfo
+	for name, preset := range m.presets {
+		infos = append(infos, PresetInfo{Name: name, Preset: preset, Source: SourceWorkspace})
+	}
+	if m.global != nil {
+		for name, preset := range m.global.presets {
+			infos = append(infos, PresetInfo{Name: name, Preset: preset, Source: SourceGlobal, Overridden: m.Has(name)})
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].Source == SourceWorkspace
+	})
+	return infos
+}
+
+// owner returns the store a preset is defined in
+func (m *Manager) owner(name string) *Manager {
+	if !m.Locked() && !m.Has(name) && m.global != nil && m.global.Has(name) {
+		return m.global
+	}
+	return m
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// ClientCertificate loads the certificate an mtls preset presents to the
+// server. Other preset types return nil.
+func (p *AuthPreset) ClientCertificate() (*tls.Certificate, error) {
+	if AuthType(strings.ToLower(p.Type)) != AuthTypeMTLS {
+		return nil, nil
+	}
+	if p.CertFile == "" {
+		return nil, fmt.Errorf("mtls auth requires certFile")
+	}
+	return LoadClientCertificate(p.resolvePath(p.CertFile), p.resolvePath(p.KeyFile), p.Password)
+}
+
+// CAFiles returns the extra CA bundle of an mtls preset, if any
+func (p *AuthPreset) CAFiles() []string {
+	if AuthType(strings.ToLower(p.Type)) != AuthTypeMTLS || p.CAFile == "" {
+		return nil
+	}
+	return []string{p.resolvePath(p.CAFile)}
+}
+
+// resolvePath makes a path relative to the workspace
+func (p *AuthPreset) resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) || p.root == "" {
+		return path
+	}
+	return filepath.Join(p.root, path)
+}
+
+// LoadClientCertificate loads a client certificate from a PEM certificate
+// and key, or from a PKCS#12 bundle (.p12 or .pfx) decrypted with password.
+// keyFile may be empty when certFile holds both PEM blocks.
+func LoadClientCertificate(certFile, keyFile, password string) (*tls.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(certFile))
+	if ext == ".p12" || ext == ".pfx" {
+		return parsePKCS12(data, password)
+	}
+
+	keyData := data
+	if keyFile != "" {
+		if keyData, err = os.ReadFile(keyFile); err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+	}
+	cert, err := tls.X509KeyPair(data, keyData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+// parsePKCS12 converts a PKCS#12 bundle to a certificate with its chain
+func parsePKCS12(data []byte, password string) (*tls.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PKCS#12 client certificate: %w", err)
+	}
+
+	// The certificate sharing the key's localKeyId is the leaf and must
+	// come first
+	keyID := ""
+	for _, block := range blocks {
+		if block.Type == "PRIVATE KEY" {
+			keyID = block.Headers["localKeyId"]
+		}
+	}
+
+	var leafPEM, chainPEM, keyPEM []byte
+	for _, block := range blocks {
+		isLeaf := keyID != "" && block.Headers["localKeyId"] == keyID
+		// Bag attributes aren't valid PEM headers for tls.X509KeyPair
+		block.Headers = nil
+		switch {
+		case block.Type == "PRIVATE KEY":
+			keyPEM = append(keyPEM, pem.EncodeToMemory(block)...)
+		case isLeaf:
+			leafPEM = append(leafPEM, pem.EncodeToMemory(block)...)
+		default:
+			chainPEM = append(chainPEM, pem.EncodeToMemory(block)...)
+		}
+	}
+	certPEM := append(leafPEM, chainPEM...)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PKCS#12 client certificate: %w", err)
+	}
+	return &cert, nil
+}
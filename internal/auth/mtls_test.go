@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientP12 is a self-signed P-256 certificate for CN=gosh-client and its
+// key, exported by openssl with the password "secret"
+const clientP12 = `
+	MIIDigIBAzCCA1AGCSqGSIb3DQEHAaCCA0EEggM9MIIDOTCCAi8GCSqGSIb3DQEHBqCCAiAwggIc
+	AgEAMIICFQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQMwDgQImObki855ei0CAggAgIIB6PWm1p1H
+	46iOAC8XywAdIiZlzDy5tBQIC6FZS+v8rFFV5cz7KpxDIqWYQx2DMPIdIR6v5MBkW7+5HZF8pQXU
+	dbRah/Z/M9i2Ub+Mg30xqhJBEyP+GdZ1KIw0363kCp4XAzUZV1a3RqpTG3Vmfd8KeDVHZqimKQHk
+	yDImnHm19KrBwR8CzlaH6jd7EI0wgdiJv3g0mwZjUcGnYwEhnhnDQoB5SZrPtctDL9xUYKdCwfE5
+	SZ5DJnHHLLI827dfLjjZJ7yzCL+1zeGCyOsnx/yU6qw4HHHoisLNCT/Ufi1vtunygRD9+iGeHZgG
+	DyBwTnXPEshhNv7dayuVYNSEIOKlp8H6c8x24/RhMbipz3LQIG5XqwzXv3r9SwwcF1dPLToIGB35
+	P4JKIZ0aBU5oZ5KT7Ceo48DgxTTf1YIXsQ/dsdK9PM/IwSDZVvdqW9vbQ27dFTUn0H7jEBvieTTe
+	eutfTFkp74XqJxTCxUq0zLhVMzKyx/AUHy6ZjE4SvkprIna9E5WxajQHIZsLF6yxoze7sVxOgWuI
+	Eif4do+jwQDGhYuH5Ios9c/VSHxTLmle3Wcu4R5y/NZtbnfD62wXdDd3ktZYVMqN6b8uypWckDGg
+	wBoo5cm06yd9AIbh9Uzs2WdANo9y6coeJtFRMIIBAgYJKoZIhvcNAQcBoIH0BIHxMIHuMIHrBgsq
+	hkiG9w0BDAoBAqCBtDCBsTAcBgoqhkiG9w0BDAEDMA4ECGYdS/8TDsrsAgIIAASBkPl+UQxfmUva
+	acEF2zStw0DgmTYyO4ZOXcEYuXczbK8+/iKnG0mQQI3lN0IdQq0VkOXIgmyywetPMAbUaUB0O/0x
+	p59YPA67fXf5mSA2mxI1NioFzc0WeKZJojPmdneOqOlI7sD7bt5FA3DqrEzAV5DtiAPpV2Tv0uGN
+	uUjIpe05w8B6DRwo28Vr/9C9NcoMlzElMCMGCSqGSIb3DQEJFTEWBBQ9hUQYj0n7TbPZANy+GOiS
+	DNvFtjAxMCEwCQYFKw4DAhoFAAQU9W4G2qHG3Rejs1bhzUn+ONXwSpgECC34U2unJSwHAgIIAA==`
+
+// writeClientCert writes a self-signed certificate and key as PEM files
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gosh-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// leafName returns the common name of a loaded certificate
+func leafName(t *testing.T, der []byte) string {
+	t.Helper()
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Subject.CommonName
+}
+
+func TestLoadClientCertificatePEM(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeClientCert(t, dir)
+
+	cert, err := LoadClientCertificate(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if name := leafName(t, cert.Certificate[0]); name != "gosh-client" {
+		t.Errorf("expected gosh-client, got %q", name)
+	}
+
+	// Certificate and key in one file
+	certPEM, _ := os.ReadFile(certFile)
+	keyPEM, _ := os.ReadFile(keyFile)
+	combined := filepath.Join(dir, "combined.pem")
+	if err := os.WriteFile(combined, append(certPEM, keyPEM...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadClientCertificate(combined, "", ""); err != nil {
+		t.Errorf("expected combined PEM to load, got %v", err)
+	}
+}
+
+func TestLoadClientCertificatePKCS12(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(clientP12), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "client.p12")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := LoadClientCertificate(path, "", "secret")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if name := leafName(t, cert.Certificate[0]); name != "gosh-client" {
+		t.Errorf("expected gosh-client, got %q", name)
+	}
+
+	if _, err := LoadClientCertificate(path, "", "wrong"); err == nil {
+		t.Error("expected error for wrong password")
+	}
+}
+
+func TestMTLSPreset(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeClientCert(t, dir)
+	preset := &AuthPreset{
+		Type:     "mtls",
+		CertFile: filepath.Base(certFile),
+		KeyFile:  filepath.Base(keyFile),
+		CAFile:   "ca.pem",
+		root:     dir,
+	}
+
+	// No headers are added; the certificate is used in the handshake
+	req, _ := http.NewRequest("GET", "https://api.example.com", nil)
+	if err := preset.Apply(req); err != nil || len(req.Header) != 0 {
+		t.Errorf("expected no headers, got %v (%v)", req.Header, err)
+	}
+
+	cert, err := preset.ClientCertificate()
+	if err != nil || cert == nil {
+		t.Fatalf("expected certificate, got %v", err)
+	}
+	if files := preset.CAFiles(); len(files) != 1 || files[0] != filepath.Join(dir, "ca.pem") {
+		t.Errorf("expected CA file relative to the workspace, got %v", files)
+	}
+
+	if cert, err := (&AuthPreset{Type: "bearer"}).ClientCertificate(); cert != nil || err != nil {
+		t.Errorf("expected no certificate for bearer presets, got %v, %v", cert, err)
+	}
+	if _, err := (&AuthPreset{Type: "mtls"}).ClientCertificate(); err == nil {
+		t.Error("expected error without certFile")
+	}
+}
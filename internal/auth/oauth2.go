@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth 2.0 grant types
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// tokenTimeout bounds token endpoint requests
+const tokenTimeout = 30 * time.Second
+
+// tokenResponse is a token endpoint response (RFC 6749 section 5)
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	RefreshToken     string      `json:"refresh_token"`
+	Scope            string      `json:"scope"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// SetTransport sets the transport for token endpoint requests, so they go
+// through the same proxy and TLS settings as the request; nil uses the
+// default transport
+func (p *AuthPreset) SetTransport(transport http.RoundTripper) {
+	p.transport = transport
+}
+
+// applyOAuth2 sets the Authorization header from a cached or newly
+// fetched access token
+func (p *AuthPreset) applyOAuth2(req *http.Request) error {
+	token, err := p.OAuth2Token()
+	if err != nil {
+		return err
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return nil
+}
+
+// OAuth2Token returns a valid access token, using the cached token when it
+// hasn't expired, then the refresh token, then the configured grant
+func (p *AuthPreset) OAuth2Token() (*Token, error) {
+	if p.TokenURL == "" {
+		return nil, fmt.Errorf("oauth2 auth requires tokenUrl")
+	}
+
+	cached, err := p.cachedToken()
+	if err != nil {
+		return nil, err
+	}
+	if cached.Valid() {
+		return cached, nil
+	}
+
+	refreshToken := p.RefreshToken
+	if cached != nil && cached.RefreshToken != "" {
+		refreshToken = cached.RefreshToken
+	}
+
+	var token *Token
+	if refreshToken != "" {
+		token, err = p.requestToken(url.Values{
+			"grant_type":    {GrantRefreshToken},
+			"refresh_token": {refreshToken},
+		})
+		if err != nil && p.grant() == GrantRefreshToken {
+			return nil, err
+		}
+		if token != nil && token.RefreshToken == "" {
+			token.RefreshToken = refreshToken
+		}
+	}
+
+	// Fall back to the configured grant when refreshing isn't possible
+	if token == nil {
+		form, err := p.grantForm()
+		if err != nil {
+			return nil, err
+		}
+		if token, err = p.requestToken(form); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.tokens != nil {
+		if err := p.tokens.Put(p.Name, token); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// cachedToken returns the cached token for the preset, if any
+func (p *AuthPreset) cachedToken() (*Token, error) {
+	if p.tokens == nil {
+		return nil, nil
+	}
+	return p.tokens.Get(p.Name)
+}
+
+// grant returns the configured grant type, defaulting to client credentials
+func (p *AuthPreset) grant() string {
+	if p.Grant == "" {
+		return GrantClientCredentials
+	}
+	return strings.ToLower(p.Grant)
+}
+
+// grantForm builds the token request for the configured grant
+func (p *AuthPreset) grantForm() (url.Values, error) {
+	switch p.grant() {
+	case GrantClientCredentials:
+		if p.ClientID == "" {
+			return nil, fmt.Errorf("oauth2 client_credentials grant requires clientId")
+		}
+		return url.Values{"grant_type": {GrantClientCredentials}}, nil
+	case GrantPassword:
+		if p.Username == "" {
+			return nil, fmt.Errorf("oauth2 password grant requires username")
+		}
+		return url.Values{
+			"grant_type": {GrantPassword},
+			"username":   {p.Username},
+			"password":   {p.Password},
+		}, nil
+	case GrantRefreshToken:
+		return nil, fmt.Errorf("oauth2 refresh_token grant requires refreshToken")
+	case GrantAuthorizationCode:
+		return nil, fmt.Errorf("no token for auth preset %s: run 'gosh auth login %s'", p.Name, p.Name)
+	default:
+		return nil, fmt.Errorf("unsupported oauth2 grant: %s", p.Grant)
+	}
+}
+
+// requestToken posts a token request and parses the response
+func (p *AuthPreset) requestToken(form url.Values) (*Token, error) {
+	if p.Scope != "" && form.Get("grant_type") != GrantRefreshToken {
+		form.Set("scope", p.Scope)
+	}
+
+	// Public clients and servers that don't accept Basic get the
+	// credentials in the form body
+	useBasic := p.ClientSecret != "" && !strings.EqualFold(p.ClientAuth, "body")
+	if !useBasic && p.ClientID != "" {
+		form.Set("client_id", p.ClientID)
+		if p.ClientSecret != "" {
+			form.Set("client_secret", p.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	client := &http.Client{Timeout: tokenTimeout, Transport: p.transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	parsed, err := parseTokenResponse(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if parsed.Error != "" {
+		if parsed.ErrorDescription != "" {
+			return nil, fmt.Errorf("token request failed: %s: %s", parsed.Error, parsed.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request failed: %s", parsed.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed: HTTP %d", resp.StatusCode)
+	}
+	if parsed.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	token := &Token{
+		AccessToken:  parsed.AccessToken,
+		TokenType:    parsed.TokenType,
+		RefreshToken: parsed.RefreshToken,
+		Scope:        parsed.Scope,
+	}
+	if seconds, err := parsed.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.ExpiresAt = now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// parseTokenResponse decodes a JSON token response. Some providers answer
+// with a URL-encoded form instead.
+func parseTokenResponse(contentType string, body []byte) (*tokenResponse, error) {
+	var parsed tokenResponse
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		parsed.AccessToken = values.Get("access_token")
+		parsed.TokenType = values.Get("token_type")
+		parsed.ExpiresIn = json.Number(values.Get("expires_in"))
+		parsed.RefreshToken = values.Get("refresh_token")
+		parsed.Scope = values.Get("scope")
+		parsed.Error = values.Get("error")
+		parsed.ErrorDescription = values.Get("error_description")
+		return &parsed, nil
+	}
+
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer records token requests and answers with numbered tokens
+type tokenServer struct {
+	*httptest.Server
+	calls     int32
+	grants    []string
+	expiresIn int
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	ts := &tokenServer{expiresIn: 3600}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse token request: %v", err)
+		}
+		n := atomic.AddInt32(&ts.calls, 1)
+		grant := r.PostForm.Get("grant_type")
+		ts.grants = append(ts.grants, grant)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch grant {
+		case GrantClientCredentials:
+			if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error":"invalid_client"}`)
+				return
+			}
+		case GrantPassword:
+			if r.PostForm.Get("username") != "john" || r.PostForm.Get("password") != "hunter2" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant","error_description":"bad credentials"}`)
+				return
+			}
+		case GrantRefreshToken:
+			if r.PostForm.Get("refresh_token") == "revoked" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"token_type":    "bearer",
+			"expires_in":    ts.expiresIn,
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+			"scope":         r.PostForm.Get("scope"),
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func applyOAuth2(t *testing.T, preset *AuthPreset) string {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	if err := preset.Apply(req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return req.Header.Get("Authorization")
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	ts := newTokenServer(t)
+	preset := &AuthPreset{
+		Name:         "api",
+		Type:         "oauth2",
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+		Scope:        "read write",
+		tokens:       NewTokenStore(t.TempDir()),
+	}
+
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-1" {
+		t.Errorf("expected 'Bearer access-1', got '%s'", auth)
+	}
+
+	// The cached token is reused
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-1" {
+		t.Errorf("expected cached token, got '%s'", auth)
+	}
+	if ts.calls != 1 {
+		t.Errorf("expected 1 token request, got %d", ts.calls)
+	}
+}
+
+func TestOAuth2ClientCredentialsInBody(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Error("expected no Basic credentials")
+		}
+		fmt.Fprint(w, `{"access_token":"abc","token_type":"Bearer"}`)
+	}))
+	defer server.Close()
+
+	preset := &AuthPreset{
+		Type:         "oauth2",
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+		ClientAuth:   "body",
+	}
+	if auth := applyOAuth2(t, preset); auth != "Bearer abc" {
+		t.Errorf("expected 'Bearer abc', got '%s'", auth)
+	}
+	if form["client_id"][0] != "client" || form["client_secret"][0] != "s3cret" {
+		t.Errorf("expected client credentials in body, got %v", form)
+	}
+}
+
+func TestOAuth2PasswordGrant(t *testing.T) {
+	ts := newTokenServer(t)
+	preset := &AuthPreset{
+		Name:     "api",
+		Type:     "oauth2",
+		Grant:    "password",
+		TokenURL: ts.URL,
+		ClientID: "client",
+		Username: "john",
+		Password: "hunter2",
+	}
+
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-1" {
+		t.Errorf("expected 'Bearer access-1', got '%s'", auth)
+	}
+
+	preset.Password = "wrong"
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	err := preset.Apply(req)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant: bad credentials") {
+		t.Errorf("expected invalid_grant error, got %v", err)
+	}
+}
+
+func TestOAuth2RefreshTokenGrant(t *testing.T) {
+	ts := newTokenServer(t)
+	preset := &AuthPreset{
+		Name:         "api",
+		Type:         "oauth2",
+		Grant:        "refresh_token",
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		RefreshToken: "initial",
+	}
+
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-1" {
+		t.Errorf("expected 'Bearer access-1', got '%s'", auth)
+	}
+
+	preset.RefreshToken = "revoked"
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	if err := preset.Apply(req); err == nil {
+		t.Error("expected error for revoked refresh token")
+	}
+}
+
+func TestOAuth2RefreshesExpiredToken(t *testing.T) {
+	ts := newTokenServer(t)
+	ts.expiresIn = 60
+	store := NewTokenStore(t.TempDir())
+	preset := &AuthPreset{
+		Name:         "api",
+		Type:         "oauth2",
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+		tokens:       store,
+	}
+
+	applyOAuth2(t, preset)
+
+	// Move the clock to within the expiry skew
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Now().Add(45 * time.Second) }
+
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-2" {
+		t.Errorf("expected refreshed token, got '%s'", auth)
+	}
+	if len(ts.grants) != 2 || ts.grants[1] != GrantRefreshToken {
+		t.Errorf("expected refresh_token grant, got %v", ts.grants)
+	}
+
+	cached, _ := store.Get("api")
+	if cached.AccessToken != "access-2" || cached.RefreshToken != "refresh-2" {
+		t.Errorf("expected refreshed token in cache, got %+v", cached)
+	}
+}
+
+func TestOAuth2FallsBackWhenRefreshFails(t *testing.T) {
+	ts := newTokenServer(t)
+	store := NewTokenStore(t.TempDir())
+	store.Put("api", &Token{
+		AccessToken:  "old",
+		RefreshToken: "revoked",
+		ExpiresAt:    time.Now().Add(-time.Minute),
+	})
+
+	preset := &AuthPreset{
+		Name:         "api",
+		Type:         "oauth2",
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+		tokens:       store,
+	}
+
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-2" {
+		t.Errorf("expected new token, got '%s'", auth)
+	}
+	if len(ts.grants) != 2 || ts.grants[1] != GrantClientCredentials {
+		t.Errorf("expected fallback to client_credentials, got %v", ts.grants)
+	}
+}
+
+func TestOAuth2MissingTokenURL(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	preset := &AuthPreset{Type: "oauth2", ClientID: "client"}
+
+	if err := preset.Apply(req); err == nil {
+		t.Error("expected error for missing tokenUrl")
+	}
+}
+
+func TestOAuth2FormEncodedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		fmt.Fprint(w, "access_token=xyz&token_type=bearer&expires_in=3600")
+	}))
+	defer server.Close()
+
+	preset := &AuthPreset{Type: "oauth2", TokenURL: server.URL, ClientID: "client"}
+	if auth := applyOAuth2(t, preset); auth != "Bearer xyz" {
+		t.Errorf("expected 'Bearer xyz', got '%s'", auth)
+	}
+}
+
+func TestTokenCachePersisted(t *testing.T) {
+	ts := newTokenServer(t)
+	dir := t.TempDir()
+
+	manager := NewManager(dir)
+	manager.Add(&AuthPreset{
+		Name:         "api",
+		Type:         "oauth2",
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+	})
+	preset, _ := manager.Get("api")
+	applyOAuth2(t, preset)
+
+	path := filepath.Join(dir, ".gosh", "tokens.yaml")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected token cache, got %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// A new manager reuses the cached token
+	manager = NewManager(dir)
+	manager.Load()
+	preset, _ = manager.Get("api")
+	if auth := applyOAuth2(t, preset); auth != "Bearer access-1" {
+		t.Errorf("expected cached token, got '%s'", auth)
+	}
+	if ts.calls != 1 {
+		t.Errorf("expected 1 token request, got %d", ts.calls)
+	}
+
+	// Removing the preset drops its token
+	manager.Remove("api")
+	if token, _ := NewTokenStore(dir).Get("api"); token != nil {
+		t.Errorf("expected token removed, got %+v", token)
+	}
+}
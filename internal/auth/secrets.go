@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolveSecrets sets the fields named in the preset's env from
+// environment variables, then runs credential helpers. Other values are
+// literal and used as they are.
+func (p *AuthPreset) resolveSecrets(cache *helperCache) error {
+	for name, variable := range p.Env {
+		field := p.stringField(name)
+		if field == nil {
+			return fmt.Errorf("env: no such field %s", name)
+		}
+		value, ok := os.LookupEnv(variable)
+		if !ok {
+			return fmt.Errorf("environment variable %s for %s is not set", variable, name)
+		}
+		*field = value
+	}
+
+	ttl, err := p.commandTTL()
+	if err != nil {
+		return err
+	}
+	return p.runHelpers(cache, ttl)
+}
+
+// HasSource reports whether a field is read from the environment or a
+// credential helper rather than stored
+func (p *AuthPreset) HasSource(name string) bool {
+	return p.HasCommand(name) || hasField(p, p.Env, name)
+}
+
+// SetEnv reads a field from an environment variable at request time
+func (p *AuthPreset) SetEnv(name, variable string) error {
+	key := p.fieldName(name)
+	if key == "" {
+		return fmt.Errorf("unknown auth preset field: %s", name)
+	}
+	if p.Env == nil {
+		p.Env = make(map[string]string)
+	}
+	p.Env[key] = variable
+	return nil
+}
+
+// Secrets returns the preset's non-empty credential values, so they can be
+// hidden wherever they appear in --verbose output
+func (p *AuthPreset) Secrets() []string {
+	var secrets []string
+	for _, value := range []string{
+		p.Password, p.Token, p.Value, p.ClientSecret, p.RefreshToken,
+		p.SecretKey, p.SessionToken, p.Secret,
+	} {
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// SecretHeaders returns the headers Apply may set that carry credentials.
+// Unlike HeaderNames it leaves out timestamps, nonces and content hashes.
+func (p *AuthPreset) SecretHeaders() []string {
+	names := []string{"Authorization"}
+	switch AuthType(strings.ToLower(p.Type)) {
+	case AuthTypeCustom:
+		names = append(names, p.Header)
+		for _, h := range p.Headers {
+			if key, _, ok := strings.Cut(h, ":"); ok {
+				names = append(names, strings.TrimSpace(key))
+			}
+		}
+	case AuthTypeSigV4:
+		names = append(names, "X-Amz-Security-Token")
+	case AuthTypeHMAC:
+		header := p.Header
+		if header == "" {
+			header = DefaultHMACHeader
+		}
+		names = append(names, header)
+	}
+	return names
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManagerGetResolvesSecrets(t *testing.T) {
+	t.Setenv("GOSH_TEST_PASSWORD", "hunter2")
+	dir := t.TempDir()
+	m := NewManager(dir)
+	preset := &AuthPreset{Name: "api", Type: "basic"}
+	if err := preset.SetEnv("password", "GOSH_TEST_PASSWORD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := preset.SetCommand("username", "echo admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(preset); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := m.Get("api")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resolved.Username != "admin" || resolved.Password != "hunter2" {
+		t.Errorf("expected resolved credentials, got %q/%q", resolved.Username, resolved.Password)
+	}
+	if !resolved.HasSource("password") || !resolved.HasSource("username") || resolved.HasSource("token") {
+		t.Errorf("unexpected HasSource results for %v and %v", resolved.Env, resolved.Commands)
+	}
+
+	// References are saved, never the values
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".gosh", "auth.yaml"))
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), "password: GOSH_TEST_PASSWORD") {
+		t.Errorf("expected secret references on disk, got:\n%s", data)
+	}
+}
+
+func TestEnvSecretErrors(t *testing.T) {
+	tests := []struct {
+		preset   string
+		expected string
+	}{
+		{"env: {token: GOSH_TEST_UNSET_SECRET}", "environment variable GOSH_TEST_UNSET_SECRET for token is not set"},
+		{"env: {colour: HOME}", "env: no such field colour"},
+	}
+
+	for _, tt := range tests {
+		m, _ := writeAuthYAML(t, "presets:\n  api:\n    name: api\n    type: bearer\n    "+tt.preset+"\n")
+		_, err := m.Get("api")
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.preset, tt.expected, err)
+		}
+	}
+}
+
+// Values written before secret sources existed must not be run or expanded
+func TestLiteralSecretsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GOSH_TEST_SECRET", "expanded")
+	m, _ := writeAuthYAML(t, `presets:
+  api:
+    name: api
+    type: basic
+    username: "${GOSH_TEST_SECRET}"
+    password: "!touch `+filepath.Join(dir, "ran")+`"
+`)
+
+	preset, err := m.Get("api")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if preset.Username != "${GOSH_TEST_SECRET}" || !strings.HasPrefix(preset.Password, "!touch ") {
+		t.Errorf("expected literal credentials, got %q/%q", preset.Username, preset.Password)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); !os.IsNotExist(err) {
+		t.Error("expected the password not to be run as a command")
+	}
+}
+
+func TestPresetSecrets(t *testing.T) {
+	preset := &AuthPreset{Type: "basic", Username: "admin", Password: "hunter2"}
+	if got := preset.Secrets(); len(got) != 1 || got[0] != "hunter2" {
+		t.Errorf("expected only the password, got %v", got)
+	}
+
+	custom := &AuthPreset{Type: "custom", Header: "X-API-Key", Value: "k3y", Headers: []string{"X-Tenant: acme"}}
+	got := strings.Join(custom.SecretHeaders(), ",")
+	if got != "Authorization,X-API-Key,X-Tenant" {
+		t.Errorf("unexpected custom secret headers: %s", got)
+	}
+
+	hmac := &AuthPreset{Type: "hmac", Secret: "shh"}
+	if got := strings.Join(hmac.SecretHeaders(), ","); got != "Authorization,"+DefaultHMACHeader {
+		t.Errorf("expected the signature header without the timestamp, got %s", got)
+	}
+}
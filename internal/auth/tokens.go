@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expirySkew refreshes tokens slightly before they expire, so a token
+// doesn't lapse while the request is in flight
+const expirySkew = 30 * time.Second
+
+// now is replaced in tests
+var now = time.Now
+
+// Token is an OAuth 2.0 token set
+type Token struct {
+	AccessToken  string    `yaml:"accessToken"`
+	TokenType    string    `yaml:"tokenType,omitempty"`
+	RefreshToken string    `yaml:"refreshToken,omitempty"`
+	Scope        string    `yaml:"scope,omitempty"`
+	ExpiresAt    time.Time `yaml:"expiresAt,omitempty"`
+}
+
+// Valid reports whether the access token can be used now
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || now().Add(expirySkew).Before(t.ExpiresAt)
+}
+
+// TokenStore caches tokens by preset name in .gosh/tokens.yaml
+type TokenStore struct {
+	path   string
+	mu     sync.Mutex
+	loaded bool
+	tokens map[string]*Token
+	sealer *sealer // Encrypts the cache when presets are encrypted
+}
+
+// sealedTokens is the on-disk form of an encrypted token cache
+type sealedTokens struct {
+	Encrypted *EncryptedData `yaml:"encrypted"`
+}
+
+// NewTokenStore creates a token store for a workspace
+func NewTokenStore(workspaceRoot string) *TokenStore {
+	return &TokenStore{
+		path:   filepath.Join(workspaceRoot, ".gosh", "tokens.yaml"),
+		tokens: make(map[string]*Token),
+	}
+}
+
+// Get returns the cached token for a preset, or nil
+func (s *TokenStore) Get(name string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.tokens[name], nil
+}
+
+// Put caches a token for a preset and writes the store to disk
+func (s *TokenStore) Put(name string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.tokens[name] = token
+	return s.save()
+}
+
+// Delete removes the cached token for a preset
+func (s *TokenStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.tokens[name]; !ok {
+		return nil
+	}
+	delete(s.tokens, name)
+	return s.save()
+}
+
+// setSealer decrypts the cache with the presets' key from now on
+func (s *TokenStore) setSealer(sealer *sealer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sealer = sealer
+	s.loaded = false
+	s.tokens = make(map[string]*Token)
+}
+
+// reseal rewrites the cache encrypted with a new key, or in plaintext when
+// sealer is nil
+func (s *TokenStore) reseal(sealer *sealer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.sealer = sealer
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+	return s.save()
+}
+
+// load reads the store on first use
+func (s *TokenStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.loaded = true
+			return nil
+		}
+		return fmt.Errorf("failed to read token cache: %w", err)
+	}
+
+	var sealed sealedTokens
+	if err := yaml.Unmarshal(data, &sealed); err == nil && sealed.Encrypted != nil && sealed.Encrypted.Ciphertext != "" {
+		if s.sealer == nil {
+			return fmt.Errorf("token cache is encrypted; unlock auth presets first")
+		}
+		// A cache sealed with another key is stale; start over
+		if data, err = s.sealer.open(sealed.Encrypted); err != nil {
+			s.loaded = true
+			return nil
+		}
+	}
+
+	var tokens map[string]*Token
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("failed to parse token cache: %w", err)
+	}
+	if tokens != nil {
+		s.tokens = tokens
+	}
+	s.loaded = true
+	return nil
+}
+
+// save writes the store with owner-only permissions
+func (s *TokenStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(s.tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token cache: %w", err)
+	}
+
+	if s.sealer != nil {
+		encrypted, err := s.sealer.seal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt token cache: %w", err)
+		}
+		if data, err = yaml.Marshal(sealedTokens{Encrypted: encrypted}); err != nil {
+			return fmt.Errorf("failed to marshal token cache: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	return nil
+}
@@ -14,24 +14,82 @@ const (
 	AuthTypeBasic  AuthType = "basic"
 	AuthTypeBearer AuthType = "bearer"
 	AuthTypeCustom AuthType = "custom"
+	AuthTypeOAuth2 AuthType = "oauth2"
+	AuthTypeSigV4  AuthType = "aws-sigv4"
+	AuthTypeDigest AuthType = "digest"
+	AuthTypeHMAC   AuthType = "hmac"
+	AuthTypeJWT    AuthType = "jwt"
+	AuthTypeMTLS   AuthType = "mtls"
 )
 
 // AuthPreset represents a saved authentication preset
 type AuthPreset struct {
 	Name     string   `yaml:"name"`
-	Type     string   `yaml:"type"` // "basic", "bearer", "custom"
+	Type     string   `yaml:"type"` // "basic", "bearer", "custom", "oauth2", "aws-sigv4", "digest", "hmac", "jwt", "mtls"
 	Username string   `yaml:"username"`
 	Password string   `yaml:"password"`
 	Token    string   `yaml:"token"`
-	Header   string   `yaml:"header"`  // Header name for custom auth
-	Prefix   string   `yaml:"prefix"`  // Prefix for custom auth (e.g., "Bearer ", "token ")
+	Header   string   `yaml:"header"`  // Header name for custom and hmac auth
+	Prefix   string   `yaml:"prefix"`  // Prefix for custom and hmac auth (e.g., "Bearer ", "token ")
 	Value    string   `yaml:"value"`   // Value for custom auth
 	Headers  []string `yaml:"headers"` // Additional headers to add
+
+	// OAuth 2.0 settings
+	AuthURL      string `yaml:"authUrl,omitempty"` // Authorize endpoint for "gosh auth login"
+	TokenURL     string `yaml:"tokenUrl,omitempty"`
+	RedirectURL  string `yaml:"redirectUrl,omitempty"` // Loopback callback, random port if empty
+	ClientID     string `yaml:"clientId,omitempty"`
+	ClientSecret string `yaml:"clientSecret,omitempty"`
+	Scope        string `yaml:"scope,omitempty"`        // Space-separated scopes
+	Grant        string `yaml:"grant,omitempty"`        // "client_credentials", "password", "refresh_token", "authorization_code"
+	RefreshToken string `yaml:"refreshToken,omitempty"` // Initial refresh token for the refresh_token grant
+	ClientAuth   string `yaml:"clientAuth,omitempty"`   // "basic" (default) or "body"
+
+	// AWS Signature Version 4 settings
+	AccessKey    string `yaml:"accessKey,omitempty"`
+	SecretKey    string `yaml:"secretKey,omitempty"`
+	SessionToken string `yaml:"sessionToken,omitempty"` // Temporary credentials only
+	Region       string `yaml:"region,omitempty"`
+	Service      string `yaml:"service,omitempty"` // e.g. "execute-api", "s3"
+
+	// HMAC signing settings, also used by jwt auth
+	Secret          string `yaml:"secret,omitempty"`
+	Algorithm       string `yaml:"algorithm,omitempty"`       // "sha256" (default) or "sha512"; "HS256", "RS256" or "ES256" for jwt
+	Encoding        string `yaml:"encoding,omitempty"`        // "hex" (default), "base64" or "base64url"
+	Template        string `yaml:"template,omitempty"`        // Canonical string, see DefaultHMACTemplate
+	TimestampHeader string `yaml:"timestampHeader,omitempty"` // Defaults to X-Timestamp
+	TimestampFormat string `yaml:"timestampFormat,omitempty"` // "unix" (default), "unix-ms", "rfc3339" or "http"
+
+	// JWT settings
+	Claims   map[string]interface{} `yaml:"claims,omitempty"`
+	KeyFile  string                 `yaml:"keyFile,omitempty"`  // PEM private key, or the HS256 secret; also the mtls key
+	KeyID    string                 `yaml:"keyId,omitempty"`    // "kid" header
+	Lifetime string                 `yaml:"lifetime,omitempty"` // e.g. "10m"; defaults to 5m
+
+	// Client certificate settings for mtls auth. Password decrypts a
+	// PKCS#12 certFile.
+	CertFile string `yaml:"certFile,omitempty"` // PEM certificate, or a .p12/.pfx bundle
+	CAFile   string `yaml:"caFile,omitempty"`   // Extra CA bundle for verifying the server
+
+	// Environment variables (e.g. password: API_PASSWORD) read at request
+	// time as the named fields' values
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// Credential helpers: each command (e.g. token: pass show api/prod) runs
+	// at request time and its output is used as the named field's value
+	Commands   map[string]string `yaml:"commands,omitempty"`
+	CommandTTL string            `yaml:"command_ttl,omitempty"` // How long helper output is cached, e.g. "5m"
+
+	root      string            // Workspace root for relative paths, set by the Manager
+	tokens    *TokenStore       // Token cache, set by the Manager
+	digest    *digestState      // Last digest challenge, reused for later requests
+	transport http.RoundTripper // Token endpoint transport, set with SetTransport
 }
 
 // AuthConfig holds all authentication presets
 type AuthConfig struct {
-	Presets map[string]*AuthPreset `yaml:"presets"`
+	Presets   map[string]*AuthPreset `yaml:"presets,omitempty"`
+	Encrypted *EncryptedData         `yaml:"encrypted,omitempty"` // Set instead of Presets when encrypted at rest
 }
 
 // Apply adds the authentication to the given HTTP request
@@ -43,11 +101,51 @@ func (p *AuthPreset) Apply(req *http.Request) error {
 		return p.applyBearer(req)
 	case AuthTypeCustom:
 		return p.applyCustom(req)
+	case AuthTypeOAuth2:
+		return p.applyOAuth2(req)
+	case AuthTypeSigV4:
+		return p.applySigV4(req)
+	case AuthTypeDigest:
+		return p.applyDigest(req)
+	case AuthTypeHMAC:
+		return p.applyHMAC(req)
+	case AuthTypeJWT:
+		return p.applyJWT(req)
+	case AuthTypeMTLS:
+		// The certificate is presented in the TLS handshake, see ClientCertificate
+		return nil
 	default:
 		return fmt.Errorf("unknown auth type: %s", p.Type)
 	}
 }
 
+// HeaderNames returns the headers Apply may set, so they can be removed
+// before a request is redirected to another host
+func (p *AuthPreset) HeaderNames() []string {
+	names := []string{"Authorization"}
+	switch AuthType(strings.ToLower(p.Type)) {
+	case AuthTypeCustom:
+		names = append(names, p.Header)
+		for _, h := range p.Headers {
+			if key, _, ok := strings.Cut(h, ":"); ok {
+				names = append(names, strings.TrimSpace(key))
+			}
+		}
+	case AuthTypeSigV4:
+		names = append(names, "X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token")
+	case AuthTypeHMAC:
+		header, timestampHeader := p.Header, p.TimestampHeader
+		if header == "" {
+			header = DefaultHMACHeader
+		}
+		if timestampHeader == "" {
+			timestampHeader = DefaultHMACTimestampHeader
+		}
+		names = append(names, header, timestampHeader, DefaultHMACNonceHeader)
+	}
+	return names
+}
+
 // applyBasic applies HTTP Basic Authentication
 func (p *AuthPreset) applyBasic(req *http.Request) error {
 	if p.Username == "" {
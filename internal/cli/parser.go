@@ -2,7 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/gosh/internal/output"
+	"github.com/gosh/internal/request"
 )
 
 // Parser handles command-line argument parsing
@@ -32,6 +36,8 @@ func (p *Parser) Parse() (interface{}, error) {
 		return p.parseDelete()
 	case "auth":
 		return p.parseAuth()
+	case "env":
+		return p.parseEnv()
 	case "--version", "-v":
 		return "version", nil
 	case "--help", "-h":
@@ -68,6 +74,12 @@ func (p *Parser) parseRequest() (*ParsedRequest, error) {
 		PathParams:  make(map[string]string),
 	}
 
+	// Template variables in the URL, for the name=value path param shorthand
+	pathVars := make(map[string]bool)
+	for _, name := range request.NewTemplate(url).ExtractPathVars() {
+		pathVars[name] = true
+	}
+
 	// Parse remaining arguments
 	for i := 2; i < len(p.Args); i++ {
 		arg := p.Args[i]
@@ -83,8 +95,32 @@ func (p *Parser) parseRequest() (*ParsedRequest, error) {
 			req.Dry = true
 		case arg == "--info":
 			req.Info = true
+		case arg == "--verbose" || arg == "-v":
+			req.Verbose = true
+		case arg == "--show-secrets":
+			req.ShowSecrets = true
 		case arg == "--no-interactive":
 			req.NoInteractive = true
+		case arg == "--form" || arg == "-f":
+			req.Form = true
+		case arg == "--multipart":
+			req.Multipart = true
+		case arg == "--download":
+			req.Download = true
+		case arg == "--continue":
+			req.Continue = true
+		case arg == "--stream":
+			req.Stream = true
+		case strings.HasPrefix(arg, "--output="):
+			req.Output = strings.TrimPrefix(arg, "--output=")
+			req.Download = true
+		case arg == "--output" || arg == "-o":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("%s requires a file name", arg)
+			}
+			i++
+			req.Output = p.Args[i]
+			req.Download = true
 		case strings.HasPrefix(arg, "--env="):
 			req.Env = strings.TrimPrefix(arg, "--env=")
 		case arg == "--env":
@@ -101,6 +137,14 @@ func (p *Parser) parseRequest() (*ParsedRequest, error) {
 			}
 			i++
 			req.Format = p.Args[i]
+		case strings.HasPrefix(arg, "--filter="):
+			req.Filter = strings.TrimPrefix(arg, "--filter=")
+		case arg == "--filter":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--filter requires an expression")
+			}
+			i++
+			req.Filter = p.Args[i]
 		case strings.HasPrefix(arg, "--auth="):
 			req.Auth = strings.TrimPrefix(arg, "--auth=")
 		case arg == "--auth":
@@ -109,6 +153,102 @@ func (p *Parser) parseRequest() (*ParsedRequest, error) {
 			}
 			i++
 			req.Auth = p.Args[i]
+		case strings.HasPrefix(arg, "--cert="):
+			req.CertFile = strings.TrimPrefix(arg, "--cert=")
+		case arg == "--cert":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--cert requires a file")
+			}
+			i++
+			req.CertFile = p.Args[i]
+		case strings.HasPrefix(arg, "--key="):
+			req.KeyFile = strings.TrimPrefix(arg, "--key=")
+		case arg == "--key":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--key requires a file")
+			}
+			i++
+			req.KeyFile = p.Args[i]
+		case strings.HasPrefix(arg, "--cert-password="):
+			req.CertPassword = strings.TrimPrefix(arg, "--cert-password=")
+		case arg == "--cert-password":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--cert-password requires a value")
+			}
+			i++
+			req.CertPassword = p.Args[i]
+		case strings.HasPrefix(arg, "--tls-min="):
+			req.TLSMin = strings.TrimPrefix(arg, "--tls-min=")
+		case arg == "--tls-min":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--tls-min requires a version")
+			}
+			i++
+			req.TLSMin = p.Args[i]
+		case strings.HasPrefix(arg, "--sni="):
+			req.SNI = strings.TrimPrefix(arg, "--sni=")
+		case arg == "--sni":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--sni requires a server name")
+			}
+			i++
+			req.SNI = p.Args[i]
+		case strings.HasPrefix(arg, "--cacert="):
+			req.CACerts = append(req.CACerts, strings.TrimPrefix(arg, "--cacert="))
+		case arg == "--cacert":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--cacert requires a file")
+			}
+			i++
+			req.CACerts = append(req.CACerts, p.Args[i])
+		case strings.HasPrefix(arg, "--proxy="):
+			req.Proxy = strings.TrimPrefix(arg, "--proxy=")
+		case arg == "--proxy":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--proxy requires a URL")
+			}
+			i++
+			req.Proxy = p.Args[i]
+		case strings.HasPrefix(arg, "--no-proxy="):
+			req.NoProxy = append(req.NoProxy, strings.Split(strings.TrimPrefix(arg, "--no-proxy="), ",")...)
+		case arg == "--no-proxy":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--no-proxy requires a list of hosts")
+			}
+			i++
+			req.NoProxy = append(req.NoProxy, strings.Split(p.Args[i], ",")...)
+		case arg == "--no-auth":
+			req.NoAuth = true
+		case strings.HasPrefix(arg, "--retry="):
+			if err := parseRetry(req, strings.TrimPrefix(arg, "--retry=")); err != nil {
+				return nil, err
+			}
+		case arg == "--retry":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--retry requires a number")
+			}
+			i++
+			if err := parseRetry(req, p.Args[i]); err != nil {
+				return nil, err
+			}
+		case arg == "--follow":
+			req.NoFollow = false
+		case arg == "--no-follow":
+			req.NoFollow = true
+		case strings.HasPrefix(arg, "--max-redirects="):
+			if err := parseMaxRedirects(req, strings.TrimPrefix(arg, "--max-redirects=")); err != nil {
+				return nil, err
+			}
+		case arg == "--max-redirects":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--max-redirects requires a number")
+			}
+			i++
+			if err := parseMaxRedirects(req, p.Args[i]); err != nil {
+				return nil, err
+			}
+		case arg == "--insecure" || arg == "-k":
+			req.Insecure = true
 		case strings.HasPrefix(arg, "-H"):
 			// Header: -H key:value or -H key=value
 			var headerVal string
@@ -146,24 +286,111 @@ func (p *Parser) parseRequest() (*ParsedRequest, error) {
 			}
 			req.Body = bodyVal
 		default:
-			// Could be query param, path param, or unknown
-			if strings.Contains(arg, "==") {
-				parts := strings.SplitN(arg, "==", 2)
-				req.QueryParams[parts[0]] = parts[1]
-			} else if strings.Contains(arg, "=") && !strings.HasPrefix(arg, "-") {
-				// Path parameter: key=value
-				parts := strings.SplitN(arg, "=", 2)
-				req.PathParams[parts[0]] = parts[1]
-			} else {
-				// Unknown parameter
+			// Path param, query param, data item, or unknown
+			if strings.HasPrefix(arg, "-") {
 				return nil, fmt.Errorf("unexpected argument: %s", arg)
 			}
+			if name, val, ok := parsePathParam(arg); ok {
+				req.PathParams[name] = val
+				continue
+			}
+
+			item, ok := request.ParseItem(arg)
+			if !ok {
+				return nil, fmt.Errorf("unexpected argument: %s", arg)
+			}
+			switch {
+			case item.Type == request.ItemQuery:
+				req.QueryParams[item.Key] = item.Value
+			case item.Type == request.ItemData && pathVars[item.Key]:
+				// name=value still sets a path variable when the URL has {name}
+				req.PathParams[item.Key] = item.Value
+			default:
+				req.Items = append(req.Items, item)
+			}
+		}
+	}
+
+	if req.Continue && req.Output == "" {
+		return nil, fmt.Errorf("--continue requires --output")
+	}
+	if req.Download && req.Stream {
+		return nil, fmt.Errorf("--download and --stream cannot be combined")
+	}
+	if !output.IsValidFormat(req.Format) {
+		return nil, fmt.Errorf("invalid format: %s (expected one of %s)", req.Format, strings.Join(output.Formats, ", "))
+	}
+	if (req.Format == output.FormatJSON || req.Format == output.FormatHAR) && (req.Download || req.Stream) {
+		return nil, fmt.Errorf("--format %s cannot be combined with --download or --stream", req.Format)
+	}
+	if req.NoAuth && req.Auth != "" {
+		return nil, fmt.Errorf("--auth and --no-auth cannot be combined")
+	}
+	if req.KeyFile != "" && req.CertFile == "" {
+		return nil, fmt.Errorf("--key requires --cert")
+	}
+	if req.ShowSecrets && !req.Verbose && req.Format != output.FormatHAR {
+		return nil, fmt.Errorf("--show-secrets requires --verbose or --format har")
+	}
+	if req.TLSMin != "" {
+		if _, err := request.ParseTLSVersion(req.TLSMin); err != nil {
+			return nil, err
+		}
+	}
+	if req.Proxy != "" {
+		if _, err := request.ParseProxyURL(req.Proxy); err != nil {
+			return nil, err
+		}
+	}
+	if req.Filter != "" {
+		if _, err := output.ParseFilter(req.Filter); err != nil {
+			return nil, err
+		}
+		if req.Download || req.Stream {
+			return nil, fmt.Errorf("--filter cannot be combined with --download or --stream")
+		}
+		if req.Format == output.FormatHeaders {
+			return nil, fmt.Errorf("--filter cannot be combined with --format headers")
 		}
 	}
 
 	return req, nil
 }
 
+// parsePathParam parses an explicit path parameter: {name}=value
+func parsePathParam(arg string) (string, string, bool) {
+	if !strings.HasPrefix(arg, "{") {
+		return "", "", false
+	}
+	end := strings.Index(arg, "}=")
+	if end < 2 {
+		return "", "", false
+	}
+	return arg[1:end], arg[end+2:], true
+}
+
+// parseMaxRedirects sets the redirect limit; 0 turns following off
+func parseMaxRedirects(req *ParsedRequest, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid --max-redirects: %s (expected a number of redirects)", value)
+	}
+	req.MaxRedirects = n
+	req.NoFollow = n == 0
+	return nil
+}
+
+// parseRetry sets the number of retries; 0 turns retrying off
+func parseRetry(req *ParsedRequest, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid --retry: %s (expected a number of retries)", value)
+	}
+	req.Retry = n
+	req.NoRetry = n == 0
+	return nil
+}
+
 // parseRecall parses a recall command
 func (p *Parser) parseRecall() (*RecallOptions, error) {
 	if len(p.Args) < 2 {
@@ -173,6 +400,8 @@ func (p *Parser) parseRecall() (*RecallOptions, error) {
 	opts := &RecallOptions{
 		Name:              p.Args[1],
 		ParameterOverride: make(map[string]string),
+		PathParams:        make(map[string]string),
+		QueryParams:       make(map[string]string),
 		Headers:           make(map[string]string),
 	}
 
@@ -209,14 +438,51 @@ func (p *Parser) parseRecall() (*RecallOptions, error) {
 			}
 			i++
 			opts.Env = p.Args[i]
+		case arg == "--info":
+			opts.Info = true
+		case arg == "--verbose" || arg == "-v":
+			opts.Verbose = true
+		case arg == "--show-secrets":
+			opts.ShowSecrets = true
+		case strings.HasPrefix(arg, "--filter="):
+			opts.Filter = strings.TrimPrefix(arg, "--filter=")
+		case arg == "--filter":
+			if i+1 >= len(p.Args) {
+				return nil, fmt.Errorf("--filter requires an expression")
+			}
+			i++
+			opts.Filter = p.Args[i]
 		default:
-			// Parameter override: key=value
-			if strings.Contains(arg, "=") {
-				parts := strings.SplitN(arg, "=", 2)
-				opts.ParameterOverride[parts[0]] = parts[1]
+			// Overrides use the same item syntax as requests
+			if name, val, ok := parsePathParam(arg); ok {
+				opts.PathParams[name] = val
+				continue
+			}
+
+			item, ok := request.ParseItem(arg)
+			if !ok || strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("unexpected argument: %s", arg)
 			}
+			switch item.Type {
+			case request.ItemQuery:
+				opts.QueryParams[item.Key] = item.Value
+			case request.ItemData:
+				// Resolved against the saved URL's template variables when recalled
+				opts.ParameterOverride[item.Key] = item.Value
+			default:
+				opts.Items = append(opts.Items, item)
+			}
+		}
+	}
+
+	if opts.Filter != "" {
+		if _, err := output.ParseFilter(opts.Filter); err != nil {
+			return nil, err
 		}
 	}
+	if opts.ShowSecrets && !opts.Verbose {
+		return nil, fmt.Errorf("--show-secrets requires --verbose")
+	}
 
 	return opts, nil
 }
@@ -238,6 +504,27 @@ func (p *Parser) parseDelete() (string, error) {
 
 // parseAuth parses an auth command
 func (p *Parser) parseAuth() (*AuthCommand, error) {
+	// --global targets the user-level presets, wherever it appears
+	global := false
+	args := make([]string, 0, len(p.Args))
+	for _, arg := range p.Args {
+		if arg == "--global" {
+			global = true
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	cmd, err := (&Parser{Args: args}).parseAuthCommand()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Global = global
+	return cmd, nil
+}
+
+// parseAuthCommand parses an auth subcommand and its arguments
+func (p *Parser) parseAuthCommand() (*AuthCommand, error) {
 	if len(p.Args) < 2 {
 		return &AuthCommand{Subcommand: "list"}, nil
 	}
@@ -282,7 +569,47 @@ func (p *Parser) parseAuth() (*AuthCommand, error) {
 			Subcommand: "remove",
 			Name:       p.Args[2],
 		}, nil
+	case "login", "logout":
+		if len(p.Args) < 3 {
+			return nil, fmt.Errorf("auth %s requires: name", subcmd)
+		}
+		return &AuthCommand{
+			Subcommand: subcmd,
+			Name:       p.Args[2],
+		}, nil
+	case "lock", "unlock":
+		return &AuthCommand{Subcommand: subcmd}, nil
 	default:
 		return nil, fmt.Errorf("unknown auth subcommand: %s", subcmd)
 	}
 }
+
+// parseEnv parses an env command
+func (p *Parser) parseEnv() (*EnvCommand, error) {
+	if len(p.Args) < 2 {
+		return &EnvCommand{Subcommand: "list"}, nil
+	}
+
+	subcmd := strings.ToLower(p.Args[1])
+
+	switch subcmd {
+	case "list":
+		return &EnvCommand{Subcommand: "list"}, nil
+	case "show":
+		cmd := &EnvCommand{Subcommand: "show"}
+		if len(p.Args) > 2 {
+			cmd.Name = p.Args[2]
+		}
+		return cmd, nil
+	case "use":
+		if len(p.Args) < 3 {
+			return nil, fmt.Errorf("env use requires: name")
+		}
+		return &EnvCommand{
+			Subcommand: "use",
+			Name:       p.Args[2],
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown env subcommand: %s", subcmd)
+	}
+}
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"testing"
+
+	"github.com/gosh/internal/request"
 )
 
 func TestParseGetRequest(t *testing.T) {
@@ -285,6 +287,67 @@ func TestParseAuthRemoveMissingName(t *testing.T) {
 	}
 }
 
+// TestParseAuthLogin tests auth login and logout commands
+func TestParseAuthLogin(t *testing.T) {
+	for _, subcmd := range []string{"login", "logout"} {
+		parser := NewParser([]string{"auth", subcmd, "github"})
+		result, err := parser.Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		cmd := result.(*AuthCommand)
+		if cmd.Subcommand != subcmd {
+			t.Errorf("expected subcommand %q, got %q", subcmd, cmd.Subcommand)
+		}
+		if cmd.Name != "github" {
+			t.Errorf("expected name 'github', got %q", cmd.Name)
+		}
+
+		if _, err := NewParser([]string{"auth", subcmd}).Parse(); err == nil {
+			t.Errorf("expected error for auth %s without name", subcmd)
+		}
+	}
+}
+
+// TestParseAuthLock tests auth lock and unlock commands
+func TestParseAuthLock(t *testing.T) {
+	for _, subcmd := range []string{"lock", "unlock"} {
+		parser := NewParser([]string{"auth", subcmd})
+		result, err := parser.Parse()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		cmd, ok := result.(*AuthCommand)
+		if !ok {
+			t.Fatalf("expected *AuthCommand, got %T", result)
+		}
+		if cmd.Subcommand != subcmd {
+			t.Errorf("expected subcommand %q, got %q", subcmd, cmd.Subcommand)
+		}
+	}
+}
+
+// TestParseAuthGlobal tests the --global flag on auth commands
+func TestParseAuthGlobal(t *testing.T) {
+	parser := NewParser([]string{"auth", "add", "--global", "bearer", "github", "token=abc"})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cmd := result.(*AuthCommand)
+	if !cmd.Global || cmd.Type != "bearer" || cmd.Name != "github" || cmd.Flags["token"] != "abc" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+
+	result, err = NewParser([]string{"auth", "lock", "--global"}).Parse()
+	if err != nil || !result.(*AuthCommand).Global {
+		t.Errorf("expected global lock, got %+v (%v)", result, err)
+	}
+}
+
 // TestParseAuthInvalidSubcommand tests invalid auth subcommand
 func TestParseAuthInvalidSubcommand(t *testing.T) {
 	parser := NewParser([]string{"auth", "invalid"})
@@ -512,6 +575,136 @@ func TestParseWithAuthSeparate(t *testing.T) {
 	}
 }
 
+// TestParseTLSFlags tests client certificate and verification flags
+func TestParseTLSFlags(t *testing.T) {
+	parser := NewParser([]string{
+		"get", "https://api.internal/users",
+		"--cert", "client.p12", "--cert-password=secret",
+		"--cacert", "ca.pem", "--cacert=other-ca.pem",
+		"-k", "--tls-min", "1.3", "--sni=api.example.com",
+	})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := result.(*ParsedRequest)
+	if req.CertFile != "client.p12" || req.CertPassword != "secret" {
+		t.Errorf("unexpected certificate %q/%q", req.CertFile, req.CertPassword)
+	}
+	if len(req.CACerts) != 2 || req.CACerts[1] != "other-ca.pem" {
+		t.Errorf("expected two CA files, got %v", req.CACerts)
+	}
+	if !req.Insecure || req.TLSMin != "1.3" || req.SNI != "api.example.com" {
+		t.Errorf("unexpected TLS flags %+v", req)
+	}
+
+	for _, args := range [][]string{
+		{"get", "https://x", "--key", "client-key.pem"},
+		{"get", "https://x", "--tls-min", "1.4"},
+		{"get", "https://x", "--cacert"},
+	} {
+		if _, err := NewParser(args).Parse(); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+// TestParseNoAuth tests skipping the default auth preset
+func TestParseNoAuth(t *testing.T) {
+	result, err := NewParser([]string{"get", "https://api.example.com", "--no-auth"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.(*ParsedRequest).NoAuth {
+		t.Error("expected NoAuth")
+	}
+
+	if _, err := NewParser([]string{"get", "https://x", "--no-auth", "--auth", "api"}).Parse(); err == nil {
+		t.Error("expected error combining --auth and --no-auth")
+	}
+}
+
+// TestParseRedirectFlags tests --follow, --no-follow and --max-redirects
+func TestParseRedirectFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		noFollow bool
+		max      int
+	}{
+		{[]string{"--no-follow"}, true, 0},
+		{[]string{"--no-follow", "--follow"}, false, 0},
+		{[]string{"--max-redirects", "3"}, false, 3},
+		{[]string{"--max-redirects=0"}, true, 0},
+	}
+
+	for _, tt := range tests {
+		args := append([]string{"get", "https://api.example.com"}, tt.args...)
+		result, err := NewParser(args).Parse()
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		req := result.(*ParsedRequest)
+		if req.NoFollow != tt.noFollow || req.MaxRedirects != tt.max {
+			t.Errorf("%v: got NoFollow=%v MaxRedirects=%d", tt.args, req.NoFollow, req.MaxRedirects)
+		}
+	}
+
+	for _, value := range []string{"-1", "many"} {
+		if _, err := NewParser([]string{"get", "https://x", "--max-redirects", value}).Parse(); err == nil {
+			t.Errorf("expected error for --max-redirects %s", value)
+		}
+	}
+}
+
+// TestParseRetry tests --retry
+func TestParseRetry(t *testing.T) {
+	result, err := NewParser([]string{"get", "https://api.example.com", "--retry", "3"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req := result.(*ParsedRequest); req.Retry != 3 || req.NoRetry {
+		t.Errorf("expected 3 retries, got %d (NoRetry %v)", req.Retry, req.NoRetry)
+	}
+
+	result, err = NewParser([]string{"get", "https://api.example.com", "--retry=0"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.(*ParsedRequest).NoRetry {
+		t.Error("expected --retry 0 to turn retries off")
+	}
+
+	if _, err := NewParser([]string{"get", "https://x", "--retry", "-2"}).Parse(); err == nil {
+		t.Error("expected error for a negative --retry")
+	}
+}
+
+// TestParseProxyFlags tests --proxy and --no-proxy
+func TestParseProxyFlags(t *testing.T) {
+	result, err := NewParser([]string{
+		"get", "https://api.example.com",
+		"--proxy", "socks5://user:pw@localhost:1080",
+		"--no-proxy", "localhost,.internal",
+		"--no-proxy=10.0.0.0/8",
+	}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := result.(*ParsedRequest)
+	if req.Proxy != "socks5://user:pw@localhost:1080" {
+		t.Errorf("unexpected proxy %q", req.Proxy)
+	}
+	if len(req.NoProxy) != 3 || req.NoProxy[1] != ".internal" || req.NoProxy[2] != "10.0.0.0/8" {
+		t.Errorf("unexpected no-proxy list %v", req.NoProxy)
+	}
+
+	if _, err := NewParser([]string{"get", "https://x", "--proxy", "ftp://proxy:21"}).Parse(); err == nil {
+		t.Error("expected error for an unsupported proxy scheme")
+	}
+}
+
 // TestParseHeaderWithEquals tests header parsing with = separator
 func TestParseHeaderWithEquals(t *testing.T) {
 	parser := NewParser([]string{
@@ -719,3 +912,293 @@ func TestParseHelpShort(t *testing.T) {
 		t.Errorf("expected 'help', got %v", result)
 	}
 }
+
+// TestParseEnvCommands tests env list, show and use commands
+func TestParseEnvCommands(t *testing.T) {
+	tests := []struct {
+		args       []string
+		subcommand string
+		name       string
+	}{
+		{[]string{"env"}, "list", ""},
+		{[]string{"env", "list"}, "list", ""},
+		{[]string{"env", "show"}, "show", ""},
+		{[]string{"env", "show", "staging"}, "show", "staging"},
+		{[]string{"env", "use", "prod"}, "use", "prod"},
+	}
+
+	for _, tt := range tests {
+		result, err := NewParser(tt.args).Parse()
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+
+		cmd, ok := result.(*EnvCommand)
+		if !ok {
+			t.Fatalf("%v: expected EnvCommand, got %T", tt.args, result)
+		}
+		if cmd.Subcommand != tt.subcommand {
+			t.Errorf("%v: expected subcommand %q, got %q", tt.args, tt.subcommand, cmd.Subcommand)
+		}
+		if cmd.Name != tt.name {
+			t.Errorf("%v: expected name %q, got %q", tt.args, tt.name, cmd.Name)
+		}
+	}
+}
+
+// TestParseEnvUseMissingName tests env use without a name
+func TestParseEnvUseMissingName(t *testing.T) {
+	parser := NewParser([]string{"env", "use"})
+	_, err := parser.Parse()
+	if err == nil {
+		t.Fatal("expected error for missing environment name")
+	}
+}
+
+// TestParseRequestItems tests HTTPie-style data items
+func TestParseRequestItems(t *testing.T) {
+	parser := NewParser([]string{
+		"post", "https://api.example.com/users/{id}",
+		"name=John",
+		"age:=42",
+		"user[address][city]=Paris",
+		"bio@bio.txt",
+		"{id}=7",
+		"limit==10",
+	})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := result.(*ParsedRequest)
+	expected := []request.Item{
+		{Type: request.ItemData, Key: "name", Value: "John"},
+		{Type: request.ItemRawJSON, Key: "age", Value: "42"},
+		{Type: request.ItemData, Key: "user[address][city]", Value: "Paris"},
+		{Type: request.ItemFile, Key: "bio", Value: "bio.txt"},
+	}
+	if len(req.Items) != len(expected) {
+		t.Fatalf("expected %d items, got %d: %v", len(expected), len(req.Items), req.Items)
+	}
+	for i, item := range expected {
+		if req.Items[i] != item {
+			t.Errorf("item %d: got %+v, want %+v", i, req.Items[i], item)
+		}
+	}
+	if req.PathParams["id"] != "7" {
+		t.Errorf("expected path param id '7', got %q", req.PathParams["id"])
+	}
+	if req.QueryParams["limit"] != "10" {
+		t.Errorf("expected query param limit '10', got %q", req.QueryParams["limit"])
+	}
+}
+
+// TestParseItemMatchingNoPathVar tests that name=value is a data field without {name}
+func TestParseItemMatchingNoPathVar(t *testing.T) {
+	parser := NewParser([]string{"post", "https://api.example.com/users", "id=7"})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := result.(*ParsedRequest)
+	if len(req.PathParams) != 0 {
+		t.Errorf("expected no path params, got %v", req.PathParams)
+	}
+	if len(req.Items) != 1 || req.Items[0].Key != "id" {
+		t.Errorf("expected data item id, got %v", req.Items)
+	}
+}
+
+// TestParseWithForm tests the --form flag
+func TestParseWithForm(t *testing.T) {
+	for _, flag := range []string{"--form", "-f"} {
+		parser := NewParser([]string{"post", "https://api.example.com/login", flag, "user=john"})
+		result, err := parser.Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.(*ParsedRequest).Form {
+			t.Errorf("%s: expected Form to be set", flag)
+		}
+	}
+}
+
+// TestParseRecallItems tests recall overrides using item syntax
+func TestParseRecallItems(t *testing.T) {
+	parser := NewParser([]string{
+		"recall", "create-user",
+		"name=Jane",
+		"age:=31",
+		"{id}=9",
+		"page==2",
+		"--info",
+	})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := result.(*RecallOptions)
+	if opts.ParameterOverride["name"] != "Jane" {
+		t.Errorf("expected name override 'Jane', got %q", opts.ParameterOverride["name"])
+	}
+	if len(opts.Items) != 1 || opts.Items[0].Key != "age" {
+		t.Errorf("expected raw JSON item age, got %v", opts.Items)
+	}
+	if opts.PathParams["id"] != "9" {
+		t.Errorf("expected path param id '9', got %q", opts.PathParams["id"])
+	}
+	if opts.QueryParams["page"] != "2" {
+		t.Errorf("expected query param page '2', got %q", opts.QueryParams["page"])
+	}
+	if !opts.Info {
+		t.Error("expected Info to be set")
+	}
+}
+
+// TestParseWithMultipart tests the --multipart flag with file items
+func TestParseWithMultipart(t *testing.T) {
+	parser := NewParser([]string{
+		"post", "https://api.example.com/upload",
+		"--multipart",
+		"image@photo.png;type=image/png",
+	})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := result.(*ParsedRequest)
+	if !req.Multipart {
+		t.Error("expected Multipart to be set")
+	}
+	if len(req.Items) != 1 || req.Items[0].Value != "photo.png;type=image/png" {
+		t.Errorf("expected file item, got %v", req.Items)
+	}
+}
+
+// TestParseDownloadFlags tests --download, --output, --continue and --stream
+func TestParseDownloadFlags(t *testing.T) {
+	parser := NewParser([]string{
+		"get", "https://example.com/file.tar.gz",
+		"-o", "out.tar.gz",
+		"--continue",
+	})
+	result, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := result.(*ParsedRequest)
+	if !req.Download || req.Output != "out.tar.gz" || !req.Continue {
+		t.Errorf("got Download=%v Output=%q Continue=%v", req.Download, req.Output, req.Continue)
+	}
+
+	result, err = NewParser([]string{"get", "https://example.com/events", "--stream"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.(*ParsedRequest).Stream {
+		t.Error("expected Stream to be set")
+	}
+}
+
+// TestParseDownloadFlagErrors tests invalid download flag combinations
+func TestParseDownloadFlagErrors(t *testing.T) {
+	invalid := [][]string{
+		{"get", "https://example.com/file", "--download", "--continue"},
+		{"get", "https://example.com/file", "--download", "--stream"},
+		{"get", "https://example.com/file", "-o"},
+	}
+	for _, args := range invalid {
+		if _, err := NewParser(args).Parse(); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// TestParseFormatValidation tests --format values and combinations
+func TestParseFormatValidation(t *testing.T) {
+	result, err := NewParser([]string{"get", "https://example.com", "--format", "har"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.(*ParsedRequest).Format != "har" {
+		t.Errorf("expected format har, got %q", result.(*ParsedRequest).Format)
+	}
+
+	invalid := [][]string{
+		{"get", "https://example.com", "--format=yaml"},
+		{"get", "https://example.com", "--format", "json", "--stream"},
+		{"get", "https://example.com", "--format=har", "-o", "out.har"},
+	}
+	for _, args := range invalid {
+		if _, err := NewParser(args).Parse(); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// TestParseFilter tests --filter on requests and recall
+func TestParseFilter(t *testing.T) {
+	result, err := NewParser([]string{"get", "https://example.com", "--filter", "$.items[*].id"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.(*ParsedRequest).Filter != "$.items[*].id" {
+		t.Errorf("got filter %q", result.(*ParsedRequest).Filter)
+	}
+
+	result, err = NewParser([]string{"recall", "users", "--filter=.items[].name"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.(*RecallOptions).Filter != ".items[].name" {
+		t.Errorf("got filter %q", result.(*RecallOptions).Filter)
+	}
+
+	invalid := [][]string{
+		{"get", "https://example.com", "--filter", ".items["},
+		{"get", "https://example.com", "--filter"},
+		{"get", "https://example.com", "--filter", ".a", "--stream"},
+		{"get", "https://example.com", "--filter", ".a", "--format", "headers"},
+		{"recall", "users", "--filter", "nope"},
+	}
+	for _, args := range invalid {
+		if _, err := NewParser(args).Parse(); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// TestParseVerboseFlags tests -v, --verbose and --show-secrets, and that -v
+// is still the version flag on its own
+func TestParseVerboseFlags(t *testing.T) {
+	result, err := NewParser([]string{"get", "https://api.example.com", "-v"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req := result.(*ParsedRequest); !req.Verbose || req.ShowSecrets {
+		t.Errorf("expected verbose without secrets, got %v/%v", req.Verbose, req.ShowSecrets)
+	}
+
+	result, err = NewParser([]string{"recall", "my-call", "--verbose", "--show-secrets"}).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts := result.(*RecallOptions); !opts.Verbose || !opts.ShowSecrets {
+		t.Errorf("expected verbose recall with secrets, got %v/%v", opts.Verbose, opts.ShowSecrets)
+	}
+
+	if _, err := NewParser([]string{"get", "https://x", "--show-secrets"}).Parse(); err == nil {
+		t.Error("expected error for --show-secrets without --verbose")
+	}
+	if _, err := NewParser([]string{"get", "https://x", "--format", "har", "--show-secrets"}).Parse(); err != nil {
+		t.Errorf("expected --show-secrets to apply to HAR output, got %v", err)
+	}
+	if result, _ := NewParser([]string{"-v"}).Parse(); result != "version" {
+		t.Errorf("expected -v alone to print the version, got %v", result)
+	}
+}
@@ -1,5 +1,7 @@
 package cli
 
+import "github.com/gosh/internal/request"
+
 // ParsedRequest holds all parsed CLI arguments
 type ParsedRequest struct {
 	Method       string
@@ -7,9 +9,12 @@ type ParsedRequest struct {
 	Headers      map[string]string
 	QueryParams  map[string]string
 	Body         string
+	Items        []request.Item    // HTTPie-style data items (name=value, age:=42, field@file)
 	PathParams   map[string]string // {var} style parameters
 	HasStdinBody bool
 	// Flags
+	Form          bool   // Encode data items as form fields
+	Multipart     bool   // Stream data items as multipart/form-data
 	Save          string // Name to save as
 	Dry           bool   // Don't execute
 	Info          bool   // Show full response info
@@ -17,14 +22,44 @@ type ParsedRequest struct {
 	Env           string // Environment to use
 	Format        string // Output format
 	Auth          string // Authentication preset to use (format: "type:name")
+	Download      bool   // Write the body to a file
+	Output        string // File to download to
+	Continue      bool   // Resume a partial download
+	Stream        bool   // Print the body as it arrives
+	Filter        string // JSONPath or jq expression applied to the response
+	NoAuth        bool   // Skip the workspace defaultAuth preset
+	NoFollow      bool   // Return redirects instead of following them
+	MaxRedirects  int    // Redirects to follow; 0 for the default
+	Retry         int    // Retries for failed attempts; 0 uses .gosh.yaml
+	NoRetry       bool   // Send once even if .gosh.yaml configures retries
+	Verbose       bool   // Dump each request and response on stderr
+	ShowSecrets   bool   // Don't redact credentials in the verbose dump
+	// TLS
+	CertFile     string   // Client certificate (PEM, .p12 or .pfx)
+	KeyFile      string   // Client key for a PEM certificate
+	CertPassword string   // PKCS#12 password
+	CACerts      []string // Extra CA bundles
+	Insecure     bool     // Skip server certificate verification
+	TLSMin       string   // Minimum TLS version
+	SNI          string   // Server name override
+	// Proxy
+	Proxy   string   // Proxy URL
+	NoProxy []string // Hosts reached without the proxy
 }
 
 // RecallOptions holds options for recall command
 type RecallOptions struct {
 	Name              string
-	ParameterOverride map[string]string
+	ParameterOverride map[string]string // name=value: path variable or data field
+	PathParams        map[string]string // {name}=value path variables
+	QueryParams       map[string]string
+	Items             []request.Item // Other data items (age:=42, field@file)
 	Headers           map[string]string
 	Env               string
+	Info              bool   // Show full response info
+	Filter            string // JSONPath or jq expression applied to the response
+	Verbose           bool   // Dump each request and response on stderr
+	ShowSecrets       bool   // Don't redact credentials in the verbose dump
 }
 
 // AuthCommand holds auth subcommand details
@@ -33,4 +68,11 @@ type AuthCommand struct {
 	Type       string            // Auth type: "basic", "bearer", "custom"
 	Name       string            // Preset name
 	Flags      map[string]string // Additional flags for add/remove
+	Global     bool              // Use the user-level presets instead of the workspace's
+}
+
+// EnvCommand holds env subcommand details
+type EnvCommand struct {
+	Subcommand string // "list", "show", "use"
+	Name       string // Environment name
 }
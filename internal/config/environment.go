@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GetActiveEnvironmentPath returns the file where the active environment is persisted
+func GetActiveEnvironmentPath(workspaceRoot string) string {
+	return filepath.Join(workspaceRoot, ".gosh", "environment")
+}
+
+// LoadActiveEnvironment reads the persisted active environment name.
+// An empty name is returned when no environment has been selected.
+func LoadActiveEnvironment(workspaceRoot string) (string, error) {
+	data, err := os.ReadFile(GetActiveEnvironmentPath(workspaceRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read active environment: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SaveActiveEnvironment persists the active environment name
+func SaveActiveEnvironment(workspaceRoot, name string) error {
+	path := GetActiveEnvironmentPath(workspaceRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create .gosh directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write active environment: %w", err)
+	}
+	return nil
+}
+
+// ClearActiveEnvironment forgets the persisted active environment
+func ClearActiveEnvironment(workspaceRoot string) error {
+	if err := os.Remove(GetActiveEnvironmentPath(workspaceRoot)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear active environment: %w", err)
+	}
+	return nil
+}
+
+// EnvironmentNames returns the sorted names of all configured environments
+func (w *Workspace) EnvironmentNames() []string {
+	if w.Config == nil {
+		return nil
+	}
+	names := make([]string, 0, len(w.Config.Environments))
+	for name := range w.Config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasEnvironment reports whether the workspace config defines the named environment
+func (w *Workspace) HasEnvironment(name string) bool {
+	if w.Config == nil {
+		return false
+	}
+	_, ok := w.Config.Environments[name]
+	return ok
+}
+
+// CheckDefaultEnvironment reports a global defaultEnvironment that this
+// workspace doesn't define. Workspaces without environments accept any name.
+func (w *Workspace) CheckDefaultEnvironment(name string) error {
+	names := w.EnvironmentNames()
+	if name == "" || len(names) == 0 || w.HasEnvironment(name) {
+		return nil
+	}
+	return fmt.Errorf("defaultEnvironment %s is not defined in .gosh.yaml (available: %s)", name, strings.Join(names, ", "))
+}
+
+// ResolveEnvironment returns the workspace variables with the named
+// environment merged over them. An empty name returns the base variables.
+func (w *Workspace) ResolveEnvironment(name string) (map[string]string, error) {
+	merged := make(map[string]string, len(w.Env))
+	for key, val := range w.Env {
+		merged[key] = val
+	}
+
+	if name == "" {
+		return merged, nil
+	}
+	if !w.HasEnvironment(name) {
+		return nil, fmt.Errorf("unknown environment: %s", name)
+	}
+
+	for key, val := range w.Config.Environments[name] {
+		merged[key] = val
+	}
+	return merged, nil
+}
+
+// SelectEnvironment merges the named environment into the workspace variables
+// and records it as the selected environment
+func (w *Workspace) SelectEnvironment(name string) error {
+	env, err := w.ResolveEnvironment(name)
+	if err != nil {
+		return err
+	}
+	w.Env = env
+	w.Environment = name
+	return nil
+}
+
+// BaseURL returns the base URL for relative request paths. The selected
+// environment's entry in baseUrls overrides the workspace-level baseUrl.
+func (w *Workspace) BaseURL() string {
+	if w.Config == nil {
+		return ""
+	}
+	if baseURL, ok := w.Config.BaseURLs[w.Environment]; ok && w.Environment != "" {
+		return baseURL
+	}
+	return w.Config.BaseURL
+}
+
+// TLS returns the workspace TLS settings with the selected environment's
+// settings merged over them
+func (w *Workspace) TLS() TLSConfig {
+	if w.Config == nil || w.Config.TLS == nil {
+		return TLSConfig{}
+	}
+	merged := w.Config.TLS.TLSConfig
+	override, ok := w.Config.TLS.Environments[w.Environment]
+	if w.Environment == "" || !ok {
+		return merged
+	}
+
+	if override.CertFile != "" {
+		merged.CertFile = override.CertFile
+		merged.KeyFile = override.KeyFile
+		merged.CertPassword = override.CertPassword
+	}
+	if len(override.CAFiles) > 0 {
+		merged.CAFiles = override.CAFiles
+	}
+	if override.Insecure {
+		merged.Insecure = true
+	}
+	if override.MinVersion != "" {
+		merged.MinVersion = override.MinVersion
+	}
+	if override.ServerName != "" {
+		merged.ServerName = override.ServerName
+	}
+	return merged
+}
+
+// Proxy returns the workspace proxy settings with the selected
+// environment's settings merged over them
+func (w *Workspace) Proxy() ProxyConfig {
+	if w.Config == nil || w.Config.Proxy == nil {
+		return ProxyConfig{}
+	}
+	merged := w.Config.Proxy.ProxyConfig
+	override, ok := w.Config.Proxy.Environments[w.Environment]
+	if w.Environment == "" || !ok {
+		return merged
+	}
+
+	if override.URL != "" {
+		merged.URL = override.URL
+	}
+	if len(override.NoProxy) > 0 {
+		merged.NoProxy = override.NoProxy
+	}
+	return merged
+}
+
+// UnmarshalYAML accepts a list of rules or a single preset name
+func (r *AuthRules) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = AuthRules{{Auth: node.Value}}
+		return nil
+	}
+	var rules []AuthRule
+	if err := node.Decode(&rules); err != nil {
+		return err
+	}
+	*r = rules
+	return nil
+}
+
+// DefaultAuth returns the auth preset for a request to host (with an
+// optional port) in the selected environment, or "" when no rule matches
+func (w *Workspace) DefaultAuth(host string) string {
+	if rule := w.DefaultAuthRule(host); rule != nil {
+		return rule.Auth
+	}
+	return ""
+}
+
+// DefaultAuthRule returns the first defaultAuth rule matching host in the
+// selected environment, or nil
+func (w *Workspace) DefaultAuthRule(host string) *AuthRule {
+	if w.Config == nil {
+		return nil
+	}
+	hostname := host
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		hostname = host[:i]
+	}
+
+	for i, rule := range w.Config.DefaultAuth {
+		if rule.Env != "" && rule.Env != w.Environment {
+			continue
+		}
+		if rule.Host != "" && !matchHost(rule.Host, host, hostname) {
+			continue
+		}
+		return &w.Config.DefaultAuth[i]
+	}
+	return nil
+}
+
+// matchHost matches a host glob against host:port, or the bare host name
+// when the pattern has no port
+func matchHost(pattern, host, hostname string) bool {
+	pattern = strings.ToLower(pattern)
+	if ok, _ := path.Match(pattern, strings.ToLower(host)); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, strings.ToLower(hostname))
+	return ok
+}
@@ -9,18 +9,28 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// LoadGlobalConfig loads global config from $XDG_CONFIG_HOME/gosh/config.yaml
-func LoadGlobalConfig() (*GlobalConfig, error) {
+// GlobalConfigDir returns the user-level config directory,
+// $XDG_CONFIG_HOME/gosh or ~/.config/gosh
+func GlobalConfigDir() (string, error) {
 	configHome := os.Getenv("XDG_CONFIG_HOME")
 	if configHome == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		configHome = filepath.Join(home, ".config")
 	}
+	return filepath.Join(configHome, "gosh"), nil
+}
+
+// LoadGlobalConfig loads global config from $XDG_CONFIG_HOME/gosh/config.yaml
+func LoadGlobalConfig() (*GlobalConfig, error) {
+	configDir, err := GlobalConfigDir()
+	if err != nil {
+		return nil, err
+	}
 
-	configPath := filepath.Join(configHome, "gosh", "config.yaml")
+	configPath := filepath.Join(configDir, "config.yaml")
 	if _, err := os.Stat(configPath); err != nil {
 		// Config file doesn't exist, return empty config
 		return &GlobalConfig{}, nil
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetTrustedPath returns the user-level file listing the workspace
+// credential helpers the user has confirmed. It is kept outside the
+// workspace so a repository can't mark its own helpers as trusted.
+func GetTrustedPath() (string, error) {
+	configDir, err := GlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "trusted"), nil
+}
+
+// IsTrusted reports whether key was recorded with Trust
+func IsTrusted(key string) (bool, error) {
+	path, err := GetTrustedPath()
+	if err != nil {
+		return false, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read trusted helpers: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == key {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Trust records key as confirmed by the user
+func Trust(key string) error {
+	path, err := GetTrustedPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write trusted helpers: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, key); err != nil {
+		return fmt.Errorf("failed to write trusted helpers: %w", err)
+	}
+	return nil
+}
@@ -6,19 +6,80 @@ type GlobalConfig struct {
 	PrettyPrint        bool   `yaml:"prettyPrint"`
 	Timeout            string `yaml:"timeout"`
 	UserAgent          string `yaml:"userAgent"`
+	Theme              string `yaml:"theme"`
 }
 
 // WorkspaceConfig represents workspace-level configuration
 type WorkspaceConfig struct {
 	Name           string                       `yaml:"name"`
 	BaseURL        string                       `yaml:"baseUrl"`
+	BaseURLs       map[string]string            `yaml:"baseUrls,omitempty"` // Per-environment baseUrl overrides
 	DefaultHeaders map[string]string            `yaml:"defaultHeaders"`
 	Environments   map[string]map[string]string `yaml:"environments"`
+	TLS            *WorkspaceTLS                `yaml:"tls,omitempty"`
+	DefaultAuth    AuthRules                    `yaml:"defaultAuth,omitempty"`
+	Retry          *RetryConfig                 `yaml:"retry,omitempty"`
+	Proxy          *WorkspaceProxy              `yaml:"proxy,omitempty"`
+}
+
+// RetryConfig controls how failed requests are retried. Empty fields use
+// the defaults.
+type RetryConfig struct {
+	Attempts      int      `yaml:"attempts"`                // Retries after the first attempt
+	Statuses      []int    `yaml:"statuses,omitempty"`      // Defaults to 408, 429, 500, 502, 503, 504
+	NetworkErrors *bool    `yaml:"networkErrors,omitempty"` // Retry connection failures; defaults to true
+	Methods       []string `yaml:"methods,omitempty"`       // Defaults to idempotent methods
+	Backoff       string   `yaml:"backoff,omitempty"`       // First delay, e.g. "500ms"; doubled each retry
+	MaxBackoff    string   `yaml:"maxBackoff,omitempty"`    // Longest delay, e.g. "30s"
+}
+
+// AuthRule selects an auth preset for requests without --auth. Empty
+// fields match anything.
+type AuthRule struct {
+	Auth string `yaml:"auth"`
+	Host string `yaml:"host,omitempty"` // Host glob, e.g. "*.example.com" or "localhost:*"
+	Env  string `yaml:"env,omitempty"`  // Environment name
+}
+
+// AuthRules are tried in order; the first match wins. A plain preset name
+// is a single rule that matches every request.
+type AuthRules []AuthRule
+
+// TLSConfig holds TLS settings for requests. Paths are relative to the
+// workspace root.
+type TLSConfig struct {
+	CertFile     string   `yaml:"certFile,omitempty"`     // PEM, or a .p12/.pfx bundle
+	KeyFile      string   `yaml:"keyFile,omitempty"`      // Key for a PEM certificate
+	CertPassword string   `yaml:"certPassword,omitempty"` // PKCS#12 password
+	CAFiles      []string `yaml:"caFiles,omitempty"`
+	Insecure     bool     `yaml:"insecure,omitempty"`
+	MinVersion   string   `yaml:"minVersion,omitempty"` // "1.0" to "1.3"
+	ServerName   string   `yaml:"serverName,omitempty"` // SNI override
+}
+
+// WorkspaceTLS is the workspace TLS config, with per-environment overrides
+type WorkspaceTLS struct {
+	TLSConfig    `yaml:",inline"`
+	Environments map[string]TLSConfig `yaml:"environments,omitempty"`
+}
+
+// ProxyConfig routes requests through a proxy
+type ProxyConfig struct {
+	URL     string   `yaml:"url,omitempty"`     // http://, https://, socks5:// or socks5h://, with optional user:password@
+	NoProxy []string `yaml:"noProxy,omitempty"` // Hosts, domains, IPs and CIDRs reached directly
+}
+
+// WorkspaceProxy is the workspace proxy config, with per-environment
+// overrides
+type WorkspaceProxy struct {
+	ProxyConfig  `yaml:",inline"`
+	Environments map[string]ProxyConfig `yaml:"environments,omitempty"`
 }
 
 // Workspace holds information about the current workspace
 type Workspace struct {
-	Root   string            // Root directory of workspace
-	Config *WorkspaceConfig  // Loaded config
-	Env    map[string]string // Merged environment variables
+	Root        string            // Root directory of workspace
+	Config      *WorkspaceConfig  // Loaded config
+	Env         map[string]string // Merged environment variables
+	Environment string            // Name of the selected environment, if any
 }
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultDownloadName is used when neither the headers nor the URL name a file
+const defaultDownloadName = "download"
+
+// DownloadFilename picks a file name for a download from the
+// Content-Disposition header, falling back to the last URL path segment
+func DownloadFilename(headers map[string][]string, rawURL string) string {
+	if disposition := getHeader(headers, "Content-Disposition"); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil {
+			if name := sanitizeFilename(params["filename"]); name != "" {
+				return name
+			}
+		}
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := sanitizeFilename(path.Base(u.Path)); name != "" {
+			return name
+		}
+	}
+
+	return defaultDownloadName
+}
+
+// AvailableFilename returns name, or name with a numeric suffix if a file
+// with that name already exists
+func AvailableFilename(name string) string {
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return name
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// ResumeOffset returns the size of a partially downloaded file, or 0 if it
+// doesn't exist
+func ResumeOffset(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if info.IsDir() {
+		return 0, fmt.Errorf("%s is a directory", path)
+	}
+	return info.Size(), nil
+}
+
+// WriteDownload copies body into the file at path, appending to it when
+// resuming a partial download. Bytes are also written to progress, if set.
+// It returns the number of bytes written.
+func WriteDownload(path string, body io.Reader, resume bool, progress io.Writer) (int64, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open download file: %w", err)
+	}
+	defer file.Close()
+
+	var w io.Writer = file
+	if progress != nil {
+		w = io.MultiWriter(file, progress)
+	}
+
+	written, err := io.Copy(w, body)
+	if err != nil {
+		return written, fmt.Errorf("download interrupted after %d bytes: %w", written, err)
+	}
+	return written, file.Close()
+}
+
+// sanitizeFilename keeps only the final path element of a server-supplied name
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string][]string
+		url      string
+		expected string
+	}{
+		{
+			name:     "from URL path",
+			url:      "https://example.com/files/report.pdf?v=2",
+			expected: "report.pdf",
+		},
+		{
+			name:     "from Content-Disposition",
+			headers:  map[string][]string{"Content-Disposition": {`attachment; filename="data.csv"`}},
+			url:      "https://example.com/export",
+			expected: "data.csv",
+		},
+		{
+			name:     "Content-Disposition path is stripped",
+			headers:  map[string][]string{"Content-Disposition": {`attachment; filename="../../etc/passwd"`}},
+			url:      "https://example.com/export",
+			expected: "passwd",
+		},
+		{
+			name:     "no name available",
+			url:      "https://example.com/",
+			expected: "download",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DownloadFilename(tt.headers, tt.url)
+			if result != tt.expected {
+				t.Errorf("got %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAvailableFilename(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file.txt")
+
+	if got := AvailableFilename(name); got != name {
+		t.Errorf("got %q, want %q", got, name)
+	}
+
+	if err := os.WriteFile(name, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join(dir, "file-1.txt")
+	if got := AvailableFilename(name); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestWriteDownloadResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.bin")
+
+	written, err := WriteDownload(path, strings.NewReader("hello "), false, nil)
+	if err != nil || written != 6 {
+		t.Fatalf("first write: got (%d, %v)", written, err)
+	}
+
+	offset, err := ResumeOffset(path)
+	if err != nil || offset != 6 {
+		t.Fatalf("resume offset: got (%d, %v)", offset, err)
+	}
+
+	var progress strings.Builder
+	if _, err := WriteDownload(path, strings.NewReader("world"), true, &progress); err != nil {
+		t.Fatalf("resumed write: %v", err)
+	}
+	if progress.String() != "world" {
+		t.Errorf("progress writer: got %q", progress.String())
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "hello world" {
+		t.Errorf("file contents: got %q", data)
+	}
+
+	// A fresh download truncates the file
+	if _, err := WriteDownload(path, strings.NewReader("new"), false, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "new" {
+		t.Errorf("file contents: got %q", data)
+	}
+}
+
+func TestResumeOffsetMissingFile(t *testing.T) {
+	offset, err := ResumeOffset(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || offset != 0 {
+		t.Errorf("got (%d, %v), want (0, nil)", offset, err)
+	}
+}
@@ -0,0 +1,808 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosh/internal/request"
+)
+
+// ErrNoMatch is returned when a filter selects nothing, such as a missing path
+var ErrNoMatch = errors.New("filter matched nothing")
+
+// Filter is a compiled --filter expression. It accepts JSONPath
+// ($.items[*].id, $..name, $.items[?(@.age > 30)]) or a jq subset
+// (.items[].id, .items[0], .[] | select(.active) | .name, keys, length).
+type Filter struct {
+	expr   string
+	stages [][]filterStep // stages are joined by "|"
+}
+
+// filterStep maps one value to zero or more values
+type filterStep func(v interface{}) ([]interface{}, error)
+
+// ParseFilter compiles a filter expression
+func ParseFilter(expr string) (*Filter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("invalid filter: expression is empty")
+	}
+
+	filter := &Filter{expr: expr}
+	if strings.HasPrefix(expr, "$") {
+		steps, err := parsePath(expr[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+		}
+		filter.stages = [][]filterStep{steps}
+		return filter, nil
+	}
+
+	for _, stage := range splitTopLevel(expr, "|") {
+		steps, err := parseStage(strings.TrimSpace(stage))
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+		}
+		filter.stages = append(filter.stages, steps)
+	}
+	return filter, nil
+}
+
+// String returns the filter expression
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Apply evaluates the filter against a decoded document
+func (f *Filter) Apply(doc interface{}) ([]interface{}, error) {
+	values := []interface{}{doc}
+	for _, steps := range f.stages {
+		for _, step := range steps {
+			var next []interface{}
+			for _, v := range values {
+				results, err := step(v)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, results...)
+			}
+			values = next
+		}
+	}
+	return values, nil
+}
+
+// FilterJSON applies filter to a JSON document and formats each result on
+// its own line. Strings are printed unquoted; other values as JSON, indented
+// on a TTY and compact otherwise. It returns ErrNoMatch if nothing matched.
+func (f *Formatter) FilterJSON(data []byte, filter *Filter) (string, error) {
+	doc, err := decodeOrdered(data)
+	if err != nil {
+		return "", fmt.Errorf("cannot apply filter: response body is not JSON")
+	}
+
+	results, err := filter.Apply(doc)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoMatch, filter)
+	}
+
+	var out strings.Builder
+	for _, result := range results {
+		if s, ok := result.(string); ok {
+			out.WriteString(s)
+			out.WriteString("\n")
+			continue
+		}
+
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return "", err
+		}
+		if f.isTTY {
+			out.WriteString(f.prettyPrintJSON(encoded))
+		} else {
+			out.Write(encoded)
+		}
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+// FilterResponse applies filter to the response body, or to the whole
+// envelope for the json and har formats
+func (f *Formatter) FilterResponse(resp *request.Response, format string, filter *Filter) (string, error) {
+	data := resp.Body
+	switch format {
+	case FormatJSON, FormatHAR:
+		var doc interface{} = newEnvelope(resp)
+		if format == FormatHAR {
+			doc = newHAR(resp, f.redact)
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+		data = encoded
+	}
+	return f.FilterJSON(data, filter)
+}
+
+// parseStage parses one jq stage: a path, keys, length or select(cond)
+func parseStage(stage string) ([]filterStep, error) {
+	switch {
+	case stage == "":
+		return nil, fmt.Errorf("empty pipeline stage")
+	case stage == "keys":
+		return []filterStep{keysStep}, nil
+	case stage == "length":
+		return []filterStep{lengthStep}, nil
+	case strings.HasPrefix(stage, "select(") && strings.HasSuffix(stage, ")"):
+		cond, err := parseCondition(stage[len("select(") : len(stage)-1])
+		if err != nil {
+			return nil, err
+		}
+		return []filterStep{func(v interface{}) ([]interface{}, error) {
+			if cond(v) {
+				return []interface{}{v}, nil
+			}
+			return nil, nil
+		}}, nil
+	case strings.HasPrefix(stage, "."):
+		return parsePath(stage)
+	default:
+		return nil, fmt.Errorf("unsupported expression %q", stage)
+	}
+}
+
+// parsePath parses path steps such as .a.b[0], ["key"], [*], [1:3], ..name
+// and [?(@.x == 1)]
+func parsePath(path string) ([]filterStep, error) {
+	var steps []filterStep
+	for i := 0; i < len(path); {
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			i += 2
+			steps = append(steps, recursiveStep)
+			if i < len(path) && path[i] == '*' {
+				i++
+			} else if name, n := scanIdentifier(path[i:]); n > 0 {
+				steps = append(steps, fieldStep(name))
+				i += n
+			}
+		case path[i] == '.':
+			i++
+			if i == len(path) {
+				// "." on its own is the identity
+				continue
+			}
+			switch {
+			case path[i] == '*':
+				steps = append(steps, wildcardStep)
+				i++
+			case path[i] == '"':
+				name, n, err := scanQuoted(path[i:])
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, fieldStep(name))
+				i += n
+			case path[i] == '[':
+				// .[0] is the same as [0]
+			default:
+				name, n := scanIdentifier(path[i:])
+				if n == 0 {
+					return nil, fmt.Errorf("expected a field name at %q", path[i:])
+				}
+				steps = append(steps, fieldStep(name))
+				i += n
+			}
+		case path[i] == '[':
+			end := matchingBracket(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", path)
+			}
+			step, err := parseBracket(path[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			i = end + 1
+		case path[i] == '?':
+			// jq's optional marker; type mismatches never fail anyway
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", path[i:], path)
+		}
+	}
+	return steps, nil
+}
+
+// parseBracket parses the contents of [...]
+func parseBracket(inner string) (filterStep, error) {
+	inner = strings.TrimSpace(inner)
+	switch {
+	case inner == "" || inner == "*":
+		return wildcardStep, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		cond, err := parseCondition(inner[2 : len(inner)-1])
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) ([]interface{}, error) {
+			var matched []interface{}
+			for _, child := range children(v) {
+				if cond(child) {
+					matched = append(matched, child)
+				}
+			}
+			return matched, nil
+		}, nil
+	case strings.Contains(inner, ":") && !strings.ContainsAny(inner, `"'`):
+		return parseSlice(inner)
+	}
+
+	// A union of names or indexes
+	var steps []filterStep
+	for _, part := range splitTopLevel(inner, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" && (part[0] == '"' || part[0] == '\'') {
+			name, n, err := scanQuoted(part)
+			if err != nil || n != len(part) {
+				return nil, fmt.Errorf("invalid key %s", part)
+			}
+			steps = append(steps, fieldStep(name))
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", part)
+		}
+		steps = append(steps, indexStep(index))
+	}
+	if len(steps) == 1 {
+		return steps[0], nil
+	}
+	return func(v interface{}) ([]interface{}, error) {
+		var results []interface{}
+		for _, step := range steps {
+			r, _ := step(v)
+			results = append(results, r...)
+		}
+		return results, nil
+	}, nil
+}
+
+// parseSlice parses start:end with either bound optional
+func parseSlice(inner string) (filterStep, error) {
+	startText, endText, _ := strings.Cut(inner, ":")
+	bound := func(text string, def int, length int) (int, error) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return 0, fmt.Errorf("invalid slice %q", inner)
+		}
+		if n < 0 {
+			n += length
+		}
+		return min(max(n, 0), length), nil
+	}
+	if _, err := bound(startText, 0, 0); err != nil {
+		return nil, err
+	}
+	if _, err := bound(endText, 0, 0); err != nil {
+		return nil, err
+	}
+
+	return func(v interface{}) ([]interface{}, error) {
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		start, _ := bound(startText, 0, len(list))
+		end, _ := bound(endText, len(list), len(list))
+		if start >= end {
+			return nil, nil
+		}
+		return list[start:end], nil
+	}, nil
+}
+
+// condition tests a value inside select(...) or [?(...)]
+type condition func(v interface{}) bool
+
+// comparisonOps is ordered so two-character operators match first
+var comparisonOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseCondition parses comparisons like @.age > 30 or .name == "x",
+// combined with && and ||. A bare path tests for a truthy value.
+func parseCondition(text string) (condition, error) {
+	if parts := splitTopLevel(text, "||"); len(parts) > 1 {
+		conds, err := parseConditions(parts)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) bool {
+			for _, c := range conds {
+				if c(v) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	if parts := splitTopLevel(text, "&&"); len(parts) > 1 {
+		conds, err := parseConditions(parts)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) bool {
+			for _, c := range conds {
+				if !c(v) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	}
+
+	text = strings.TrimSpace(text)
+	for _, op := range comparisonOps {
+		idx := indexTopLevel(text, op)
+		if idx < 0 {
+			continue
+		}
+		steps, err := parseOperand(text[:idx])
+		if err != nil {
+			return nil, err
+		}
+		literal, err := parseLiteral(strings.TrimSpace(text[idx+len(op):]))
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) bool {
+			for _, left := range evaluate(steps, v) {
+				if compare(left, op, literal) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	steps, err := parseOperand(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(v interface{}) bool {
+		for _, result := range evaluate(steps, v) {
+			if result != nil && result != false {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func parseConditions(parts []string) ([]condition, error) {
+	conds := make([]condition, 0, len(parts))
+	for _, part := range parts {
+		cond, err := parseCondition(part)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+	}
+	return conds, nil
+}
+
+// parseOperand parses the path on the left of a comparison (@.a or .a)
+func parseOperand(text string) ([]filterStep, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "@")
+	if text != "" && text[0] != '.' && text[0] != '[' {
+		return nil, fmt.Errorf("expected a path in condition, got %q", text)
+	}
+	return parsePath(text)
+}
+
+// parseLiteral parses a JSON literal; single-quoted strings are accepted
+func parseLiteral(text string) (interface{}, error) {
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return text[1 : len(text)-1], nil
+	}
+	v, err := decodeOrdered([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q in condition", text)
+	}
+	return v, nil
+}
+
+// evaluate applies path steps to a single value
+func evaluate(steps []filterStep, v interface{}) []interface{} {
+	values := []interface{}{v}
+	for _, step := range steps {
+		var next []interface{}
+		for _, value := range values {
+			results, _ := step(value)
+			next = append(next, results...)
+		}
+		values = next
+	}
+	return values
+}
+
+// compare compares a value with a literal. Numbers and strings are ordered;
+// other values only support equality.
+func compare(left interface{}, op string, right interface{}) bool {
+	if l, ok := toFloat(left); ok {
+		if r, ok := toFloat(right); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+
+	// Objects and arrays never equal a literal
+	var equal bool
+	switch left.(type) {
+	case *orderedObject, []interface{}:
+	default:
+		switch right.(type) {
+		case *orderedObject, []interface{}:
+		default:
+			equal = left == right
+		}
+	}
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// fieldStep selects a key of an object
+func fieldStep(name string) filterStep {
+	return func(v interface{}) ([]interface{}, error) {
+		obj, ok := v.(*orderedObject)
+		if !ok {
+			return nil, nil
+		}
+		child, ok := obj.values[name]
+		if !ok {
+			return nil, nil
+		}
+		return []interface{}{child}, nil
+	}
+}
+
+// indexStep selects an array element; negative indexes count from the end
+func indexStep(index int) filterStep {
+	return func(v interface{}) ([]interface{}, error) {
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		i := index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil, nil
+		}
+		return []interface{}{list[i]}, nil
+	}
+}
+
+// wildcardStep selects every element of an array or value of an object
+func wildcardStep(v interface{}) ([]interface{}, error) {
+	return children(v), nil
+}
+
+// recursiveStep selects a value and all of its descendants
+func recursiveStep(v interface{}) ([]interface{}, error) {
+	results := []interface{}{v}
+	for _, child := range children(v) {
+		descendants, _ := recursiveStep(child)
+		results = append(results, descendants...)
+	}
+	return results, nil
+}
+
+// keysStep returns the keys of an object, or the indexes of an array
+func keysStep(v interface{}) ([]interface{}, error) {
+	switch value := v.(type) {
+	case *orderedObject:
+		keys := append([]string(nil), value.keys...)
+		sort.Strings(keys)
+		list := make([]interface{}, len(keys))
+		for i, key := range keys {
+			list[i] = key
+		}
+		return []interface{}{list}, nil
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i := range value {
+			list[i] = json.Number(strconv.Itoa(i))
+		}
+		return []interface{}{list}, nil
+	}
+	return nil, fmt.Errorf("keys: value has no keys")
+}
+
+// lengthStep returns the length of a string, array or object
+func lengthStep(v interface{}) ([]interface{}, error) {
+	var n int
+	switch value := v.(type) {
+	case string:
+		n = len([]rune(value))
+	case []interface{}:
+		n = len(value)
+	case *orderedObject:
+		n = len(value.keys)
+	case nil:
+		n = 0
+	default:
+		return nil, fmt.Errorf("length: value has no length")
+	}
+	return []interface{}{json.Number(strconv.Itoa(n))}, nil
+}
+
+// children returns array elements or object values in order
+func children(v interface{}) []interface{} {
+	switch value := v.(type) {
+	case []interface{}:
+		return value
+	case *orderedObject:
+		list := make([]interface{}, len(value.keys))
+		for i, key := range value.keys {
+			list[i] = value.values[key]
+		}
+		return list
+	}
+	return nil
+}
+
+// scanIdentifier reads a bare field name
+func scanIdentifier(s string) (string, int) {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c == '_' || c == '-' || c == '$' || c >= 0x80 ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			n++
+			continue
+		}
+		break
+	}
+	return s[:n], n
+}
+
+// scanQuoted reads a single- or double-quoted name and returns its length
+func scanQuoted(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if quote == '\'' {
+				return strings.ReplaceAll(s[1:i], `\'`, `'`), i + 1, nil
+			}
+			name, err := strconv.Unquote(s[:i+1])
+			return name, i + 1, err
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
+
+// matchingBracket returns the index of the ] closing the [ at open
+func matchingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			_, n, err := scanQuoted(s[i:])
+			if err != nil {
+				return -1
+			}
+			i += n - 1
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s on sep outside quotes, brackets and parentheses
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	for {
+		idx := indexTopLevel(s, sep)
+		if idx < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:idx])
+		s = s[idx+len(sep):]
+	}
+}
+
+// indexTopLevel finds sep outside quotes, brackets and parentheses. A
+// single "|" never matches inside "||".
+func indexTopLevel(s, sep string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			if _, n, err := scanQuoted(s[i:]); err == nil {
+				i += n - 1
+				continue
+			}
+		case '[', '(':
+			depth++
+			continue
+		case ']', ')':
+			depth--
+			continue
+		}
+		if depth == 0 && strings.HasPrefix(s[i:], sep) {
+			if sep == "|" && strings.HasPrefix(s[i:], "||") {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// orderedObject is a JSON object that remembers its key order
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON encodes the object with its keys in their original order
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		encoded, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrdered decodes JSON keeping object key order and number text
+func decodeOrdered(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+func decodeValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := &orderedObject{values: make(map[string]interface{})}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := keyToken.(string)
+				value, err := decodeValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if _, exists := obj.values[key]; !exists {
+					obj.keys = append(obj.keys, key)
+				}
+				obj.values[key] = value
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return obj, nil
+		case '[':
+			list := []interface{}{}
+			for decoder.More() {
+				value, err := decodeValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return list, nil
+		}
+		return nil, fmt.Errorf("unexpected %v", t)
+	default:
+		return t, nil
+	}
+}
@@ -0,0 +1,125 @@
+package output
+
+import (
+	"errors"
+	"testing"
+)
+
+const filterDoc = `{
+	"store": {"name": "corner", "open": true},
+	"items": [
+		{"id": 1, "name": "apple", "price": 1.5, "tags": ["fruit"]},
+		{"id": 2, "name": "bread", "price": 3, "tags": []},
+		{"id": 3, "name": "cheese", "price": 7.25, "tags": ["dairy", "aged"], "zone": null}
+	],
+	"empty": {}
+}`
+
+func TestFilterJSON(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		// JSONPath
+		{"$.store.name", "corner\n"},
+		{"$.items[*].id", "1\n2\n3\n"},
+		{"$.items[0]", `{"id":1,"name":"apple","price":1.5,"tags":["fruit"]}` + "\n"},
+		{"$.items[-1].name", "cheese\n"},
+		{"$.items[0,2].name", "apple\ncheese\n"},
+		{"$.items[1:].id", "2\n3\n"},
+		{"$['store']['open']", "true\n"},
+		{"$..tags[0]", "fruit\ndairy\n"},
+		{"$.items[?(@.price > 2)].name", "bread\ncheese\n"},
+		{"$.items[?(@.name == 'apple' || @.id >= 3)].id", "1\n3\n"},
+		{"$.items[?(@.tags[1])].id", "3\n"},
+		// jq
+		{".store.name", "corner\n"},
+		{".items[].name", "apple\nbread\ncheese\n"},
+		{".items[1]", `{"id":2,"name":"bread","price":3,"tags":[]}` + "\n"},
+		{`.["store"].open`, "true\n"},
+		{".items | length", "3\n"},
+		{".store | keys", `["name","open"]` + "\n"},
+		{`.items[] | select(.price < 5 && .id > 1) | .id`, "2\n"},
+		{`.items[] | select(.name != "bread") | .id`, "1\n3\n"},
+		{".items[2].zone", "null\n"},
+		{".empty", "{}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+
+			output, err := formatter.FilterJSON([]byte(filterDoc), filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("got %q, want %q", output, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterJSONNoMatch(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	for _, expr := range []string{"$.missing", ".store.missing", ".items[10]", "$.items[?(@.price > 100)]", "$.items[?(@.zone)]"} {
+		filter, err := ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", expr, err)
+		}
+		if _, err := formatter.FilterJSON([]byte(filterDoc), filter); !errors.Is(err, ErrNoMatch) {
+			t.Errorf("%s: expected ErrNoMatch, got %v", expr, err)
+		}
+	}
+}
+
+func TestFilterJSONIdentity(t *testing.T) {
+	formatter := NewFormatter(false)
+	filter, _ := ParseFilter(".")
+
+	output, err := formatter.FilterJSON([]byte(`{"b":1,"a":[1,2]}`), filter)
+	if err != nil || output != `{"b":1,"a":[1,2]}`+"\n" {
+		t.Errorf("expected document with key order kept, got %q, %v", output, err)
+	}
+}
+
+func TestFilterJSONNotJSON(t *testing.T) {
+	formatter := NewFormatter(false)
+	filter, _ := ParseFilter(".a")
+
+	_, err := formatter.FilterJSON([]byte("<html></html>"), filter)
+	if err == nil || errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected a non-JSON error, got %v", err)
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	for _, expr := range []string{"", "items", ".items[", "$.a[x]", ".a | ", "select(", "$.a[?(foo)]"} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("%q: expected parse error", expr)
+		}
+	}
+}
+
+func TestFilterResponseEnvelope(t *testing.T) {
+	formatter := NewFormatter(false)
+	resp := newRenderResponse("application/json", []byte(`{"id":1}`))
+	filter, _ := ParseFilter(".status")
+
+	output, err := formatter.FilterResponse(resp, FormatJSON, filter)
+	if err != nil || output != "201\n" {
+		t.Errorf("expected envelope status, got %q, %v", output, err)
+	}
+
+	filter, _ = ParseFilter("$.log.entries[0].request.method")
+	output, err = formatter.FilterResponse(resp, FormatHAR, filter)
+	if err != nil || output != "POST\n" {
+		t.Errorf("expected HAR request method, got %q, %v", output, err)
+	}
+}
@@ -1,21 +1,37 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/gosh/internal/request"
 )
 
 // Formatter handles response formatting
 type Formatter struct {
-	isTTY bool
+	isTTY  bool
+	theme  Theme
+	redact *Redactor // Hides credentials in HAR output; nil shows them
 }
 
 // NewFormatter creates a new formatter
 func NewFormatter(isTTY bool) *Formatter {
-	return &Formatter{isTTY: isTTY}
+	return &Formatter{isTTY: isTTY, theme: themes[DefaultThemeName]}
+}
+
+// SetTheme sets the colors used for JSON bodies on a TTY
+func (f *Formatter) SetTheme(theme Theme) {
+	f.theme = theme
+}
+
+// SetRedactor sets how credentials are hidden in HAR output, which is
+// often shared; nil leaves them visible
+func (f *Formatter) SetRedactor(redact *Redactor) {
+	f.redact = redact
 }
 
 // FormatResponse formats the response for display
@@ -27,6 +43,30 @@ func (f *Formatter) FormatResponse(resp *request.Response, showInfo bool) string
 	output.WriteString(fmt.Sprintf("%s\n", statusColor))
 
 	if showInfo {
+		// Attempts, when the request was retried
+		if len(resp.Attempts) > 1 {
+			output.WriteString("\nAttempts:\n")
+			for i, attempt := range resp.Attempts {
+				outcome := attempt.Error
+				if outcome == "" {
+					outcome = f.colorizeStatus(attempt.StatusCode)
+				}
+				output.WriteString(fmt.Sprintf("  %d. %s (%v)", i+1, outcome, attempt.Duration.Round(time.Millisecond)))
+				if attempt.Wait > 0 {
+					output.WriteString(fmt.Sprintf(", retried after %v", attempt.Wait.Round(time.Millisecond)))
+				}
+				output.WriteString("\n")
+			}
+		}
+
+		// Redirects followed on the way, one line per hop
+		if len(resp.Redirects) > 0 {
+			output.WriteString("\nRedirects:\n")
+			for _, hop := range resp.Redirects {
+				output.WriteString(fmt.Sprintf("  %s %s %s -> %s\n", f.colorizeStatus(hop.StatusCode), hop.Method, hop.URL, hop.Location))
+			}
+		}
+
 		// Headers
 		output.WriteString("\nHeaders:\n")
 		for key, values := range resp.Headers {
@@ -36,7 +76,12 @@ func (f *Formatter) FormatResponse(resp *request.Response, showInfo bool) string
 		}
 
 		// Timing and size info
-		output.WriteString(fmt.Sprintf("\nTiming: %v\n", resp.Duration))
+		if resp.Timing != nil {
+			output.WriteString(fmt.Sprintf("\nTiming: %v\n", roundDuration(resp.Timing.Total)))
+			output.WriteString(f.formatWaterfall(resp.Timing))
+		} else {
+			output.WriteString(fmt.Sprintf("\nTiming: %v\n", resp.Duration))
+		}
 		output.WriteString(fmt.Sprintf("Size: %d bytes\n", resp.Size))
 	}
 
@@ -50,36 +95,35 @@ func (f *Formatter) FormatResponse(resp *request.Response, showInfo bool) string
 	return output.String()
 }
 
-// formatBody attempts to pretty-print JSON, otherwise returns raw
+// formatBody pretty-prints the body with the formatter registered for its
+// content type, otherwise returns raw
 func (f *Formatter) formatBody(body []byte, headers map[string][]string) string {
-	// Check content type
-	contentType := f.getContentType(headers)
-
-	if strings.Contains(contentType, "application/json") {
-		return f.prettyPrintJSON(body)
+	formatter, ok := lookupBodyFormatter(mediaType(headers))
+	if !ok {
+		return string(body)
 	}
 
-	return string(body)
+	formatted, err := formatter(f, body)
+	if err != nil {
+		return string(body)
+	}
+	return formatted
 }
 
-// prettyPrintJSON attempts to pretty-print JSON
+// prettyPrintJSON attempts to pretty-print JSON, keeping the original key
+// order and number formatting
 func (f *Formatter) prettyPrintJSON(body []byte) string {
-	var obj interface{}
-	if err := json.Unmarshal(body, &obj); err != nil {
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, bytes.TrimSpace(body), "", "  "); err != nil {
 		// Not valid JSON, return as-is
 		return string(body)
 	}
 
-	pretty, err := json.MarshalIndent(obj, "", "  ")
-	if err != nil {
-		return string(body)
-	}
-
 	if f.isTTY {
-		return f.colorizeJSON(string(pretty))
+		return f.colorizeJSON(pretty.String())
 	}
 
-	return string(pretty)
+	return pretty.String()
 }
 
 // colorizeStatus returns colored status text if TTY, else plain
@@ -101,17 +145,37 @@ func (f *Formatter) colorizeStatus(statusCode int) string {
 	}
 }
 
-// colorizeJSON adds basic syntax coloring to JSON
+// colorizeJSON adds syntax coloring to JSON using the formatter's theme
 func (f *Formatter) colorizeJSON(jsonStr string) string {
-	// Simple approach: color keys and values differently
-	// For production, consider a JSON-specific coloring library
-	return jsonStr
+	if !f.isTTY {
+		return jsonStr
+	}
+	return colorizeJSONTokens(jsonStr, f.theme)
+}
+
+// color wraps text in an escape code on a TTY
+func (f *Formatter) color(code, text string) string {
+	if !f.isTTY {
+		return text
+	}
+	return paint(code, text)
+}
+
+// StreamBody copies a response body to w as chunks arrive
+func (f *Formatter) StreamBody(w io.Writer, body io.Reader) error {
+	_, err := io.Copy(w, body)
+	return err
 }
 
 // getContentType extracts content type from headers
 func (f *Formatter) getContentType(headers map[string][]string) string {
+	return getHeader(headers, "Content-Type")
+}
+
+// getHeader returns the first value of a header, matched case-insensitively
+func getHeader(headers map[string][]string, name string) string {
 	for key, values := range headers {
-		if strings.EqualFold(key, "content-type") && len(values) > 0 {
+		if strings.EqualFold(key, name) && len(values) > 0 {
 			return values[0]
 		}
 	}
@@ -76,6 +76,54 @@ func TestFormatResponseWithInfo(t *testing.T) {
 	}
 }
 
+// TestFormatResponseRedirects tests that --info lists each redirect hop
+func TestFormatResponseRedirects(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	resp := &request.Response{
+		StatusCode: 200,
+		Redirects: []request.Redirect{
+			{Method: "POST", URL: "http://a.test/old", StatusCode: 308, Location: "/new"},
+			{Method: "POST", URL: "http://a.test/new", StatusCode: 303, Location: "http://b.test/done"},
+		},
+	}
+
+	output := formatter.FormatResponse(resp, true)
+	expected := "Redirects:\n  308 POST http://a.test/old -> /new\n  303 POST http://a.test/new -> http://b.test/done\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("expected redirect chain %q, got: %s", expected, output)
+	}
+
+	if strings.Contains(formatter.FormatResponse(resp, false), "Redirects:") {
+		t.Error("expected no redirect chain without info")
+	}
+}
+
+// TestFormatResponseAttempts tests that --info lists retried attempts
+func TestFormatResponseAttempts(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	resp := &request.Response{
+		StatusCode: 200,
+		Attempts: []request.Attempt{
+			{StatusCode: 503, Duration: 120 * time.Millisecond, Wait: time.Second},
+			{Error: "connection refused", Duration: 2 * time.Millisecond, Wait: 2 * time.Second},
+			{StatusCode: 200, Duration: 80 * time.Millisecond},
+		},
+	}
+
+	output := formatter.FormatResponse(resp, true)
+	expected := "Attempts:\n  1. 503 (120ms), retried after 1s\n  2. connection refused (2ms), retried after 2s\n  3. 200 (80ms)\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("expected attempts %q, got: %s", expected, output)
+	}
+
+	resp.Attempts = resp.Attempts[2:]
+	if strings.Contains(formatter.FormatResponse(resp, true), "Attempts:") {
+		t.Error("expected no attempts section for a single attempt")
+	}
+}
+
 // TestFormatResponsePrettyPrintJSON tests JSON pretty-printing
 func TestFormatResponsePrettyPrintJSON(t *testing.T) {
 	formatter := NewFormatter(false)
@@ -0,0 +1,177 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markupIndent is the indentation used for each nesting level
+const markupIndent = "  "
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// htmlVoidElements never have content or an end tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// formatXML indents XML bodies, keeping namespace prefixes as written
+func formatXML(f *Formatter, body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return f.formatMarkup(decoder.RawToken, false)
+}
+
+// formatHTML indents HTML bodies, tolerating unclosed and void elements
+func formatHTML(f *Formatter, body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return f.formatMarkup(decoder.Token, true)
+}
+
+// formatMarkup renders XML tokens one element per line. Elements holding
+// only a single line of text stay on one line.
+func (f *Formatter) formatMarkup(next func() (xml.Token, error), html bool) (string, error) {
+	var tokens []xml.Token
+	var open []xml.Name
+	for {
+		token, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// Raw tokens aren't checked for matching end tags
+		switch t := token.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return "", fmt.Errorf("unexpected end element </%s>", markupName(t.Name, html))
+			}
+			open = open[:len(open)-1]
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+	if len(open) > 0 {
+		return "", fmt.Errorf("unclosed element <%s>", markupName(open[len(open)-1], html))
+	}
+
+	var out strings.Builder
+	depth := 0
+	writeLine := func(line string) {
+		out.WriteString(strings.Repeat(markupIndent, depth))
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch t := tokens[i].(type) {
+		case xml.StartElement:
+			name := markupName(t.Name, html)
+
+			// Empty element
+			if i+1 < len(tokens) {
+				if _, ok := tokens[i+1].(xml.EndElement); ok {
+					switch {
+					case html && htmlVoidElements[strings.ToLower(name)]:
+						writeLine(f.startTag(t, html, false))
+					case html:
+						writeLine(f.startTag(t, html, false) + f.endTag(name))
+					default:
+						writeLine(f.startTag(t, html, true))
+					}
+					i++
+					continue
+				}
+			}
+
+			// Element with a single line of text
+			if i+2 < len(tokens) {
+				text, isText := tokens[i+1].(xml.CharData)
+				_, isEnd := tokens[i+2].(xml.EndElement)
+				trimmed := strings.TrimSpace(string(text))
+				if isText && isEnd && !strings.Contains(trimmed, "\n") {
+					writeLine(f.startTag(t, html, false) + textEscaper.Replace(trimmed) + f.endTag(name))
+					i += 2
+					continue
+				}
+			}
+
+			writeLine(f.startTag(t, html, false))
+			depth++
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+			}
+			writeLine(f.endTag(markupName(t.Name, html)))
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text != "" {
+				writeLine(textEscaper.Replace(text))
+			}
+		case xml.Comment:
+			writeLine(f.color(f.theme.Comment, "<!--"+string(t)+"-->"))
+		case xml.ProcInst:
+			inst := "<?" + t.Target
+			if len(t.Inst) > 0 {
+				inst += " " + string(t.Inst)
+			}
+			writeLine(f.color(f.theme.Punctuation, inst+"?>"))
+		case xml.Directive:
+			writeLine(f.color(f.theme.Punctuation, "<!"+string(t)+">"))
+		}
+	}
+
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// startTag renders an opening tag with its attributes
+func (f *Formatter) startTag(t xml.StartElement, html, selfClosing bool) string {
+	var b strings.Builder
+	b.WriteString(f.color(f.theme.Punctuation, "<"))
+	b.WriteString(f.color(f.theme.Tag, markupName(t.Name, html)))
+	for _, attr := range t.Attr {
+		b.WriteString(" ")
+		b.WriteString(f.color(f.theme.Attribute, markupName(attr.Name, html)))
+		b.WriteString(f.color(f.theme.Punctuation, "="))
+		b.WriteString(f.color(f.theme.String, `"`+attrEscaper.Replace(attr.Value)+`"`))
+	}
+	if selfClosing {
+		b.WriteString(f.color(f.theme.Punctuation, "/>"))
+	} else {
+		b.WriteString(f.color(f.theme.Punctuation, ">"))
+	}
+	return b.String()
+}
+
+// endTag renders a closing tag
+func (f *Formatter) endTag(name string) string {
+	return f.color(f.theme.Punctuation, "</") + f.color(f.theme.Tag, name) + f.color(f.theme.Punctuation, ">")
+}
+
+// markupName returns a name as written. Raw XML tokens keep the prefix in
+// Space; HTML tokens are namespace-resolved, so only xmlns prefixes are kept.
+func markupName(name xml.Name, html bool) string {
+	if name.Space == "" || (html && name.Space != "xmlns") {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
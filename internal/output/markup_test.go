@@ -0,0 +1,84 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatXML(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := `<?xml version="1.0"?><soap:Envelope xmlns:soap="http://example.com/soap"><soap:Body>` +
+		`<m:Item id="1 &amp; 2"><m:Name>Widget</m:Name><m:Empty></m:Empty></m:Item><!-- note --></soap:Body></soap:Envelope>`
+
+	output, err := formatXML(formatter, []byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://example.com/soap">
+  <soap:Body>
+    <m:Item id="1 &amp; 2">
+      <m:Name>Widget</m:Name>
+      <m:Empty/>
+    </m:Item>
+    <!-- note -->
+  </soap:Body>
+</soap:Envelope>`
+	if output != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestFormatXMLInvalid(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := []byte(`<a><b></a>`)
+
+	output := formatter.formatBody(body, map[string][]string{"Content-Type": {"application/xml"}})
+	if output != string(body) {
+		t.Errorf("expected raw body for invalid XML, got %q", output)
+	}
+}
+
+func TestFormatHTML(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Hi</title></head>` +
+		`<body><p>Hello<br>world</p><div></div></body></html>`
+
+	output, err := formatHTML(formatter, []byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Hi</title>
+  </head>
+  <body>
+    <p>
+      Hello
+      <br>
+      world
+    </p>
+    <div></div>
+  </body>
+</html>`
+	if output != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestFormatXMLColors(t *testing.T) {
+	formatter := NewFormatter(true)
+	formatter.SetTheme(Theme{Tag: "<t>", Attribute: "<a>"})
+
+	output, err := formatXML(formatter, []byte(`<item id="1"/>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "<t>item"+ansiReset) || !strings.Contains(output, "<a>id"+ansiReset) {
+		t.Errorf("expected colored tag and attribute, got %q", output)
+	}
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"strings"
+	"sync"
+)
+
+// BodyFormatter pretty-prints a response body. Returning an error makes
+// the formatter fall back to the raw body.
+type BodyFormatter func(f *Formatter, body []byte) (string, error)
+
+var (
+	bodyFormattersMu sync.RWMutex
+	// bodyFormatters maps media types, or structured syntax suffixes such
+	// as "+json", to the formatter used for them
+	bodyFormatters = map[string]BodyFormatter{}
+)
+
+func init() {
+	RegisterBodyFormatter(formatJSON, "application/json", "+json")
+	RegisterBodyFormatter(formatXML, "application/xml", "text/xml", "+xml")
+	RegisterBodyFormatter(formatHTML, "text/html", "application/xhtml+xml")
+	RegisterBodyFormatter(formatYAML, "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml", "+yaml")
+	RegisterBodyFormatter(formatForm, "application/x-www-form-urlencoded")
+	RegisterBodyFormatter(formatCSV, "text/csv")
+}
+
+// RegisterBodyFormatter registers a formatter for the given media types.
+// A type starting with "+" matches structured syntax suffixes, so "+xml"
+// covers application/soap+xml and application/atom+xml.
+func RegisterBodyFormatter(formatter BodyFormatter, mediaTypes ...string) {
+	bodyFormattersMu.Lock()
+	defer bodyFormattersMu.Unlock()
+
+	for _, mediaType := range mediaTypes {
+		bodyFormatters[strings.ToLower(mediaType)] = formatter
+	}
+}
+
+// lookupBodyFormatter finds the formatter for a media type, trying an exact
+// match before the structured syntax suffix
+func lookupBodyFormatter(mediaType string) (BodyFormatter, bool) {
+	bodyFormattersMu.RLock()
+	defer bodyFormattersMu.RUnlock()
+
+	mediaType = strings.ToLower(mediaType)
+	if formatter, ok := bodyFormatters[mediaType]; ok {
+		return formatter, true
+	}
+	if idx := strings.LastIndex(mediaType, "+"); idx >= 0 {
+		formatter, ok := bodyFormatters[mediaType[idx:]]
+		return formatter, ok
+	}
+	return nil, false
+}
+
+// formatJSON pretty-prints JSON bodies
+func formatJSON(f *Formatter, body []byte) (string, error) {
+	return f.prettyPrintJSON(body), nil
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLookupBodyFormatter(t *testing.T) {
+	tests := map[string]bool{
+		"application/json":          true,
+		"application/problem+json":  true,
+		"application/soap+xml":      true,
+		"text/xml":                  true,
+		"text/html":                 true,
+		"application/x-yaml":        true,
+		"application/vnd.api+yaml":  true,
+		"text/csv":                  true,
+		"TEXT/CSV":                  true,
+		"text/plain":                false,
+		"application/octet-stream":  false,
+		"application/vnd.unknown+x": false,
+	}
+	for mediaType, expected := range tests {
+		if _, ok := lookupBodyFormatter(mediaType); ok != expected {
+			t.Errorf("lookupBodyFormatter(%q): got %v, want %v", mediaType, ok, expected)
+		}
+	}
+}
+
+func TestRegisterBodyFormatter(t *testing.T) {
+	RegisterBodyFormatter(func(f *Formatter, body []byte) (string, error) {
+		return strings.ToUpper(string(body)), nil
+	}, "application/x-test-upper")
+	RegisterBodyFormatter(func(f *Formatter, body []byte) (string, error) {
+		return "", errors.New("cannot format")
+	}, "application/x-test-broken")
+	defer func() {
+		delete(bodyFormatters, "application/x-test-upper")
+		delete(bodyFormatters, "application/x-test-broken")
+	}()
+
+	formatter := NewFormatter(false)
+
+	output := formatter.formatBody([]byte("hello"), map[string][]string{"Content-Type": {"application/x-test-upper; charset=utf-8"}})
+	if output != "HELLO" {
+		t.Errorf("expected registered formatter to be used, got %q", output)
+	}
+
+	output = formatter.formatBody([]byte("raw"), map[string][]string{"Content-Type": {"application/x-test-broken"}})
+	if output != "raw" {
+		t.Errorf("expected raw body when formatting fails, got %q", output)
+	}
+}
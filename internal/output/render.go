@@ -0,0 +1,486 @@
+package output
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gosh/internal/request"
+	version "github.com/gosh/pkg"
+)
+
+// Output formats selected with --format
+const (
+	FormatText    = "text"    // Status line and pretty-printed body (the default)
+	FormatRaw     = "raw"     // Body bytes exactly as received
+	FormatBody    = "body"    // Pretty-printed body only
+	FormatHeaders = "headers" // Status line and headers only
+	FormatJSON    = "json"    // JSON envelope with status, headers, body and timing
+	FormatHAR     = "har"     // HAR 1.2 log with a single entry
+)
+
+// Formats lists the valid --format values
+var Formats = []string{FormatText, FormatRaw, FormatBody, FormatHeaders, FormatJSON, FormatHAR}
+
+// IsValidFormat reports whether name is a known output format.
+// An empty name selects the default text format.
+func IsValidFormat(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, format := range Formats {
+		if name == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Render formats the response in the given output format. showInfo only
+// applies to the default text format.
+func (f *Formatter) Render(resp *request.Response, format string, showInfo bool) (string, error) {
+	switch format {
+	case "", FormatText:
+		return f.FormatResponse(resp, showInfo), nil
+	case FormatRaw:
+		return string(resp.Body), nil
+	case FormatBody:
+		if len(resp.Body) == 0 {
+			return "", nil
+		}
+		return ensureNewline(f.formatBody(resp.Body, resp.Headers)), nil
+	case FormatHeaders:
+		return f.FormatHeaders(resp), nil
+	case FormatJSON:
+		return f.marshal(newEnvelope(resp))
+	case FormatHAR:
+		return f.marshal(newHAR(resp, f.redact))
+	default:
+		return "", fmt.Errorf("unknown format: %s (expected one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// FormatHeaders formats the status line and response headers, sorted by name
+func (f *Formatter) FormatHeaders(resp *request.Response) string {
+	var output strings.Builder
+
+	proto := resp.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	output.WriteString(fmt.Sprintf("%s %s\n", proto, f.colorizeStatusLine(resp)))
+
+	for _, name := range sortedHeaderNames(resp.Headers) {
+		for _, val := range resp.Headers[name] {
+			output.WriteString(fmt.Sprintf("%s: %s\n", f.color(f.theme.Key, name), val))
+		}
+	}
+	return output.String()
+}
+
+// colorizeStatusLine returns "200 OK" with the code colored on a TTY
+func (f *Formatter) colorizeStatusLine(resp *request.Response) string {
+	text := strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode)))
+	if text == "" {
+		text = http.StatusText(resp.StatusCode)
+	}
+	return strings.TrimSpace(f.colorizeStatus(resp.StatusCode) + " " + text)
+}
+
+// marshal encodes v as indented JSON without HTML escaping, colored on a TTY
+func (f *Formatter) marshal(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return f.colorizeJSON(buf.String()), nil
+}
+
+// envelope is the --format json representation of a response
+type envelope struct {
+	Status       int                 `json:"status"`
+	StatusText   string              `json:"statusText"`
+	URL          string              `json:"url,omitempty"`
+	Headers      map[string][]string `json:"headers"`
+	Body         json.RawMessage     `json:"body"`
+	BodyEncoding string              `json:"bodyEncoding,omitempty"`
+	Size         int                 `json:"size"`
+	Timing       envelopeTiming      `json:"timing"`
+	Connection   *envelopeConnection `json:"connection,omitempty"`
+	Redirects    []envelopeRedirect  `json:"redirects,omitempty"`
+	Attempts     []envelopeAttempt   `json:"attempts,omitempty"`
+}
+
+// envelopeAttempt is one try at sending a retried request, with durations
+// in milliseconds
+type envelopeAttempt struct {
+	Status int     `json:"status,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Time   float64 `json:"time"`
+	Wait   float64 `json:"wait,omitempty"`
+}
+
+// envelopeRedirect is one redirect that was followed
+type envelopeRedirect struct {
+	Status   int    `json:"status"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Location string `json:"location"`
+}
+
+// envelopeTiming holds durations in milliseconds. The phases are only
+// present when they were traced.
+type envelopeTiming struct {
+	Total float64 `json:"total"`
+	*envelopePhases
+}
+
+// envelopePhases breaks the total down, see request.Timing
+type envelopePhases struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	Send     float64 `json:"send"`
+	TTFB     float64 `json:"ttfb"`
+	Transfer float64 `json:"transfer"`
+}
+
+// envelopeConnection describes the connection the response came over
+type envelopeConnection struct {
+	RemoteAddr string `json:"remoteAddr"`
+	Reused     bool   `json:"reused"`
+}
+
+// newEnvelope builds the JSON envelope. JSON bodies are embedded as-is,
+// other text as a string and binary data base64-encoded.
+func newEnvelope(resp *request.Response) *envelope {
+	env := &envelope{
+		Status:     resp.StatusCode,
+		StatusText: http.StatusText(resp.StatusCode),
+		Headers:    resp.Headers,
+		Size:       resp.Size,
+		Timing:     envelopeTiming{Total: milliseconds(resp.Duration)},
+	}
+	if env.Headers == nil {
+		env.Headers = map[string][]string{}
+	}
+	if resp.Request != nil {
+		env.URL = resp.Request.URL
+	}
+	if t := resp.Timing; t != nil {
+		env.Timing = envelopeTiming{
+			Total: milliseconds(t.Total),
+			envelopePhases: &envelopePhases{
+				DNS:      milliseconds(t.DNS),
+				Connect:  milliseconds(t.Connect),
+				TLS:      milliseconds(t.TLS),
+				Send:     milliseconds(t.Send),
+				TTFB:     milliseconds(t.TTFB),
+				Transfer: milliseconds(t.Transfer),
+			},
+		}
+		if t.RemoteAddr != "" {
+			env.Connection = &envelopeConnection{RemoteAddr: t.RemoteAddr, Reused: t.Reused}
+		}
+	}
+	for _, hop := range resp.Redirects {
+		env.Redirects = append(env.Redirects, envelopeRedirect{
+			Status:   hop.StatusCode,
+			Method:   hop.Method,
+			URL:      hop.URL,
+			Location: hop.Location,
+		})
+	}
+
+	if len(resp.Attempts) > 1 {
+		for _, attempt := range resp.Attempts {
+			env.Attempts = append(env.Attempts, envelopeAttempt{
+				Status: attempt.StatusCode,
+				Error:  attempt.Error,
+				Time:   milliseconds(attempt.Duration),
+				Wait:   milliseconds(attempt.Wait),
+			})
+		}
+	}
+
+	body, encoding := encodeBody(resp.Body, resp.Headers)
+	env.Body = body
+	env.BodyEncoding = encoding
+	return env
+}
+
+// encodeBody returns the body as a JSON value and the encoding used, if any
+func encodeBody(body []byte, headers map[string][]string) (json.RawMessage, string) {
+	switch {
+	case len(body) == 0:
+		return json.RawMessage("null"), ""
+	case isJSONMediaType(mediaType(headers)) && json.Valid(body):
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err == nil {
+			return compact.Bytes(), ""
+		}
+	case !utf8.Valid(body):
+		return marshalString(base64.StdEncoding.EncodeToString(body)), "base64"
+	}
+	return marshalString(string(body)), ""
+}
+
+// marshalString encodes s as a JSON string without HTML escaping
+func marshalString(s string) json.RawMessage {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// isJSONMediaType reports whether a media type carries JSON
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// har is a HAR 1.2 document
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings uses -1 for phases that didn't happen, as HAR specifies
+type harTimings struct {
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // Includes ssl
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// newHAR builds a HAR log holding the request and response, with
+// credentials in headers, cookies, the URL and the request body hidden by
+// redact unless it is nil
+func newHAR(resp *request.Response, redact *Redactor) *har {
+	sent := resp.Request
+	if sent == nil {
+		sent = &request.SentRequest{}
+	}
+
+	entry := harEntry{
+		StartedDateTime: resp.StartedAt.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            milliseconds(resp.Duration),
+		Request: harRequest{
+			Method:      sent.Method,
+			URL:         redact.String(sent.URL),
+			HTTPVersion: protoOrDefault(sent.Proto),
+			Cookies:     harCookies((&http.Request{Header: sent.Headers}).Cookies(), redact),
+			Headers:     harHeaders(sent.Headers, redact),
+			QueryString: harQuery(sent.URL, redact),
+			HeadersSize: -1,
+			BodySize:    len(sent.Body),
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: protoOrDefault(resp.Proto),
+			Cookies:     harCookies((&http.Response{Header: resp.Headers}).Cookies(), redact),
+			Headers:     harHeaders(resp.Headers, redact),
+			Content: harContent{
+				Size:     len(resp.Body),
+				MimeType: getHeader(resp.Headers, "Content-Type"),
+			},
+			RedirectURL: getHeader(resp.Headers, "Location"),
+			HeadersSize: -1,
+			BodySize:    len(resp.Body),
+		},
+		Timings: harTimings{DNS: -1, Connect: -1, SSL: -1, Wait: milliseconds(resp.Duration)},
+	}
+	if t := resp.Timing; t != nil {
+		entry.Time = milliseconds(t.Total)
+		entry.Timings = harTimings{
+			DNS:     harPhase(t.DNS),
+			Connect: harPhase(t.Connect + t.TLS),
+			SSL:     harPhase(t.TLS),
+			Send:    milliseconds(t.Send),
+			Wait:    milliseconds(t.TTFB),
+			Receive: milliseconds(t.Transfer),
+		}
+		if host, _, err := net.SplitHostPort(t.RemoteAddr); err == nil {
+			entry.ServerIPAddress = host
+		}
+	}
+
+	if len(sent.Body) > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: getHeader(sent.Headers, "Content-Type"),
+			Text:     redact.String(string(sent.Body)),
+		}
+	}
+	if utf8.Valid(resp.Body) {
+		entry.Response.Content.Text = string(resp.Body)
+	} else {
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString(resp.Body)
+		entry.Response.Content.Encoding = "base64"
+	}
+
+	return &har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "gosh", Version: version.Version},
+		Entries: []harEntry{entry},
+	}}
+}
+
+// harPhase returns a phase in milliseconds, or -1 when it didn't happen
+func harPhase(d time.Duration) float64 {
+	if d <= 0 {
+		return -1
+	}
+	return milliseconds(d)
+}
+
+// harHeaders lists headers sorted by name
+func harHeaders(headers map[string][]string, redact *Redactor) []harNameValue {
+	list := []harNameValue{}
+	for _, name := range sortedHeaderNames(headers) {
+		for _, val := range headers[name] {
+			list = append(list, harNameValue{Name: name, Value: redact.Header(name, val)})
+		}
+	}
+	return list
+}
+
+// harQuery lists the query parameters of a URL
+func harQuery(rawURL string, redact *Redactor) []harNameValue {
+	list := []harNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return list
+	}
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, val := range query[name] {
+			list = append(list, harNameValue{Name: name, Value: redact.String(val)})
+		}
+	}
+	return list
+}
+
+// harCookies converts cookies to name/value pairs, hiding the values when
+// redacting
+func harCookies(cookies []*http.Cookie, redact *Redactor) []harNameValue {
+	list := []harNameValue{}
+	for _, cookie := range cookies {
+		value := cookie.Value
+		if redact != nil {
+			value = redacted
+		}
+		list = append(list, harNameValue{Name: cookie.Name, Value: value})
+	}
+	return list
+}
+
+// sortedHeaderNames returns header names in sorted order
+func sortedHeaderNames(headers map[string][]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// protoOrDefault returns proto, or HTTP/1.1 when unknown
+func protoOrDefault(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// ensureNewline terminates text with a newline
+func ensureNewline(text string) string {
+	if strings.HasSuffix(text, "\n") {
+		return text
+	}
+	return text + "\n"
+}
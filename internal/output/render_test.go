@@ -0,0 +1,220 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosh/internal/auth"
+	"github.com/gosh/internal/request"
+)
+
+// newRenderResponse returns a response with a recorded request
+func newRenderResponse(contentType string, body []byte) *request.Response {
+	return &request.Response{
+		StatusCode: 201,
+		Status:     "201 Created",
+		Proto:      "HTTP/1.1",
+		Headers: map[string][]string{
+			"Content-Type": {contentType},
+			"Set-Cookie":   {"session=abc; Path=/"},
+		},
+		Body:      body,
+		Duration:  1500 * time.Microsecond,
+		Size:      len(body),
+		StartedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Request: &request.SentRequest{
+			Method:  "POST",
+			URL:     "https://api.example.com/users?page=2&q=a",
+			Proto:   "HTTP/1.1",
+			Headers: map[string][]string{"Content-Type": {"application/json"}, "Cookie": {"theme=dark"}},
+			Body:    []byte(`{"name":"John"}`),
+		},
+	}
+}
+
+func TestRenderRawAndBody(t *testing.T) {
+	formatter := NewFormatter(false)
+	resp := newRenderResponse("application/json", []byte(`{"id":1}`))
+
+	raw, err := formatter.Render(resp, FormatRaw, true)
+	if err != nil || raw != `{"id":1}` {
+		t.Errorf("raw: got %q, %v", raw, err)
+	}
+
+	body, err := formatter.Render(resp, FormatBody, false)
+	if err != nil || body != "{\n  \"id\": 1\n}\n" {
+		t.Errorf("body: got %q, %v", body, err)
+	}
+}
+
+func TestRenderHeaders(t *testing.T) {
+	formatter := NewFormatter(false)
+	resp := newRenderResponse("text/plain", []byte("hello"))
+
+	output, err := formatter.Render(resp, FormatHeaders, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "HTTP/1.1 201 Created\nContent-Type: text/plain\nSet-Cookie: session=abc; Path=/\n"
+	if output != expected {
+		t.Errorf("got %q, want %q", output, expected)
+	}
+}
+
+func TestRenderJSONEnvelope(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         []byte
+		expectedBody string
+		encoding     string
+	}{
+		{"JSON body", "application/json", []byte(`{ "id": 1 }`), `{"id":1}`, ""},
+		{"text body", "text/plain", []byte("hi <there>"), `"hi <there>"`, ""},
+		{"binary body", "application/octet-stream", []byte{0xff, 0x00}, `"/wA="`, "base64"},
+		{"empty body", "text/plain", nil, `null`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := formatter.Render(newRenderResponse(tt.contentType, tt.body), FormatJSON, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var env struct {
+				Status       int                 `json:"status"`
+				StatusText   string              `json:"statusText"`
+				URL          string              `json:"url"`
+				Headers      map[string][]string `json:"headers"`
+				Body         json.RawMessage     `json:"body"`
+				BodyEncoding string              `json:"bodyEncoding"`
+				Timing       map[string]float64  `json:"timing"`
+			}
+			if err := json.Unmarshal([]byte(output), &env); err != nil {
+				t.Fatalf("output is not valid JSON: %v\n%s", err, output)
+			}
+
+			if env.Status != 201 || env.StatusText != "Created" {
+				t.Errorf("status: got %d %q", env.Status, env.StatusText)
+			}
+			if env.Headers["Content-Type"][0] != tt.contentType {
+				t.Errorf("headers: got %v", env.Headers)
+			}
+			var body bytes.Buffer
+			_ = json.Compact(&body, env.Body)
+			if body.String() != tt.expectedBody {
+				t.Errorf("body: got %s, want %s", env.Body, tt.expectedBody)
+			}
+			if env.BodyEncoding != tt.encoding {
+				t.Errorf("bodyEncoding: got %q, want %q", env.BodyEncoding, tt.encoding)
+			}
+			if env.Timing["total"] != 1.5 {
+				t.Errorf("timing.total: got %v, want 1.5", env.Timing["total"])
+			}
+		})
+	}
+}
+
+func TestRenderHAR(t *testing.T) {
+	formatter := NewFormatter(false)
+	output, err := formatter.Render(newRenderResponse("application/json", []byte(`{"id":1}`)), FormatHAR, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc har
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	if doc.Log.Version != "1.2" || doc.Log.Creator.Name != "gosh" || len(doc.Log.Entries) != 1 {
+		t.Fatalf("unexpected log: %+v", doc.Log)
+	}
+
+	entry := doc.Log.Entries[0]
+	if entry.StartedDateTime != "2026-03-01T12:00:00.000Z" {
+		t.Errorf("startedDateTime: got %q", entry.StartedDateTime)
+	}
+	if entry.Request.Method != "POST" || len(entry.Request.QueryString) != 2 || entry.Request.QueryString[0].Name != "page" {
+		t.Errorf("request: got %+v", entry.Request)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != `{"name":"John"}` {
+		t.Errorf("postData: got %+v", entry.Request.PostData)
+	}
+	if len(entry.Request.Cookies) != 1 || entry.Request.Cookies[0].Value != "dark" {
+		t.Errorf("request cookies: got %+v", entry.Request.Cookies)
+	}
+	if entry.Response.Status != 201 || entry.Response.Content.Text != `{"id":1}` || entry.Response.Content.MimeType != "application/json" {
+		t.Errorf("response: got %+v", entry.Response)
+	}
+	if len(entry.Response.Cookies) != 1 || entry.Response.Cookies[0].Name != "session" {
+		t.Errorf("response cookies: got %+v", entry.Response.Cookies)
+	}
+}
+
+func TestRenderHARRedacted(t *testing.T) {
+	preset := &auth.AuthPreset{Type: "bearer", Token: "tok-secret"}
+	resp := newRenderResponse("application/json", []byte(`{"id":1}`))
+	resp.Request.URL = "https://api.example.com/users?token=tok-secret"
+	resp.Request.Headers["Authorization"] = []string{"Bearer tok-secret"}
+
+	formatter := NewFormatter(false)
+	formatter.SetRedactor(NewRedactor(preset))
+	output, err := formatter.Render(resp, FormatHAR, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(output, "tok-secret") || strings.Contains(output, "abc") || strings.Contains(output, "dark") {
+		t.Errorf("expected credentials to be hidden:\n%s", output)
+	}
+
+	var doc har
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	request := doc.Log.Entries[0].Request
+	var authorization string
+	for _, header := range request.Headers {
+		if header.Name == "Authorization" {
+			authorization = header.Value
+		}
+	}
+	if authorization != "Bearer [REDACTED]" {
+		t.Errorf("expected Bearer [REDACTED], got %q", authorization)
+	}
+	if request.URL != "https://api.example.com/users?token=[REDACTED]" || request.Cookies[0].Value != "[REDACTED]" {
+		t.Errorf("unexpected request %+v", request)
+	}
+
+	// Without a redactor, as with --show-secrets, everything is kept
+	formatter.SetRedactor(nil)
+	if output, _ := formatter.Render(resp, FormatHAR, false); !strings.Contains(output, "Bearer tok-secret") {
+		t.Errorf("expected the credentials with no redactor:\n%s", output)
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	formatter := NewFormatter(false)
+	_, err := formatter.Render(newRenderResponse("text/plain", nil), "xml", false)
+	if err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+}
+
+func TestIsValidFormat(t *testing.T) {
+	for _, name := range append(Formats, "") {
+		if !IsValidFormat(name) {
+			t.Errorf("expected %q to be valid", name)
+		}
+	}
+	if IsValidFormat("yaml") {
+		t.Error("expected yaml to be invalid")
+	}
+}
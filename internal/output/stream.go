@@ -0,0 +1,111 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gosh/internal/request"
+)
+
+// EventSource yields server-sent events, such as a request.EventReader or
+// a reconnecting request.EventStream
+type EventSource interface {
+	Next() (*request.Event, error)
+}
+
+// IsEventStream reports whether the response is a text/event-stream
+func IsEventStream(headers map[string][]string) bool {
+	return mediaType(headers) == request.ContentTypeEventStream
+}
+
+// IsNDJSON reports whether the response is newline-delimited JSON
+func IsNDJSON(headers map[string][]string) bool {
+	switch mediaType(headers) {
+	case request.ContentTypeNDJSON, "application/jsonl", "application/json-seq":
+		return true
+	}
+	return false
+}
+
+// StreamEvents writes each server-sent event to w as soon as it arrives
+func (f *Formatter) StreamEvents(w io.Writer, events EventSource) error {
+	for {
+		event, err := events.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, f.FormatEvent(event)); err != nil {
+			return err
+		}
+	}
+}
+
+// FormatEvent formats a server-sent event, pretty-printing JSON data
+func (f *Formatter) FormatEvent(event *request.Event) string {
+	var output strings.Builder
+
+	if event.ID != "" {
+		output.WriteString(f.eventField("id", event.ID))
+	}
+	if event.Event != "" {
+		output.WriteString(f.eventField("event", event.Event))
+	}
+	output.WriteString(f.formatStreamData([]byte(event.Data)))
+	output.WriteString("\n\n")
+
+	return output.String()
+}
+
+// StreamNDJSON pretty-prints each line of a newline-delimited JSON body as it arrives
+func (f *Formatter) StreamNDJSON(w io.Writer, body io.Reader) error {
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		// json-seq prefixes records with an ASCII record separator
+		line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte{0x1e}))
+		if len(line) > 0 {
+			if _, werr := io.WriteString(w, f.formatStreamData(line)+"\n"); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// formatStreamData pretty-prints a JSON record, leaving other text as-is
+func (f *Formatter) formatStreamData(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return f.prettyPrintJSON(trimmed)
+	}
+	return string(data)
+}
+
+// eventField formats an SSE field line, dimmed on a TTY
+func (f *Formatter) eventField(name, value string) string {
+	if f.isTTY {
+		return fmt.Sprintf("\033[2m%s:\033[0m %s\n", name, value)
+	}
+	return fmt.Sprintf("%s: %s\n", name, value)
+}
+
+// mediaType returns the response media type without parameters
+func mediaType(headers map[string][]string) string {
+	contentType := getHeader(headers, "Content-Type")
+	if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+		return parsed
+	}
+	return strings.TrimSpace(contentType)
+}
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gosh/internal/request"
+)
+
+func TestFormatEvent(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	result := formatter.FormatEvent(&request.Event{ID: "3", Event: "update", Data: `{"ok":true}`})
+	expected := "id: 3\nevent: update\n{\n  \"ok\": true\n}\n\n"
+	if result != expected {
+		t.Errorf("got %q, want %q", result, expected)
+	}
+
+	result = formatter.FormatEvent(&request.Event{Data: "plain text"})
+	if result != "plain text\n\n" {
+		t.Errorf("got %q", result)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	formatter := NewFormatter(false)
+	events := request.NewEventReader(strings.NewReader("data: one\n\ndata: two\n\n"))
+
+	var out strings.Builder
+	if err := formatter.StreamEvents(&out, events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "one\n\ntwo\n\n" {
+		t.Errorf("got %q", out.String())
+	}
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := "{\"a\":1}\n\n[1,2]\nnot json\n{\"last\":true}"
+
+	var out strings.Builder
+	if err := formatter.StreamNDJSON(&out, strings.NewReader(body)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "{\n  \"a\": 1\n}\n[\n  1,\n  2\n]\nnot json\n{\n  \"last\": true\n}\n"
+	if out.String() != expected {
+		t.Errorf("got %q, want %q", out.String(), expected)
+	}
+}
+
+func TestIsEventStreamAndNDJSON(t *testing.T) {
+	sse := map[string][]string{"Content-Type": {"text/event-stream; charset=utf-8"}}
+	ndjson := map[string][]string{"content-type": {"application/x-ndjson"}}
+
+	if !IsEventStream(sse) || IsEventStream(ndjson) {
+		t.Error("IsEventStream mismatch")
+	}
+	if !IsNDJSON(ndjson) || IsNDJSON(sse) {
+		t.Error("IsNDJSON mismatch")
+	}
+}
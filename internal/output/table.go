@@ -0,0 +1,107 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatForm lists URL-encoded fields one per line, in their original order
+func formatForm(f *Formatter, body []byte) (string, error) {
+	type field struct{ key, value string }
+
+	var fields []field
+	width := 0
+	for _, pair := range strings.Split(strings.TrimSpace(string(body)), "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return "", err
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, field{key, value})
+		if n := utf8.RuneCountInString(key); n > width {
+			width = n
+		}
+	}
+
+	var out strings.Builder
+	for i, fld := range fields {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(fld.key))
+		out.WriteString(f.color(f.theme.Key, fld.key))
+		out.WriteString(padding)
+		out.WriteString(f.color(f.theme.Punctuation, " = "))
+		out.WriteString(fld.value)
+	}
+	return out.String(), nil
+}
+
+// formatCSV renders CSV as an aligned table on a TTY. Piped output is left
+// as CSV so it stays machine-readable.
+func formatCSV(f *Formatter, body []byte) (string, error) {
+	if !f.isTTY {
+		return string(body), nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", err
+	}
+
+	var widths []int
+	for _, record := range records {
+		for i, cell := range record {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var out strings.Builder
+	for row, record := range records {
+		if row > 0 {
+			out.WriteString("\n")
+		}
+		for i, cell := range record {
+			if i > 0 {
+				out.WriteString(f.color(f.theme.Punctuation, " │ "))
+			}
+			padded := cell
+			if i < len(record)-1 {
+				padded += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			}
+			if row == 0 {
+				padded = f.color(f.theme.Key, padded)
+			}
+			out.WriteString(padded)
+		}
+
+		// Rule under the header row
+		if row == 0 && len(records) > 1 {
+			out.WriteString("\n")
+			for i, w := range widths {
+				if i > 0 {
+					out.WriteString(f.color(f.theme.Punctuation, "─┼─"))
+				}
+				out.WriteString(f.color(f.theme.Punctuation, strings.Repeat("─", w)))
+			}
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,52 @@
+package output
+
+import "testing"
+
+func TestFormatForm(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	output, err := formatForm(formatter, []byte("name=John+Doe&email=john%40example.com&id=7"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "name  = John Doe\nemail = john@example.com\nid    = 7"
+	if output != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestFormatFormInvalid(t *testing.T) {
+	formatter := NewFormatter(false)
+	if _, err := formatForm(formatter, []byte("a=%zz")); err == nil {
+		t.Error("expected error for invalid escape")
+	}
+}
+
+func TestFormatCSVTable(t *testing.T) {
+	formatter := NewFormatter(true)
+	formatter.SetTheme(Theme{})
+
+	output, err := formatCSV(formatter, []byte("name,age\nJohn,30\n\"Smith, Alexandra\",7\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "name             │ age\n" +
+		"─────────────────┼────\n" +
+		"John             │ 30\n" +
+		"Smith, Alexandra │ 7"
+	if output != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestFormatCSVNonTTY(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := "a,b\n1,2\n"
+
+	output, err := formatCSV(formatter, []byte(body))
+	if err != nil || output != body {
+		t.Errorf("expected CSV unchanged when piped, got %q, %v", output, err)
+	}
+}
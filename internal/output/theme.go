@@ -0,0 +1,172 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ansiReset ends a colored span
+const ansiReset = "\033[0m"
+
+// Theme holds the ANSI escape codes used to color response bodies.
+// An empty code leaves that token uncolored.
+type Theme struct {
+	Key         string
+	String      string
+	Number      string
+	Bool        string
+	Null        string
+	Punctuation string
+	Tag         string // XML/HTML element names
+	Attribute   string // XML/HTML attribute names
+	Comment     string
+}
+
+// DefaultThemeName is used when the global config doesn't set a theme
+const DefaultThemeName = "default"
+
+// themes are the built-in color themes, selected by name in the global config
+var themes = map[string]Theme{
+	"default": {
+		Key:         "\033[34;1m", // Bold blue
+		String:      "\033[32m",   // Green
+		Number:      "\033[36m",   // Cyan
+		Bool:        "\033[33m",   // Yellow
+		Null:        "\033[35m",   // Magenta
+		Punctuation: "\033[2m",    // Dim
+		Tag:         "\033[34;1m", // Bold blue
+		Attribute:   "\033[36m",   // Cyan
+		Comment:     "\033[2m",    // Dim
+	},
+	"solarized": {
+		Key:         "\033[38;5;33m",
+		String:      "\033[38;5;64m",
+		Number:      "\033[38;5;37m",
+		Bool:        "\033[38;5;136m",
+		Null:        "\033[38;5;125m",
+		Punctuation: "\033[38;5;245m",
+		Tag:         "\033[38;5;33m",
+		Attribute:   "\033[38;5;37m",
+		Comment:     "\033[38;5;245m",
+	},
+	"monokai": {
+		Key:         "\033[38;5;197m",
+		String:      "\033[38;5;186m",
+		Number:      "\033[38;5;141m",
+		Bool:        "\033[38;5;81m",
+		Null:        "\033[38;5;81m",
+		Punctuation: "\033[38;5;231m",
+		Tag:         "\033[38;5;197m",
+		Attribute:   "\033[38;5;148m",
+		Comment:     "\033[38;5;242m",
+	},
+	"mono": {
+		Key: "\033[1m", // Bold
+		Tag: "\033[1m",
+	},
+	"none": {},
+}
+
+// LookupTheme returns the built-in theme with the given name
+func LookupTheme(name string) (Theme, error) {
+	if name == "" {
+		name = DefaultThemeName
+	}
+	theme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme: %s (available: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	return theme, nil
+}
+
+// ThemeNames returns the names of the built-in themes, sorted
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// paint wraps text in an escape code
+func paint(code, text string) string {
+	if code == "" || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// colorizeJSONTokens colors each token of an indented JSON document,
+// leaving whitespace and token order untouched
+func colorizeJSONTokens(jsonStr string, theme Theme) string {
+	var out strings.Builder
+	out.Grow(len(jsonStr) * 2)
+
+	for i := 0; i < len(jsonStr); {
+		c := jsonStr[i]
+		switch {
+		case c == '"':
+			end := scanString(jsonStr, i)
+			code := theme.String
+			if isObjectKey(jsonStr, end) {
+				code = theme.Key
+			}
+			out.WriteString(paint(code, jsonStr[i:end]))
+			i = end
+		case c == '{' || c == '}' || c == '[' || c == ']' || c == ',' || c == ':':
+			out.WriteString(paint(theme.Punctuation, jsonStr[i:i+1]))
+			i++
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(jsonStr) && strings.IndexByte("0123456789.eE+-", jsonStr[end]) >= 0 {
+				end++
+			}
+			out.WriteString(paint(theme.Number, jsonStr[i:end]))
+			i = end
+		case strings.HasPrefix(jsonStr[i:], "true"):
+			out.WriteString(paint(theme.Bool, "true"))
+			i += len("true")
+		case strings.HasPrefix(jsonStr[i:], "false"):
+			out.WriteString(paint(theme.Bool, "false"))
+			i += len("false")
+		case strings.HasPrefix(jsonStr[i:], "null"):
+			out.WriteString(paint(theme.Null, "null"))
+			i += len("null")
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// scanString returns the index just past the string starting at start
+func scanString(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// isObjectKey reports whether the string ending at end is followed by a colon
+func isObjectKey(s string, end int) bool {
+	for i := end; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+// testTheme uses readable markers instead of escape codes
+var testTheme = Theme{
+	Key:         "<k>",
+	String:      "<s>",
+	Number:      "<n>",
+	Bool:        "<b>",
+	Null:        "<z>",
+	Punctuation: "<p>",
+}
+
+func TestColorizeJSONTokens(t *testing.T) {
+	input := `{"name": "a \"quoted\": b", "n": -1.5e3, "ok": true, "no": false, "x": null, "list": ["k"]}`
+	result := colorizeJSONTokens(input, testTheme)
+
+	expected := `<p>{` + ansiReset +
+		`<k>"name"` + ansiReset + `<p>:` + ansiReset + ` <s>"a \"quoted\": b"` + ansiReset + `<p>,` + ansiReset +
+		` <k>"n"` + ansiReset + `<p>:` + ansiReset + ` <n>-1.5e3` + ansiReset + `<p>,` + ansiReset +
+		` <k>"ok"` + ansiReset + `<p>:` + ansiReset + ` <b>true` + ansiReset + `<p>,` + ansiReset +
+		` <k>"no"` + ansiReset + `<p>:` + ansiReset + ` <b>false` + ansiReset + `<p>,` + ansiReset +
+		` <k>"x"` + ansiReset + `<p>:` + ansiReset + ` <z>null` + ansiReset + `<p>,` + ansiReset +
+		` <k>"list"` + ansiReset + `<p>:` + ansiReset + ` <p>[` + ansiReset + `<s>"k"` + ansiReset + `<p>]` + ansiReset +
+		`<p>}` + ansiReset
+
+	if result != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestColorizeJSONTokensEmptyTheme(t *testing.T) {
+	input := "{\n  \"a\": [1, true, null]\n}"
+	if result := colorizeJSONTokens(input, Theme{}); result != input {
+		t.Errorf("expected input unchanged with an empty theme, got %q", result)
+	}
+}
+
+func TestColorizeJSONTTY(t *testing.T) {
+	formatter := NewFormatter(true)
+	formatter.SetTheme(testTheme)
+
+	output := formatter.prettyPrintJSON([]byte(`{"a":1}`))
+	if !strings.Contains(output, `<k>"a"`) || !strings.Contains(output, "<n>1") {
+		t.Errorf("expected colored tokens, got %q", output)
+	}
+}
+
+// TestPrettyPrintJSONKeepsKeyOrder tests that keys aren't sorted
+func TestPrettyPrintJSONKeepsKeyOrder(t *testing.T) {
+	formatter := NewFormatter(false)
+
+	output := formatter.prettyPrintJSON([]byte(`{"zebra":1,"apple":2.50,"mango":{"b":1,"a":2}}`))
+	expected := "{\n  \"zebra\": 1,\n  \"apple\": 2.50,\n  \"mango\": {\n    \"b\": 1,\n    \"a\": 2\n  }\n}"
+	if output != expected {
+		t.Errorf("got %q, want %q", output, expected)
+	}
+}
+
+func TestLookupTheme(t *testing.T) {
+	theme, err := LookupTheme("")
+	if err != nil || theme != themes[DefaultThemeName] {
+		t.Errorf("expected default theme for empty name, got %+v, %v", theme, err)
+	}
+
+	if _, err := LookupTheme("Monokai"); err != nil {
+		t.Errorf("expected case-insensitive lookup, got %v", err)
+	}
+
+	_, err = LookupTheme("neon")
+	if err == nil || !strings.Contains(err.Error(), "solarized") {
+		t.Errorf("expected error listing themes, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosh/internal/request"
+)
+
+// waterfallWidth is the width of the longest bar in the --info waterfall
+const waterfallWidth = 40
+
+// timingPhase is one row of the waterfall
+type timingPhase struct {
+	name     string
+	duration time.Duration
+}
+
+// formatWaterfall lists each phase of a request with a bar placed where it
+// happened, like a browser's network panel
+func (f *Formatter) formatWaterfall(t *request.Timing) string {
+	phases := []timingPhase{
+		{"DNS lookup", t.DNS},
+		{"TCP connect", t.Connect},
+		{"TLS handshake", t.TLS},
+		{"Request sent", t.Send},
+		{"Waiting (TTFB)", t.TTFB},
+		{"Content download", t.Transfer},
+	}
+
+	var output strings.Builder
+	var elapsed time.Duration
+	for _, phase := range phases {
+		bar := ""
+		if t.Total > 0 && phase.duration > 0 {
+			length := max(scaleDuration(phase.duration, t.Total), 1)
+			offset := min(scaleDuration(elapsed, t.Total), waterfallWidth-length)
+			bar = "  " + strings.Repeat(" ", offset) + f.color(f.theme.Key, strings.Repeat("█", length))
+		}
+		output.WriteString(fmt.Sprintf("  %-16s %8v%s\n", phase.name, roundDuration(phase.duration), bar))
+		elapsed += phase.duration
+	}
+
+	if t.RemoteAddr != "" {
+		connection := "new connection"
+		if t.Reused {
+			connection = "reused connection"
+		}
+		output.WriteString(fmt.Sprintf("  %-16s %s (%s)\n", "Remote address", t.RemoteAddr, connection))
+	}
+	return output.String()
+}
+
+// scaleDuration converts d to a number of waterfall columns out of total
+func scaleDuration(d, total time.Duration) int {
+	return int(int64(d) * waterfallWidth / int64(total))
+}
+
+// roundDuration keeps durations readable: whole milliseconds, or
+// microseconds below a millisecond
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosh/internal/request"
+)
+
+// newTimedResponse returns a response with a traced timing breakdown
+func newTimedResponse() *request.Response {
+	resp := newRenderResponse("application/json", []byte(`{"id":1}`))
+	resp.Timing = &request.Timing{
+		DNS:        10 * time.Millisecond,
+		Connect:    10 * time.Millisecond,
+		TLS:        20 * time.Millisecond,
+		Send:       500 * time.Microsecond,
+		TTFB:       150 * time.Millisecond,
+		Transfer:   10 * time.Millisecond,
+		Total:      200 * time.Millisecond,
+		RemoteAddr: "93.184.216.34:443",
+	}
+	return resp
+}
+
+func TestFormatWaterfall(t *testing.T) {
+	formatter := NewFormatter(false)
+	output := formatter.FormatResponse(newTimedResponse(), true)
+
+	expected := "Timing: 200ms\n" +
+		"  DNS lookup           10ms  ██\n" +
+		"  TCP connect          10ms    ██\n" +
+		"  TLS handshake        20ms      ████\n" +
+		"  Request sent        500µs          █\n" +
+		"  Waiting (TTFB)      150ms          ██████████████████████████████\n" +
+		"  Content download     10ms                                        ██\n" +
+		"  Remote address   93.184.216.34:443 (new connection)\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("expected waterfall:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestFormatWaterfallReusedConnection(t *testing.T) {
+	formatter := NewFormatter(false)
+	resp := newTimedResponse()
+	resp.Timing = &request.Timing{TTFB: 8 * time.Millisecond, Total: 8 * time.Millisecond, RemoteAddr: "127.0.0.1:8080", Reused: true}
+
+	output := formatter.FormatResponse(resp, true)
+	if !strings.Contains(output, "  DNS lookup             0s\n") {
+		t.Errorf("expected skipped phases without a bar, got:\n%s", output)
+	}
+	if !strings.Contains(output, "127.0.0.1:8080 (reused connection)") {
+		t.Errorf("expected a reused connection, got:\n%s", output)
+	}
+}
+
+func TestRenderJSONEnvelopeTiming(t *testing.T) {
+	formatter := NewFormatter(false)
+	output, err := formatter.Render(newTimedResponse(), FormatJSON, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var env struct {
+		Timing     map[string]float64 `json:"timing"`
+		Connection struct {
+			RemoteAddr string `json:"remoteAddr"`
+			Reused     bool   `json:"reused"`
+		} `json:"connection"`
+	}
+	if err := json.Unmarshal([]byte(output), &env); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	want := map[string]float64{"total": 200, "dns": 10, "connect": 10, "tls": 20, "send": 0.5, "ttfb": 150, "transfer": 10}
+	for name, value := range want {
+		if env.Timing[name] != value {
+			t.Errorf("timing.%s: got %v, want %v", name, env.Timing[name], value)
+		}
+	}
+	if env.Connection.RemoteAddr != "93.184.216.34:443" || env.Connection.Reused {
+		t.Errorf("unexpected connection %+v", env.Connection)
+	}
+}
+
+func TestRenderHARTimings(t *testing.T) {
+	formatter := NewFormatter(false)
+	resp := newTimedResponse()
+	resp.Timing.DNS = 0
+
+	output, err := formatter.Render(resp, FormatHAR, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc har
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	entry := doc.Log.Entries[0]
+	timings := entry.Timings
+	if timings.DNS != -1 || timings.Connect != 30 || timings.SSL != 20 || timings.Wait != 150 || timings.Receive != 10 {
+		t.Errorf("unexpected timings %+v", timings)
+	}
+	if entry.Time != 200 || entry.ServerIPAddress != "93.184.216.34" {
+		t.Errorf("unexpected entry time %v and server %q", entry.Time, entry.ServerIPAddress)
+	}
+}
@@ -0,0 +1,179 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gosh/internal/auth"
+)
+
+// redacted replaces hidden values in --verbose and HAR output
+const redacted = "[REDACTED]"
+
+// minSecretLength is the shortest credential value hidden wherever it
+// appears; shorter values would mask unrelated text
+const minSecretLength = 4
+
+// sensitiveHeaders always carry credentials
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Redactor hides credentials in --verbose and HAR output. A nil Redactor,
+// as with --show-secrets, leaves everything visible.
+type Redactor struct {
+	headers map[string]bool // Canonical names of headers whose values are hidden
+	secrets []string        // Values hidden wherever they appear, longest first
+}
+
+// NewRedactor hides the usual credential headers, plus the headers and
+// secret values of preset, which may be nil
+func NewRedactor(preset *auth.AuthPreset) *Redactor {
+	r := &Redactor{headers: make(map[string]bool)}
+	names := sensitiveHeaders
+	if preset != nil {
+		names = append(preset.SecretHeaders(), names...)
+		for _, secret := range preset.Secrets() {
+			if len(secret) < minSecretLength {
+				continue
+			}
+			// Secrets may also appear encoded in query strings and form bodies
+			r.secrets = append(r.secrets, secret, url.QueryEscape(secret), url.PathEscape(secret))
+		}
+		sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	}
+	for _, name := range names {
+		if name != "" {
+			r.headers[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	return r
+}
+
+// Header returns a header value as it may be shown. Authorization keeps
+// its scheme, e.g. "Bearer [REDACTED]".
+func (r *Redactor) Header(name, value string) string {
+	if r == nil {
+		return value
+	}
+	name = http.CanonicalHeaderKey(name)
+	if !r.headers[name] {
+		return r.String(value)
+	}
+	if name == "Authorization" || name == "Proxy-Authorization" {
+		if scheme, _, ok := strings.Cut(value, " "); ok {
+			return scheme + " " + redacted
+		}
+	}
+	return redacted
+}
+
+// String hides the secret values in text
+func (r *Redactor) String(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	return text
+}
+
+// WireDumper writes each request and response as it crosses the wire, for
+// --verbose. Request lines are prefixed with "> " and response lines with
+// "< ", as curl does. Request bodies follow their headers; response bodies
+// are left to the usual output.
+type WireDumper struct {
+	w        io.Writer
+	redact   *Redactor
+	streamed bool // Request bodies are streamed, as with --multipart, and not shown
+}
+
+// NewWireDumper creates a dumper writing to w. Streamed request bodies are
+// summarized rather than read a second time.
+func NewWireDumper(w io.Writer, redact *Redactor, streamed bool) *WireDumper {
+	return &WireDumper{w: w, redact: redact, streamed: streamed}
+}
+
+// Request writes the request line, the headers as net/http sends them,
+// including the defaults it adds such as User-Agent, and the body
+func (d *WireDumper) Request(req *http.Request) {
+	// The dump goes through a transport; keep it out of the timing trace
+	dump, err := httputil.DumpRequestOut(req.WithContext(context.Background()), false)
+	if err != nil {
+		fmt.Fprintf(d.w, "> (request not shown: %v)\n\n", err)
+		return
+	}
+
+	var output strings.Builder
+	d.writeHead(&output, "> ", string(dump))
+	output.WriteString(d.requestBody(req))
+	output.WriteString("\n")
+	io.WriteString(d.w, output.String())
+}
+
+// Response writes the status line and headers
+func (d *WireDumper) Response(resp *http.Response) {
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		fmt.Fprintf(d.w, "< (response not shown: %v)\n\n", err)
+		return
+	}
+
+	var output strings.Builder
+	d.writeHead(&output, "< ", string(dump))
+	output.WriteString("\n")
+	io.WriteString(d.w, output.String())
+}
+
+// writeHead writes the start line and headers of a dump, redacted, each
+// line prefixed
+func (d *WireDumper) writeHead(output *strings.Builder, prefix, dump string) {
+	head, _, _ := strings.Cut(dump, "\r\n\r\n")
+	for i, line := range strings.Split(head, "\r\n") {
+		if i > 0 {
+			if name, value, ok := strings.Cut(line, ":"); ok {
+				line = name + ": " + d.redact.Header(name, strings.TrimSpace(value))
+			}
+		} else {
+			line = d.redact.String(line)
+		}
+		output.WriteString(prefix + line + "\n")
+	}
+	output.WriteString(strings.TrimSpace(prefix) + "\n")
+}
+
+// requestBody returns the request body as it may be shown
+func (d *WireDumper) requestBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	if d.streamed || req.GetBody == nil {
+		if req.ContentLength > 0 {
+			return fmt.Sprintf("[streamed body, %d bytes]\n", req.ContentLength)
+		}
+		return "[streamed body]\n"
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Sprintf("[body not shown: %v]\n", err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Sprintf("[body not shown: %v]\n", err)
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	if !utf8.Valid(data) {
+		return fmt.Sprintf("[%d bytes of binary data]\n", len(data))
+	}
+	return ensureNewline(d.redact.String(string(data)))
+}
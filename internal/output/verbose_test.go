@@ -0,0 +1,113 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gosh/internal/auth"
+)
+
+func TestWireDumperRequest(t *testing.T) {
+	preset := &auth.AuthPreset{Type: "custom", Header: "X-Token", Value: "tok-123", Secret: "s3cret-value"}
+	req, _ := http.NewRequest("POST", "https://api.example.com/users?key=s3cret-value", strings.NewReader(`{"name":"alice","secret":"s3cret-value"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer abc.def")
+	req.Header.Set("X-Token", "tok-123")
+
+	var buf bytes.Buffer
+	NewWireDumper(&buf, NewRedactor(preset), false).Request(req)
+	output := buf.String()
+
+	for _, want := range []string{
+		"> POST /users?key=[REDACTED] HTTP/1.1\n",
+		"> Host: api.example.com\n",
+		"> Authorization: Bearer [REDACTED]\n",
+		"> X-Token: [REDACTED]\n",
+		"> Content-Type: application/json\n",
+		"> User-Agent: Go-http-client/1.1\n",
+		">\n" + `{"name":"alice","secret":"[REDACTED]"}` + "\n\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "abc.def") || strings.Contains(output, "tok-123") {
+		t.Errorf("expected credentials to be hidden:\n%s", output)
+	}
+
+	// The request body is left unread for sending
+	body, _ := io.ReadAll(req.Body)
+	if !strings.HasPrefix(string(body), `{"name"`) {
+		t.Errorf("expected the body to be unread, got %q", body)
+	}
+}
+
+func TestWireDumperShowSecrets(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://api.example.com/", nil)
+	req.Header.Set("Authorization", "Bearer abc.def")
+
+	var buf bytes.Buffer
+	NewWireDumper(&buf, nil, false).Request(req)
+	if !strings.Contains(buf.String(), "> Authorization: Bearer abc.def\n") {
+		t.Errorf("expected the credentials with no redactor, got:\n%s", buf.String())
+	}
+	if !strings.HasSuffix(buf.String(), ">\n\n") {
+		t.Errorf("expected no body, got:\n%s", buf.String())
+	}
+}
+
+func TestWireDumperRequestBodies(t *testing.T) {
+	tests := []struct {
+		body     []byte
+		streamed bool
+		want     string
+	}{
+		{[]byte{0xff, 0xfe, 0x00}, false, "[3 bytes of binary data]\n"},
+		{[]byte("a=1"), true, "[streamed body, 3 bytes]\n"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "http://api.example.com/upload", bytes.NewReader(tt.body))
+		var buf bytes.Buffer
+		NewWireDumper(&buf, NewRedactor(nil), tt.streamed).Request(req)
+		if !strings.HasSuffix(buf.String(), ">\n"+tt.want+"\n") {
+			t.Errorf("expected %q, got:\n%s", tt.want, buf.String())
+		}
+	}
+}
+
+func TestWireDumperResponse(t *testing.T) {
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"Set-Cookie":   {"session=abc123; Path=/"},
+		},
+		Body:          io.NopCloser(strings.NewReader(`{"id":1}`)),
+		ContentLength: 8,
+	}
+
+	var buf bytes.Buffer
+	NewWireDumper(&buf, NewRedactor(nil), false).Response(resp)
+	expected := "< HTTP/1.1 200 OK\n" +
+		"< Content-Length: 8\n" +
+		"< Content-Type: application/json\n" +
+		"< Set-Cookie: [REDACTED]\n" +
+		"<\n\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// The body is left for the usual output
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"id":1}` {
+		t.Errorf("expected the body to be unread, got %q", body)
+	}
+}
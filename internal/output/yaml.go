@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlKeyPattern matches the key of a "key: value" line, after any list markers
+var yamlKeyPattern = regexp.MustCompile(`^(\s*(?:- )*)([^\s#'"\-][^#]*?|"[^"]*"|'[^']*'):(\s|$)`)
+
+// formatYAML re-indents YAML documents, keeping key order and comments
+func formatYAML(f *Formatter, body []byte) (string, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(body))
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := encoder.Encode(&node); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	formatted := strings.TrimSuffix(out.String(), "\n")
+	if !f.isTTY {
+		return formatted, nil
+	}
+	return f.colorizeYAML(formatted), nil
+}
+
+// colorizeYAML colors mapping keys and comment lines
+func (f *Formatter) colorizeYAML(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = f.color(f.theme.Comment, line)
+		case trimmed == "---" || trimmed == "...":
+			lines[i] = f.color(f.theme.Punctuation, line)
+		default:
+			if m := yamlKeyPattern.FindStringSubmatchIndex(line); m != nil {
+				lines[i] = line[:m[4]] + f.color(f.theme.Key, line[m[4]:m[5]]) + line[m[5]:]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,44 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatYAML(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := "# settings\nzeta:   1\nalpha:\n    - name: a\n      tags: [x, y]\n---\nsecond: true\n"
+
+	output, err := formatYAML(formatter, []byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "# settings\nzeta: 1\nalpha:\n  - name: a\n    tags: [x, y]\n---\nsecond: true"
+	if output != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", output, expected)
+	}
+}
+
+func TestFormatYAMLInvalid(t *testing.T) {
+	formatter := NewFormatter(false)
+	body := []byte("a: [unclosed\n")
+
+	output := formatter.formatBody(body, map[string][]string{"Content-Type": {"application/yaml"}})
+	if output != string(body) {
+		t.Errorf("expected raw body for invalid YAML, got %q", output)
+	}
+}
+
+func TestColorizeYAML(t *testing.T) {
+	formatter := NewFormatter(true)
+	formatter.SetTheme(Theme{Key: "<k>", Comment: "<c>"})
+
+	output := formatter.colorizeYAML("# note\nname: gosh\nitems:\n  - id: 1\n  - plain\nurl: http://x")
+
+	for _, want := range []string{"<c># note", "<k>name" + ansiReset + ": gosh", "  - <k>id" + ansiReset, "  - plain", "<k>url" + ansiReset + ": http://x"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
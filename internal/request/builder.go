@@ -1,6 +1,7 @@
 package request
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -34,10 +35,26 @@ func (b *Builder) Build() (*http.Request, error) {
 		u.RawQuery = q.Encode()
 	}
 
+	if b.req.Multipart {
+		return b.buildMultipart(u)
+	}
+
+	// Encode data items into the body
+	body := b.req.Body
+	var contentType string
+	if len(b.req.Items) > 0 {
+		body, contentType, err = BuildBody(b.req.Items, b.req.Form, body)
+		if err != nil {
+			return nil, err
+		}
+	} else if b.req.Form {
+		contentType = ContentTypeForm
+	}
+
 	// Create request with body
 	var bodyReader io.Reader
-	if b.req.Body != "" {
-		bodyReader = strings.NewReader(b.req.Body)
+	if body != "" {
+		bodyReader = strings.NewReader(body)
 	}
 
 	httpReq, err := http.NewRequest(b.req.Method, u.String(), bodyReader)
@@ -45,17 +62,63 @@ func (b *Builder) Build() (*http.Request, error) {
 		return nil, err
 	}
 
+	if err := b.finish(httpReq, contentType, false); err != nil {
+		return nil, err
+	}
+	return httpReq, nil
+}
+
+// buildMultipart constructs a request whose body streams multipart/form-data
+// parts from disk
+func (b *Builder) buildMultipart(u *url.URL) (*http.Request, error) {
+	if b.req.Body != "" {
+		return nil, fmt.Errorf("a raw body cannot be combined with --multipart")
+	}
+
+	body, err := NewMultipartBody(b.req.Items)
+	if err != nil {
+		return nil, err
+	}
+	length, err := body.ContentLength()
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(b.req.Method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Body = body.Reader()
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return body.Reader(), nil
+	}
+	httpReq.ContentLength = length
+
+	// The boundary must match the body, so it always wins over -H
+	if err := b.finish(httpReq, body.ContentType(), true); err != nil {
+		return nil, err
+	}
+	return httpReq, nil
+}
+
+// finish adds headers and authentication to a built request. contentType
+// is a default unless override is set. Authentication comes last so that
+// signing presets see the final request.
+func (b *Builder) finish(httpReq *http.Request, contentType string, override bool) error {
 	// Add headers
 	for key, val := range b.req.Headers {
 		httpReq.Header.Set(key, val)
 	}
+	if contentType != "" && (override || httpReq.Header.Get("Content-Type") == "") {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
 
 	// Apply authentication if provided
 	if b.req.Auth != nil {
 		if err := b.req.Auth.Apply(httpReq); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	return httpReq, nil
+	return nil
 }
@@ -1,6 +1,11 @@
 package request
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -165,6 +170,38 @@ func TestBuilderWithAuth(t *testing.T) {
 	}
 }
 
+// TestBuilderSignsFinalRequest tests that signing auth sees the encoded body
+// and headers
+func TestBuilderSignsFinalRequest(t *testing.T) {
+	req := &Request{
+		Method:  "POST",
+		URL:     "https://example.amazonaws.com/items",
+		Headers: map[string]string{"X-Amz-Date": "20150830T123600Z"},
+		Items:   []Item{{Type: ItemData, Key: "name", Value: "gosh"}},
+		Auth: &auth.AuthPreset{
+			Type:      "aws-sigv4",
+			AccessKey: "AKIDEXAMPLE",
+			SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			Region:    "us-east-1",
+			Service:   "s3",
+		},
+	}
+
+	httpReq, err := NewBuilder(req).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	authHeader := httpReq.Header.Get("Authorization")
+	if !strings.Contains(authHeader, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("expected content type to be signed, got %q", authHeader)
+	}
+	sum := sha256.Sum256([]byte(`{"name":"gosh"}`))
+	if hash := httpReq.Header.Get("X-Amz-Content-Sha256"); hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected payload hash of the encoded body, got %q", hash)
+	}
+}
+
 // TestBuilderInvalidURL tests building with invalid URL
 func TestBuilderInvalidURL(t *testing.T) {
 	req := &Request{
@@ -672,3 +709,214 @@ func TestNewBuilder(t *testing.T) {
 		t.Errorf("request not stored correctly")
 	}
 }
+
+// TestBuilderWithItems tests building a JSON body from data items
+func TestBuilderWithItems(t *testing.T) {
+	req := &Request{
+		Method:  "POST",
+		URL:     "https://api.example.com/users",
+		Headers: make(map[string]string),
+		Items: []Item{
+			{Type: ItemData, Key: "name", Value: "John"},
+			{Type: ItemRawJSON, Key: "age", Value: "30"},
+		},
+	}
+
+	httpReq, err := NewBuilder(req).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if httpReq.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type: got %q, want 'application/json'", httpReq.Header.Get("Content-Type"))
+	}
+
+	body, _ := io.ReadAll(httpReq.Body)
+	if string(body) != `{"age":30,"name":"John"}` {
+		t.Errorf("body: got %s", body)
+	}
+}
+
+// TestBuilderWithFormItems tests form encoding and Content-Type precedence
+func TestBuilderWithFormItems(t *testing.T) {
+	req := &Request{
+		Method:  "POST",
+		URL:     "https://api.example.com/login",
+		Headers: make(map[string]string),
+		Items:   []Item{{Type: ItemData, Key: "user", Value: "john"}},
+		Form:    true,
+	}
+
+	httpReq, err := NewBuilder(req).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if httpReq.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type: got %q", httpReq.Header.Get("Content-Type"))
+	}
+
+	// An explicit header wins over the item-derived content type
+	req.Headers["content-type"] = "application/vnd.api+json"
+	req.Form = false
+	httpReq, err = NewBuilder(req).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if httpReq.Header.Get("Content-Type") != "application/vnd.api+json" {
+		t.Errorf("Content-Type: got %q", httpReq.Header.Get("Content-Type"))
+	}
+}
+
+// TestExecutorOpenStreamsPastTimeout tests that the timeout only covers response headers
+func TestExecutorOpenStreamsPastTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first "))
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		_, _ = w.Write([]byte("second"))
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(50 * time.Millisecond)
+	resp, body, err := executor.Open(&Request{Method: "GET", URL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("unexpected error reading body: %v", err)
+	}
+	if string(data) != "first second" {
+		t.Errorf("body: got %q", data)
+	}
+}
+
+// TestExecutorOpenTimeout tests that Open times out waiting for headers
+func TestExecutorOpenTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(10 * time.Millisecond)
+	if _, _, err := executor.Open(&Request{Method: "GET", URL: server.URL}); err == nil {
+		t.Fatal("expected timeout error, got nil")
+	}
+}
+
+// TestExecutorRecordsSentRequest tests that the response records the request as sent
+func TestExecutorRecordsSentRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{
+		Method:      "POST",
+		URL:         server.URL + "/users",
+		Headers:     map[string]string{},
+		QueryParams: map[string]string{"page": "2"},
+		Items:       []Item{{Type: ItemData, Key: "name", Value: "John"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := resp.Request
+	if sent == nil {
+		t.Fatal("expected the sent request to be recorded")
+	}
+	if sent.Method != "POST" || sent.URL != server.URL+"/users?page=2" {
+		t.Errorf("got %s %s", sent.Method, sent.URL)
+	}
+	if string(sent.Body) != `{"name":"John"}` {
+		t.Errorf("body: got %q", sent.Body)
+	}
+	if sent.Headers["Content-Type"][0] != ContentTypeJSON {
+		t.Errorf("headers: got %v", sent.Headers)
+	}
+	if resp.Proto != "HTTP/1.1" || resp.StartedAt.IsZero() {
+		t.Errorf("expected proto and start time, got %q %v", resp.Proto, resp.StartedAt)
+	}
+}
+
+// TestExecutorDigestAuth tests that a digest challenge is answered by
+// replaying the request with its body
+func TestExecutorDigestAuth(t *testing.T) {
+	var attempts int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc123", qop="auth", algorithm=MD5`)
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "challenge")
+			return
+		}
+
+		params := map[string]string{}
+		for _, part := range strings.Split(strings.TrimPrefix(header, "Digest "), ", ") {
+			key, val, _ := strings.Cut(part, "=")
+			params[key] = strings.Trim(val, `"`)
+		}
+		md5hex := func(s string) string {
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}
+		ha1 := md5hex("john:test:secret")
+		ha2 := md5hex(r.Method + ":" + params["uri"])
+		expected := md5hex(strings.Join([]string{ha1, "abc123", params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		if params["response"] != expected || params["uri"] != r.URL.RequestURI() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("welcome"))
+	}))
+	defer server.Close()
+
+	preset := &auth.AuthPreset{Type: "digest", Username: "john", Password: "secret"}
+	req := &Request{
+		Method:  "POST",
+		URL:     server.URL + "/private?page=1",
+		Headers: make(map[string]string),
+		Body:    `{"hello":"world"}`,
+		Auth:    preset,
+	}
+
+	resp, err := NewExecutor(5 * time.Second).Execute(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "welcome" {
+		t.Fatalf("expected 200 welcome, got %d %q", resp.StatusCode, resp.Body)
+	}
+	if attempts != 2 || bodies[1] != `{"hello":"world"}` {
+		t.Errorf("expected body replayed on the second attempt, got %d attempts %q", attempts, bodies)
+	}
+	if !strings.HasPrefix(http.Header(resp.Request.Headers).Get("Authorization"), "Digest ") {
+		t.Errorf("expected the sent request to record the digest header, got %v", resp.Request.Headers)
+	}
+
+	// The next request answers the known challenge up front
+	attempts = 0
+	resp, body, err := NewExecutor(5 * time.Second).Open(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body.Close()
+	if resp.StatusCode != http.StatusOK || attempts != 1 {
+		t.Errorf("expected one authorized attempt, got %d with status %d", attempts, resp.StatusCode)
+	}
+}
@@ -1,9 +1,13 @@
 package request
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/gosh/internal/auth"
 )
 
 // Executor executes HTTP requests
@@ -22,37 +26,258 @@ func NewExecutor(timeout time.Duration) *Executor {
 	}
 }
 
-// Execute executes an HTTP request and returns the response
+// Execute executes an HTTP request and returns the response. Each retry
+// rebuilds the request, so signatures and tokens are fresh.
 func (e *Executor) Execute(req *Request) (*Response, error) {
-	builder := NewBuilder(req)
-	httpReq, err := builder.Build()
+	client, err := e.clientFor(req)
 	if err != nil {
 		return nil, err
 	}
 
-	start := time.Now()
-	httpResp, err := e.client.Do(httpReq)
-	duration := time.Since(start)
-
+	var httpReq *http.Request
+	var start time.Time
+	var collector timingCollector
+	httpResp, attempts, err := req.Retry.run(req.Method, func() (*http.Response, error) {
+		built, err := NewBuilder(req).Build()
+		if err != nil {
+			return nil, err
+		}
+		built = built.WithContext(collector.withTrace(built.Context()))
+		start = time.Now()
+		httpResp, sent, err := e.send(client, built, req.Auth, req.Wire)
+		httpReq = sent
+		return httpResp, err
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer httpResp.Body.Close()
+	duration := attempts[len(attempts)-1].Duration
+
+	sent, err := newSentRequest(httpReq, req.Multipart)
+	if err != nil {
+		return nil, err
+	}
 
 	// Read response body
 	body, err := io.ReadAll(httpResp.Body)
 	if err != nil {
 		return nil, err
 	}
+	timing := collector.timing(time.Now())
 
 	resp := &Response{
 		StatusCode: httpResp.StatusCode,
 		Status:     httpResp.Status,
+		Proto:      httpResp.Proto,
 		Headers:    httpResp.Header,
 		Body:       body,
 		Duration:   duration,
 		Size:       len(body),
+		StartedAt:  start,
+		Request:    sent,
+		Redirects:  redirectChain(httpResp),
+		Attempts:   attempts,
+		Timing:     timing,
 	}
 
 	return resp, nil
 }
+
+// Open sends the request and returns the response with its body unread, for
+// downloads and streaming. The timeout covers waiting for response headers
+// only, so long transfers aren't cut off. The caller must close the body.
+func (e *Executor) Open(req *Request) (*Response, io.ReadCloser, error) {
+	baseClient, err := e.clientFor(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The client timeout would also cover reading the body
+	client := *baseClient
+	client.Timeout = 0
+
+	var httpReq *http.Request
+	var start time.Time
+	var cancel context.CancelFunc
+	var collector timingCollector
+	httpResp, attempts, err := req.Retry.run(req.Method, func() (*http.Response, error) {
+		built, err := NewBuilder(req).Build()
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, attemptCancel := context.WithCancel(collector.withTrace(context.Background()))
+		built = built.WithContext(ctx)
+		var timer *time.Timer
+		if e.timeout > 0 {
+			timer = time.AfterFunc(e.timeout, attemptCancel)
+		}
+
+		start = time.Now()
+		httpResp, sent, err := e.send(&client, built, req.Auth, req.Wire)
+		httpReq = sent
+
+		if timer != nil && !timer.Stop() {
+			err = headerTimeoutError{e.timeout}
+			if httpResp != nil {
+				httpResp.Body.Close()
+			}
+		}
+		if err != nil {
+			attemptCancel()
+			return nil, err
+		}
+		cancel = attemptCancel
+		return httpResp, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	duration := attempts[len(attempts)-1].Duration
+
+	sent, err := newSentRequest(httpReq, req.Multipart)
+	if err != nil {
+		httpResp.Body.Close()
+		cancel()
+		return nil, nil, err
+	}
+
+	resp := &Response{
+		StatusCode: httpResp.StatusCode,
+		Status:     httpResp.Status,
+		Proto:      httpResp.Proto,
+		Headers:    httpResp.Header,
+		Duration:   duration,
+		StartedAt:  start,
+		Request:    sent,
+		Redirects:  redirectChain(httpResp),
+		Attempts:   attempts,
+		Timing:     collector.timing(time.Time{}),
+	}
+
+	return resp, &cancelOnClose{ReadCloser: httpResp.Body, cancel: cancel}, nil
+}
+
+// send performs the request. A 401 challenge the auth preset can answer,
+// such as Digest, is replayed once with the body rebuilt through GetBody,
+// unless a redirect led to another host. It returns the request that
+// produced the response.
+func (e *Executor) send(client *http.Client, httpReq *http.Request, preset *auth.AuthPreset, wire WireObserver) (*http.Response, *http.Request, error) {
+	httpResp, err := do(client, httpReq, wire)
+	if err != nil || preset == nil || httpResp.StatusCode != http.StatusUnauthorized {
+		return httpResp, httpReq, err
+	}
+	if !sameOrigin(httpReq.URL, httpResp.Request.URL) {
+		return httpResp, httpReq, nil
+	}
+
+	retry, err := rebuildRequest(httpReq)
+	if err != nil {
+		httpResp.Body.Close()
+		return nil, nil, err
+	}
+	if retry == nil {
+		return httpResp, httpReq, nil
+	}
+
+	ok, err := preset.Respond(retry, httpResp)
+	if err != nil {
+		httpResp.Body.Close()
+		return nil, nil, err
+	}
+	if !ok {
+		return httpResp, httpReq, nil
+	}
+
+	// Drain the challenge so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(httpResp.Body, 64<<10))
+	httpResp.Body.Close()
+
+	httpResp, err = do(client, retry, wire)
+	return httpResp, retry, err
+}
+
+// do sends a request, showing it and its final response to wire. Redirect
+// hops in between are shown by checkRedirect.
+func do(client *http.Client, httpReq *http.Request, wire WireObserver) (*http.Response, error) {
+	if wire != nil {
+		wire.Request(httpReq)
+	}
+	httpResp, err := client.Do(httpReq)
+	if err == nil && wire != nil {
+		wire.Response(httpResp)
+	}
+	return httpResp, err
+}
+
+// rebuildRequest copies a sent request so it can be sent again, or returns
+// nil when its body can't be re-read
+func rebuildRequest(httpReq *http.Request) (*http.Request, error) {
+	retry := httpReq.Clone(httpReq.Context())
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return retry, nil
+	}
+	if httpReq.GetBody == nil {
+		return nil, nil
+	}
+
+	body, err := httpReq.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	retry.Body = body
+	return retry, nil
+}
+
+// newSentRequest records a built request. Buffered bodies are read through
+// GetBody so the request itself is left unread; streamed bodies aren't kept.
+func newSentRequest(httpReq *http.Request, streamed bool) (*SentRequest, error) {
+	sent := &SentRequest{
+		Method:  httpReq.Method,
+		URL:     httpReq.URL.String(),
+		Proto:   httpReq.Proto,
+		Headers: httpReq.Header.Clone(),
+	}
+	if httpReq.Host != "" && httpReq.Host != httpReq.URL.Host {
+		sent.Headers["Host"] = []string{httpReq.Host}
+	}
+
+	if streamed || httpReq.GetBody == nil || httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return sent, nil
+	}
+	body, err := httpReq.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if sent.Body, err = io.ReadAll(body); err != nil {
+		return nil, err
+	}
+	return sent, nil
+}
+
+// headerTimeoutError reports that response headers didn't arrive in time.
+// It is a net.Error so the attempt can be retried.
+type headerTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e headerTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v waiting for response headers", e.timeout)
+}
+
+func (e headerTimeoutError) Timeout() bool   { return true }
+func (e headerTimeoutError) Temporary() bool { return true }
+
+// cancelOnClose releases the request context once the body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,318 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ItemType identifies how a request item contributes to the request
+type ItemType string
+
+const (
+	ItemQuery       ItemType = "=="  // name==value query parameter
+	ItemData        ItemType = "="   // name=value string field
+	ItemRawJSON     ItemType = ":="  // name:=json raw JSON value
+	ItemDataFile    ItemType = "=@"  // name=@file string field read from a file
+	ItemRawJSONFile ItemType = ":=@" // name:=@file raw JSON value read from a file
+	ItemFile        ItemType = "@"   // name@file file embed
+)
+
+// Content types set by data items when no Content-Type header is given
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// itemSeparators is ordered so that longer separators win at the same position
+var itemSeparators = []ItemType{
+	ItemRawJSONFile,
+	ItemQuery,
+	ItemRawJSON,
+	ItemDataFile,
+	ItemData,
+	ItemFile,
+}
+
+// Item is a single HTTPie-style request item such as name=value or age:=42
+type Item struct {
+	Type  ItemType
+	Key   string
+	Value string
+}
+
+// String returns the item in CLI syntax
+func (i Item) String() string {
+	return escapeItemKey(i.Key) + string(i.Type) + i.Value
+}
+
+// ParseItem parses a request item. The earliest separator in the argument
+// decides its type; a backslash escapes a separator character in the key.
+// ok is false when the argument contains no separator.
+func ParseItem(arg string) (Item, bool) {
+	var key strings.Builder
+	for i := 0; i < len(arg); i++ {
+		if arg[i] == '\\' && i+1 < len(arg) && strings.ContainsRune("=:@\\", rune(arg[i+1])) {
+			i++
+			key.WriteByte(arg[i])
+			continue
+		}
+		for _, sep := range itemSeparators {
+			if strings.HasPrefix(arg[i:], string(sep)) {
+				if key.Len() == 0 {
+					return Item{}, false
+				}
+				return Item{Type: sep, Key: key.String(), Value: arg[i+len(sep):]}, true
+			}
+		}
+		key.WriteByte(arg[i])
+	}
+	return Item{}, false
+}
+
+// escapeItemKey escapes separator characters so String round-trips through ParseItem
+func escapeItemKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune("=:@\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// BuildBody encodes data items into a request body, merged over an existing
+// raw body. JSON is the default encoding; form selects URL-encoded fields.
+// It returns the body and the content type it should be sent with.
+func BuildBody(items []Item, form bool, rawBody string) (string, string, error) {
+	if form {
+		body, err := buildFormBody(items, rawBody)
+		return body, ContentTypeForm, err
+	}
+	body, err := buildJSONBody(items, rawBody)
+	return body, ContentTypeJSON, err
+}
+
+// buildJSONBody sets each item at its (possibly nested) path in a JSON object
+func buildJSONBody(items []Item, rawBody string) (string, error) {
+	root := make(map[string]interface{})
+	if strings.TrimSpace(rawBody) != "" {
+		if err := json.Unmarshal([]byte(rawBody), &root); err != nil {
+			return "", fmt.Errorf("cannot merge data items into body: body is not a JSON object")
+		}
+	}
+
+	for _, item := range items {
+		if item.Type == ItemQuery {
+			continue
+		}
+		value, err := item.jsonValue()
+		if err != nil {
+			return "", err
+		}
+		path, err := parseKeyPath(item.Key)
+		if err != nil {
+			return "", err
+		}
+		updated, err := setPath(root, path, value)
+		if err != nil {
+			return "", fmt.Errorf("invalid item %s: %w", item.Key, err)
+		}
+		root = updated.(map[string]interface{})
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// buildFormBody encodes items as URL-encoded form fields
+func buildFormBody(items []Item, rawBody string) (string, error) {
+	values := url.Values{}
+	if strings.TrimSpace(rawBody) != "" {
+		parsed, err := url.ParseQuery(rawBody)
+		if err != nil {
+			return "", fmt.Errorf("cannot merge form fields into body: %w", err)
+		}
+		values = parsed
+	}
+
+	for _, item := range items {
+		var value string
+		switch item.Type {
+		case ItemQuery:
+			continue
+		case ItemData:
+			value = item.Value
+		case ItemDataFile, ItemFile:
+			path, _ := ParseFileSpec(item.Value)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read file for %s: %w", item.Key, err)
+			}
+			value = string(data)
+		default:
+			return "", fmt.Errorf("raw JSON item %s is not supported with --form", item.Key)
+		}
+
+		if strings.HasSuffix(item.Key, "[]") {
+			values.Add(item.Key, value)
+		} else {
+			values.Set(item.Key, value)
+		}
+	}
+
+	return values.Encode(), nil
+}
+
+// jsonValue returns the value the item contributes to a JSON body
+func (i Item) jsonValue() (interface{}, error) {
+	switch i.Type {
+	case ItemData:
+		return i.Value, nil
+	case ItemRawJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(i.Value), &v); err != nil {
+			return nil, fmt.Errorf("invalid JSON for %s: %w", i.Key, err)
+		}
+		return v, nil
+	case ItemDataFile, ItemFile:
+		path, _ := ParseFileSpec(i.Value)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file for %s: %w", i.Key, err)
+		}
+		return string(data), nil
+	case ItemRawJSONFile:
+		path, _ := ParseFileSpec(i.Value)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file for %s: %w", i.Key, err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid JSON in %s: %w", path, err)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported item type for %s: %s", i.Key, i.Type)
+	}
+}
+
+// maxArrayIndex is the largest array index in a nested key. Missing
+// elements before an index are filled with null, so this bounds the array.
+const maxArrayIndex = 1000
+
+// pathSegment is one step of a nested key: an object key, an array index,
+// or an append ("[]")
+type pathSegment struct {
+	key    string
+	index  int
+	isList bool
+	append bool
+}
+
+// parseKeyPath splits a key like user[address][city] or tags[] into segments
+func parseKeyPath(key string) ([]pathSegment, error) {
+	open := strings.IndexByte(key, '[')
+	if open < 0 {
+		return []pathSegment{{key: key}}, nil
+	}
+	if open == 0 || !strings.HasSuffix(key, "]") {
+		return nil, fmt.Errorf("invalid nested key: %s", key)
+	}
+
+	segments := []pathSegment{{key: key[:open]}}
+	rest := key[open:]
+	for rest != "" {
+		if rest[0] != '[' {
+			return nil, fmt.Errorf("invalid nested key: %s", key)
+		}
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid nested key: %s", key)
+		}
+		name := rest[1:end]
+		rest = rest[end+1:]
+
+		switch {
+		case name == "":
+			segments = append(segments, pathSegment{isList: true, append: true})
+		case isDigits(name):
+			n, err := strconv.Atoi(name)
+			if err != nil || n > maxArrayIndex {
+				return nil, fmt.Errorf("array index %s in %s is too large (at most %d)", name, key, maxArrayIndex)
+			}
+			segments = append(segments, pathSegment{isList: true, index: n})
+		default:
+			segments = append(segments, pathSegment{key: name})
+		}
+	}
+	return segments, nil
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// setPath sets value at path inside container, creating intermediate
+// objects and arrays as needed, and returns the updated container
+func setPath(container interface{}, path []pathSegment, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	seg := path[0]
+
+	if !seg.isList {
+		obj, ok := container.(map[string]interface{})
+		if container == nil {
+			obj, ok = make(map[string]interface{}), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot set key %q on a non-object value", seg.key)
+		}
+		child, err := setPath(obj[seg.key], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		obj[seg.key] = child
+		return obj, nil
+	}
+
+	list, ok := container.([]interface{})
+	if container == nil {
+		list, ok = []interface{}{}, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot index a non-array value")
+	}
+
+	index := seg.index
+	if seg.append {
+		index = len(list)
+	}
+	for len(list) <= index {
+		list = append(list, nil)
+	}
+	child, err := setPath(list[index], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	list[index] = child
+	return list, nil
+}
@@ -0,0 +1,192 @@
+package request
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected Item
+	}{
+		{"name=John", Item{ItemData, "name", "John"}},
+		{"age:=42", Item{ItemRawJSON, "age", "42"}},
+		{"limit==10", Item{ItemQuery, "limit", "10"}},
+		{"bio=@bio.txt", Item{ItemDataFile, "bio", "bio.txt"}},
+		{"meta:=@meta.json", Item{ItemRawJSONFile, "meta", "meta.json"}},
+		{"avatar@photo.png", Item{ItemFile, "avatar", "photo.png"}},
+		{"email=john@example.com", Item{ItemData, "email", "john@example.com"}},
+		{"url=https://x.com/?a==b", Item{ItemData, "url", "https://x.com/?a==b"}},
+		{"user[address][city]=Paris", Item{ItemData, "user[address][city]", "Paris"}},
+		{`a\=b=c`, Item{ItemData, "a=b", "c"}},
+		{"empty=", Item{ItemData, "empty", ""}},
+	}
+
+	for _, tt := range tests {
+		item, ok := ParseItem(tt.arg)
+		if !ok {
+			t.Errorf("ParseItem(%q): expected ok", tt.arg)
+			continue
+		}
+		if item != tt.expected {
+			t.Errorf("ParseItem(%q): got %+v, want %+v", tt.arg, item, tt.expected)
+		}
+	}
+}
+
+func TestParseItemInvalid(t *testing.T) {
+	for _, arg := range []string{"plain", "=value", ":=1", ""} {
+		if _, ok := ParseItem(arg); ok {
+			t.Errorf("ParseItem(%q): expected not ok", arg)
+		}
+	}
+}
+
+func TestItemStringRoundTrip(t *testing.T) {
+	for _, arg := range []string{"name=John", "age:=42", `a\=b=c`, "avatar@photo.png"} {
+		item, _ := ParseItem(arg)
+		again, ok := ParseItem(item.String())
+		if !ok || again != item {
+			t.Errorf("round trip of %q: got %+v, want %+v", arg, again, item)
+		}
+	}
+}
+
+func TestBuildBodyJSON(t *testing.T) {
+	items := []Item{
+		{ItemData, "name", "John"},
+		{ItemRawJSON, "age", "42"},
+		{ItemRawJSON, "admin", "true"},
+		{ItemData, "user[address][city]", "Paris"},
+		{ItemData, "tags[]", "a"},
+		{ItemData, "tags[]", "b"},
+		{ItemData, "points[1][x]", "1"},
+	}
+
+	body, contentType, err := BuildBody(items, false, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != ContentTypeJSON {
+		t.Errorf("content type: got %q, want %q", contentType, ContentTypeJSON)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	expected := map[string]interface{}{
+		"name":   "John",
+		"age":    float64(42),
+		"admin":  true,
+		"user":   map[string]interface{}{"address": map[string]interface{}{"city": "Paris"}},
+		"tags":   []interface{}{"a", "b"},
+		"points": []interface{}{nil, map[string]interface{}{"x": "1"}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("body: got %v, want %v", got, expected)
+	}
+}
+
+func TestBuildBodyMergesRawJSON(t *testing.T) {
+	body, _, err := BuildBody([]Item{{ItemData, "name", "Jane"}}, false, `{"name":"John","age":30}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body != `{"age":30,"name":"Jane"}` {
+		t.Errorf("body: got %s", body)
+	}
+
+	if _, _, err := BuildBody([]Item{{ItemData, "name", "Jane"}}, false, `[1,2]`); err == nil {
+		t.Error("expected error merging into a non-object body")
+	}
+}
+
+func TestBuildBodyForm(t *testing.T) {
+	items := []Item{
+		{ItemData, "name", "John Doe"},
+		{ItemData, "tags[]", "a"},
+		{ItemData, "tags[]", "b"},
+	}
+
+	body, contentType, err := BuildBody(items, true, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != ContentTypeForm {
+		t.Errorf("content type: got %q, want %q", contentType, ContentTypeForm)
+	}
+
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		t.Fatalf("body is not form encoded: %v", err)
+	}
+	if values.Get("name") != "John Doe" {
+		t.Errorf("name: got %q", values.Get("name"))
+	}
+	if len(values["tags[]"]) != 2 {
+		t.Errorf("tags[]: got %v", values["tags[]"])
+	}
+
+	if _, _, err := BuildBody([]Item{{ItemRawJSON, "age", "42"}}, true, ""); err == nil {
+		t.Error("expected error for raw JSON item with --form")
+	}
+}
+
+func TestBuildBodyFiles(t *testing.T) {
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "bio.txt")
+	jsonPath := filepath.Join(dir, "meta.json")
+	if err := os.WriteFile(textPath, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(`{"k":[1]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []Item{
+		{ItemDataFile, "bio", textPath},
+		{ItemFile, "note", textPath},
+		{ItemRawJSONFile, "meta", jsonPath},
+		{ItemRawJSONFile, "typed", jsonPath + ";type=application/json"}, // The type is ignored, as with =@
+	}
+	body, _, err := BuildBody(items, false, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body != `{"bio":"hello","meta":{"k":[1]},"note":"hello","typed":{"k":[1]}}` {
+		t.Errorf("body: got %s", body)
+	}
+
+	if _, _, err := BuildBody([]Item{{ItemFile, "x", filepath.Join(dir, "missing")}}, false, ""); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestBuildBodyInvalidRawJSON(t *testing.T) {
+	if _, _, err := BuildBody([]Item{{ItemRawJSON, "age", "forty"}}, false, ""); err == nil {
+		t.Error("expected error for invalid raw JSON")
+	}
+}
+
+func TestBuildBodyArrayIndexLimit(t *testing.T) {
+	for _, key := range []string{"a[1001]", "a[999999999]", "a[0][99999999999999999999]"} {
+		if _, _, err := BuildBody([]Item{{ItemData, key, "1"}}, false, ""); err == nil {
+			t.Errorf("%s: expected error for a too large index", key)
+		}
+	}
+
+	body, _, err := BuildBody([]Item{{ItemData, "a[1000]", "1"}}, false, "")
+	if err != nil {
+		t.Fatalf("unexpected error at the limit: %v", err)
+	}
+	var got map[string][]interface{}
+	if err := json.Unmarshal([]byte(body), &got); err != nil || len(got["a"]) != 1001 {
+		t.Errorf("expected 1001 elements, got %d (%v)", len(got["a"]), err)
+	}
+}
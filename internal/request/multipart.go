@@ -0,0 +1,177 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MultipartBody streams data items as multipart/form-data. File parts are
+// copied from disk while the request is sent rather than buffered in memory.
+type MultipartBody struct {
+	items    []Item
+	boundary string
+}
+
+// NewMultipartBody validates items for a multipart body and checks that
+// every referenced file can be opened
+func NewMultipartBody(items []Item) (*MultipartBody, error) {
+	for _, item := range items {
+		switch item.Type {
+		case ItemQuery, ItemData:
+		case ItemFile, ItemDataFile:
+			path, _ := ParseFileSpec(item.Value)
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file for %s: %w", item.Key, err)
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("failed to read file for %s: %s is a directory", item.Key, path)
+			}
+		default:
+			return nil, fmt.Errorf("raw JSON item %s is not supported with --multipart", item.Key)
+		}
+	}
+
+	return &MultipartBody{
+		items:    items,
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}, nil
+}
+
+// ParseFileSpec splits a file item value like "photo.png;type=image/png"
+// into the path and an optional explicit content type
+func ParseFileSpec(value string) (string, string) {
+	if idx := strings.LastIndex(value, ";type="); idx >= 0 {
+		return value[:idx], value[idx+len(";type="):]
+	}
+	return value, ""
+}
+
+// ContentType returns the multipart Content-Type header including the boundary
+func (m *MultipartBody) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// ContentLength computes the encoded size from the part headers and file sizes
+// without reading any file contents
+func (m *MultipartBody) ContentLength() (int64, error) {
+	counter := &countingWriter{}
+	err := m.write(counter, func(w io.Writer, path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		counter.n += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// Reader returns a new reader over the encoded body. The body is produced
+// by a goroutine that starts on the first Read.
+func (m *MultipartBody) Reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	r := &multipartReader{pr: pr}
+	r.start = func() {
+		go func() {
+			pw.CloseWithError(m.write(pw, copyFile))
+		}()
+	}
+	return r
+}
+
+// write encodes every part to w; copyContent writes the contents of a file
+func (m *MultipartBody) write(w io.Writer, copyContent func(io.Writer, string) error) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, item := range m.items {
+		switch item.Type {
+		case ItemData:
+			if err := mw.WriteField(item.Key, item.Value); err != nil {
+				return err
+			}
+		case ItemDataFile:
+			path, _ := ParseFileSpec(item.Value)
+			part, err := mw.CreateFormField(item.Key)
+			if err != nil {
+				return err
+			}
+			if err := copyContent(part, path); err != nil {
+				return fmt.Errorf("failed to read file for %s: %w", item.Key, err)
+			}
+		case ItemFile:
+			path, contentType := ParseFileSpec(item.Value)
+			if contentType == "" {
+				contentType = mime.TypeByExtension(filepath.Ext(path))
+			}
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", multipart.FileContentDisposition(item.Key, filepath.Base(path)))
+			header.Set("Content-Type", contentType)
+			part, err := mw.CreatePart(header)
+			if err != nil {
+				return err
+			}
+			if err := copyContent(part, path); err != nil {
+				return fmt.Errorf("failed to read file for %s: %w", item.Key, err)
+			}
+		}
+	}
+
+	return mw.Close()
+}
+
+// copyFile streams a file's contents into w
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// multipartReader defers starting the encoder until the body is read,
+// so building a request that is never sent doesn't leak a goroutine
+type multipartReader struct {
+	pr    *io.PipeReader
+	start func()
+	once  sync.Once
+}
+
+func (r *multipartReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	return r.pr.Read(p)
+}
+
+func (r *multipartReader) Close() error {
+	return r.pr.Close()
+}
+
+// countingWriter counts bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
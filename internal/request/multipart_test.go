@@ -0,0 +1,166 @@
+package request
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFileSpec(t *testing.T) {
+	path, contentType := ParseFileSpec("photo.png;type=image/png")
+	if path != "photo.png" || contentType != "image/png" {
+		t.Errorf("got (%q, %q), want (photo.png, image/png)", path, contentType)
+	}
+
+	path, contentType = ParseFileSpec("report.pdf")
+	if path != "report.pdf" || contentType != "" {
+		t.Errorf("got (%q, %q), want (report.pdf, \"\")", path, contentType)
+	}
+}
+
+func TestMultipartBodyContentLength(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(filePath, []byte(strings.Repeat("x", 4096)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := NewMultipartBody([]Item{
+		{Type: ItemData, Key: "name", Value: "artifact"},
+		{Type: ItemFile, Key: "file", Value: filePath + ";type=application/x-custom"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	length, err := body.ContentLength()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reader := body.Reader()
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if int64(len(data)) != length {
+		t.Errorf("content length: computed %d, actual %d", length, len(data))
+	}
+}
+
+func TestMultipartBodyInvalidItems(t *testing.T) {
+	if _, err := NewMultipartBody([]Item{{Type: ItemRawJSON, Key: "age", Value: "1"}}); err == nil {
+		t.Error("expected error for raw JSON item")
+	}
+	if _, err := NewMultipartBody([]Item{{Type: ItemFile, Key: "f", Value: "/nonexistent/file"}}); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if _, err := NewMultipartBody([]Item{{Type: ItemFile, Key: "f", Value: t.TempDir()}}); err == nil {
+		t.Error("expected error for directory")
+	}
+}
+
+func TestMultipartBodyReaderClosedUnread(t *testing.T) {
+	body, err := NewMultipartBody([]Item{{Type: ItemData, Key: "a", Value: "b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Closing without reading must not block
+	if err := body.Reader().Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestExecutorMultipartUpload tests streaming a multipart upload to a server
+func TestExecutorMultipartUpload(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "photo.png")
+	content := strings.Repeat("png", 1000)
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotField, gotFile, gotFilename, gotPartType string
+	var gotLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/form-data" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			switch part.FormName() {
+			case "title":
+				gotField = string(data)
+			case "image":
+				gotFile = string(data)
+				gotFilename = part.FileName()
+				gotPartType = part.Header.Get("Content-Type")
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{
+		Method:  "POST",
+		URL:     server.URL,
+		Headers: map[string]string{"Content-Type": "text/plain"},
+		Items: []Item{
+			{Type: ItemData, Key: "title", Value: "holiday"},
+			{Type: ItemFile, Key: "image", Value: filePath},
+		},
+		Multipart: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	if gotLength <= int64(len(content)) {
+		t.Errorf("expected Content-Length to be set, got %d", gotLength)
+	}
+	if gotField != "holiday" {
+		t.Errorf("title field: got %q", gotField)
+	}
+	if gotFile != content {
+		t.Errorf("file part: got %d bytes, want %d", len(gotFile), len(content))
+	}
+	if gotFilename != "photo.png" {
+		t.Errorf("filename: got %q", gotFilename)
+	}
+	if gotPartType != "image/png" {
+		t.Errorf("part Content-Type: got %q", gotPartType)
+	}
+}
+
+// TestBuilderMultipartWithRawBody tests that a raw body can't be combined with multipart
+func TestBuilderMultipartWithRawBody(t *testing.T) {
+	_, err := NewBuilder(&Request{
+		Method:    "POST",
+		URL:       "https://api.example.com/upload",
+		Body:      "raw",
+		Multipart: true,
+	}).Build()
+	if err == nil {
+		t.Error("expected error for raw body with multipart")
+	}
+}
@@ -0,0 +1,118 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyOptions routes requests through an HTTP or SOCKS5 proxy
+type ProxyOptions struct {
+	URL     string   // http://, https://, socks5:// or socks5h://, with optional user:password@
+	NoProxy []string // Hosts reached directly: "*", "example.com" (and subdomains), ".internal", "host:port", IPs and CIDRs
+}
+
+// proxySchemes are the proxy URL schemes net/http can dial
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// ParseProxyURL parses a proxy URL, defaulting to http:// when the scheme
+// is missing as curl does
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !proxySchemes[u.Scheme] {
+		return nil, fmt.Errorf("unsupported proxy scheme: %s (expected http, https, socks5 or socks5h)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: %s has no host", raw)
+	}
+	return u, nil
+}
+
+// proxyFunc returns the transport's proxy selector. Without a proxy URL
+// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply, and NoProxy
+// entries are skipped in either case. HTTPS requests are tunnelled with
+// CONNECT; credentials in the URL are sent as Proxy-Authorization or as
+// SOCKS5 username/password authentication.
+func proxyFunc(opts *ProxyOptions) (func(*http.Request) (*url.URL, error), error) {
+	var proxyURL *url.URL
+	if opts.URL != "" {
+		u, err := ParseProxyURL(opts.URL)
+		if err != nil {
+			return nil, err
+		}
+		proxyURL = u
+	}
+
+	return func(r *http.Request) (*url.URL, error) {
+		if bypassProxy(r.URL, opts.NoProxy) {
+			return nil, nil
+		}
+		if proxyURL == nil {
+			return http.ProxyFromEnvironment(r)
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// bypassProxy reports whether a URL matches a no-proxy entry
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		// An optional port narrows the entry; bracketed IPv6 needs SplitHostPort
+		entryHost := entry
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entryHost = h
+		}
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+package request
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHTTPProxy starts a forwarding proxy that also tunnels CONNECT. When
+// user is set, requests must carry matching Basic Proxy-Authorization.
+func newHTTPProxy(t *testing.T, user, password string) (*httptest.Server, *int32) {
+	t.Helper()
+	var proxied int32
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user != "" && r.Header.Get("Proxy-Authorization") != want {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		atomic.AddInt32(&proxied, 1)
+
+		if r.Method == http.MethodConnect {
+			target, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				target.Close()
+				return
+			}
+			rw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+			rw.Flush()
+			pipe(conn, target)
+			return
+		}
+
+		out := r.Clone(r.Context())
+		out.RequestURI = ""
+		out.Header.Del("Proxy-Authorization")
+		resp, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Via", "1.1 test-proxy")
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &proxied
+}
+
+// newSOCKS5Proxy starts a SOCKS5 proxy supporting CONNECT, with
+// username/password authentication when user is set
+func newSOCKS5Proxy(t *testing.T, user, password string) (string, *int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var proxied int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				target, err := socks5Handshake(conn, user, password)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				atomic.AddInt32(&proxied, 1)
+				pipe(conn, target)
+			}()
+		}
+	}()
+	return listener.Addr().String(), &proxied
+}
+
+// socks5Handshake negotiates authentication and a CONNECT request, and
+// returns the connection to the target
+func socks5Handshake(conn net.Conn, user, password string) (net.Conn, error) {
+	r := bufio.NewReader(conn)
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, make([]byte, header[1])); err != nil {
+		return nil, err
+	}
+
+	if user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		version, _ := r.ReadByte()
+		gotUser, _ := readSOCKSString(r)
+		gotPassword, _ := readSOCKSString(r)
+		if version != 1 || gotUser != user || gotPassword != password {
+			conn.Write([]byte{1, 1})
+			return nil, io.ErrUnexpectedEOF
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(r, request); err != nil {
+		return nil, err
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		host, _ = readSOCKSString(r)
+	case 4:
+		ip := make([]byte, 16)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBytes); err != nil {
+		return nil, err
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes)))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return nil, err
+	}
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	return target, nil
+}
+
+// readSOCKSString reads a length-prefixed string
+func readSOCKSString(r *bufio.Reader) (string, error) {
+	n, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+// pipe copies between two connections until either side closes
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(a, b); done <- struct{}{} }()
+	go func() { io.Copy(b, a); done <- struct{}{} }()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// newEchoServer replies with the request path
+func newEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "path="+r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecutorHTTPProxy(t *testing.T) {
+	proxy, proxied := newHTTPProxy(t, "alice", "s3cret")
+	target := newEchoServer(t)
+	executor := NewExecutor(5 * time.Second)
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("alice", "s3cret")
+	resp, err := executor.Execute(&Request{Method: "GET", URL: target.URL + "/users", Proxy: &ProxyOptions{URL: proxyURL.String()}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body) != "path=/users" || resp.Headers["Via"][0] != "1.1 test-proxy" {
+		t.Errorf("expected the response through the proxy, got %q %v", resp.Body, resp.Headers)
+	}
+	if atomic.LoadInt32(proxied) != 1 {
+		t.Errorf("expected 1 proxied request, got %d", atomic.LoadInt32(proxied))
+	}
+
+	// Without credentials the proxy's 407 is returned
+	resp, err = executor.Execute(&Request{Method: "GET", URL: target.URL, Proxy: &ProxyOptions{URL: proxy.URL}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusProxyAuthRequired {
+		t.Errorf("expected 407 without proxy credentials, got %d", resp.StatusCode)
+	}
+}
+
+func TestExecutorHTTPSProxyConnect(t *testing.T) {
+	proxy, proxied := newHTTPProxy(t, "", "")
+	server, caFile := newTLSServer(t, t.TempDir(), nil)
+	executor := NewExecutor(5 * time.Second)
+
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    server.URL,
+		TLS:    &TLSOptions{CAFiles: []string{caFile}},
+		Proxy:  &ProxyOptions{URL: proxy.URL},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body) != "client=none" {
+		t.Errorf("expected the TLS server's response, got %q", resp.Body)
+	}
+	if atomic.LoadInt32(proxied) != 1 {
+		t.Errorf("expected a CONNECT tunnel, got %d proxied requests", atomic.LoadInt32(proxied))
+	}
+}
+
+func TestExecutorSOCKS5Proxy(t *testing.T) {
+	addr, proxied := newSOCKS5Proxy(t, "bob", "hunter2")
+	target := newEchoServer(t)
+	executor := NewExecutor(5 * time.Second)
+
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    target.URL + "/socks",
+		Proxy:  &ProxyOptions{URL: "socks5://bob:hunter2@" + addr},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body) != "path=/socks" || atomic.LoadInt32(proxied) != 1 {
+		t.Errorf("expected the response through the SOCKS5 proxy, got %q after %d", resp.Body, atomic.LoadInt32(proxied))
+	}
+
+	if _, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    target.URL,
+		Proxy:  &ProxyOptions{URL: "socks5://bob:wrong@" + addr},
+	}); err == nil {
+		t.Error("expected error with wrong SOCKS5 credentials")
+	}
+}
+
+func TestExecutorNoProxy(t *testing.T) {
+	proxy, proxied := newHTTPProxy(t, "", "")
+	target := newEchoServer(t)
+	executor := NewExecutor(5 * time.Second)
+
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    target.URL,
+		Proxy:  &ProxyOptions{URL: proxy.URL, NoProxy: []string{"127.0.0.0/8"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body) != "path=/" || atomic.LoadInt32(proxied) != 0 {
+		t.Errorf("expected a direct request, got %q after %d proxied", resp.Body, atomic.LoadInt32(proxied))
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	tests := []struct {
+		url     string
+		noProxy []string
+		want    bool
+	}{
+		{"http://api.example.com/x", []string{"example.com"}, true},
+		{"http://example.com/x", []string{".example.com"}, true},
+		{"http://api.example.com/x", []string{"*.example.com"}, true},
+		{"http://badexample.com/x", []string{"example.com"}, false},
+		{"http://anything.test/x", []string{"*"}, true},
+		{"http://10.1.2.3/x", []string{"10.0.0.0/8"}, true},
+		{"http://192.168.1.1/x", []string{"10.0.0.0/8"}, false},
+		{"http://[::1]:8080/x", []string{"::1"}, true},
+		{"http://localhost:8080/x", []string{"localhost:8080"}, true},
+		{"http://localhost:9090/x", []string{"localhost:8080"}, false},
+		{"https://internal.corp/x", []string{"internal.corp:443"}, true},
+		{"http://api.example.com/x", nil, false},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := bypassProxy(u, tt.noProxy); got != tt.want {
+			t.Errorf("%s with %v: got %v, want %v", tt.url, tt.noProxy, got, tt.want)
+		}
+	}
+}
+
+func TestParseProxyURL(t *testing.T) {
+	u, err := ParseProxyURL("proxy.corp:3128")
+	if err != nil || u.Scheme != "http" || u.Host != "proxy.corp:3128" {
+		t.Errorf("expected http://proxy.corp:3128, got %v, %v", u, err)
+	}
+	if u, err := ParseProxyURL("SOCKS5H://user:pw@bastion:1080"); err != nil || u.Scheme != "socks5h" || u.User.Username() != "user" {
+		t.Errorf("unexpected socks5h URL %v, %v", u, err)
+	}
+	for _, raw := range []string{"ftp://proxy:21", "http://"} {
+		if _, err := ParseProxyURL(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
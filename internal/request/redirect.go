@@ -0,0 +1,113 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gosh/internal/auth"
+)
+
+// DefaultMaxRedirects is how many redirects are followed when a request
+// doesn't set a limit
+const DefaultMaxRedirects = 10
+
+// checkRedirect returns the redirect policy for a request. net/http decides
+// the method and body of each hop: 301, 302 and 303 turn other methods into
+// a body-less GET, while 307 and 308 resend the method and body. Once a hop
+// leaves the original scheme and host, credentials are stripped and the
+// auth preset is no longer applied; same-origin hops are signed again.
+// Followed redirects and the hops they lead to are shown to req.Wire.
+func checkRedirect(req *Request) func(*http.Request, []*http.Request) error {
+	return func(next *http.Request, via []*http.Request) error {
+		if req.NoFollow {
+			return http.ErrUseLastResponse
+		}
+		max := req.MaxRedirects
+		if max <= 0 {
+			max = DefaultMaxRedirects
+		}
+		if len(via) > max {
+			return fmt.Errorf("stopped after %d redirects", max)
+		}
+
+		if crossedOrigin(next, via) {
+			next.Header.Del("Authorization")
+			next.Header.Del("Proxy-Authorization")
+			next.Header.Del("Cookie")
+			if req.Auth != nil {
+				for _, name := range req.Auth.HeaderNames() {
+					next.Header.Del(name)
+				}
+			}
+		} else if err := reapplyAuth(next, req.Auth); err != nil {
+			return err
+		}
+
+		if req.Wire != nil {
+			req.Wire.Response(next.Response)
+			req.Wire.Request(next)
+		}
+		return nil
+	}
+}
+
+// reapplyAuth signs a same-origin hop for its new URL and method
+func reapplyAuth(next *http.Request, preset *auth.AuthPreset) error {
+	if preset == nil {
+		return nil
+	}
+	for _, name := range preset.HeaderNames() {
+		next.Header.Del(name)
+	}
+	return preset.Apply(next)
+}
+
+// crossedOrigin reports whether any hop, including next, went to a
+// different scheme, host or port than the first request
+func crossedOrigin(next *http.Request, via []*http.Request) bool {
+	first := via[0].URL
+	for _, r := range via[1:] {
+		if !sameOrigin(first, r.URL) {
+			return true
+		}
+	}
+	return !sameOrigin(first, next.URL)
+}
+
+// sameOrigin compares the scheme, host and port of two URLs
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(canonicalHost(a), canonicalHost(b))
+}
+
+// canonicalHost returns host:port with the scheme's default port filled in
+func canonicalHost(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(u.Scheme, "https") {
+			port = "443"
+		}
+	}
+	return u.Hostname() + ":" + port
+}
+
+// redirectChain lists the redirects that led to a response, oldest first
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		hop := r.Response
+		chain = append(chain, Redirect{
+			Method:     hop.Request.Method,
+			URL:        hop.Request.URL.String(),
+			StatusCode: hop.StatusCode,
+			Status:     hop.Status,
+			Location:   hop.Header.Get("Location"),
+		})
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
@@ -0,0 +1,200 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosh/internal/auth"
+)
+
+// newRedirectServer redirects /from with the given status to /to, which
+// echoes the method, body and Authorization header it received
+func newRedirectServer(t *testing.T, status int, to string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/from", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, to, status)
+	})
+	mux.HandleFunc("/to", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Method+" "+string(body)+" "+r.Header.Get("Authorization"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestRedirectMethodRules tests that 301-303 switch to GET without a body
+// while 307 and 308 resend the method and body
+func TestRedirectMethodRules(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusMovedPermanently, "GET  "},
+		{http.StatusFound, "GET  "},
+		{http.StatusSeeOther, "GET  "},
+		{http.StatusTemporaryRedirect, `POST {"a":1} `},
+		{http.StatusPermanentRedirect, `POST {"a":1} `},
+	}
+
+	for _, tt := range tests {
+		server := newRedirectServer(t, tt.status, "/to")
+		executor := NewExecutor(5 * time.Second)
+		resp, err := executor.Execute(&Request{Method: "POST", URL: server.URL + "/from", Body: `{"a":1}`})
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", tt.status, err)
+		}
+		if string(resp.Body) != tt.want {
+			t.Errorf("%d: got %q, want %q", tt.status, resp.Body, tt.want)
+		}
+		if len(resp.Redirects) != 1 || resp.Redirects[0].StatusCode != tt.status || resp.Redirects[0].Location != "/to" {
+			t.Errorf("%d: unexpected redirect chain %+v", tt.status, resp.Redirects)
+		}
+	}
+}
+
+// TestRedirectChain tests that every hop is recorded in order
+func TestRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "done")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{Method: "GET", URL: server.URL + "/a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.Redirects) != 2 {
+		t.Fatalf("expected 2 redirects, got %+v", resp.Redirects)
+	}
+	first, second := resp.Redirects[0], resp.Redirects[1]
+	if first.URL != server.URL+"/a" || first.StatusCode != http.StatusFound || first.Location != "/b" {
+		t.Errorf("unexpected first hop %+v", first)
+	}
+	if second.URL != server.URL+"/b" || second.StatusCode != http.StatusMovedPermanently || second.Location != "/c" {
+		t.Errorf("unexpected second hop %+v", second)
+	}
+	if string(resp.Body) != "done" {
+		t.Errorf("expected the final body, got %q", resp.Body)
+	}
+}
+
+// TestRedirectNoFollow tests that the redirect response itself is returned
+func TestRedirectNoFollow(t *testing.T) {
+	server := newRedirectServer(t, http.StatusFound, "/to")
+	executor := NewExecutor(5 * time.Second)
+
+	resp, err := executor.Execute(&Request{Method: "GET", URL: server.URL + "/from", NoFollow: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusFound || resp.Headers["Location"][0] != "/to" {
+		t.Errorf("expected the 302, got %d %v", resp.StatusCode, resp.Headers)
+	}
+	if len(resp.Redirects) != 0 {
+		t.Errorf("expected no redirects, got %+v", resp.Redirects)
+	}
+}
+
+// TestRedirectMaxRedirects tests that a redirect loop stops at the limit
+func TestRedirectMaxRedirects(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(5 * time.Second)
+	_, err := executor.Execute(&Request{Method: "GET", URL: server.URL, MaxRedirects: 3})
+	if err == nil || !strings.Contains(err.Error(), "stopped after 3 redirects") {
+		t.Fatalf("expected the redirect limit error, got %v", err)
+	}
+	if hits != 4 {
+		t.Errorf("expected the original request and 3 redirects, got %d requests", hits)
+	}
+}
+
+// TestRedirectStripsAuthAcrossHosts tests that credentials aren't sent to
+// another host but are kept on the same host
+func TestRedirectStripsAuthAcrossHosts(t *testing.T) {
+	var gotAuth, gotKey string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("X-Api-Key")
+	}))
+	defer other.Close()
+
+	// Both servers listen on 127.0.0.1; only the port differs
+	origin := newRedirectServer(t, http.StatusFound, other.URL+"/to")
+	executor := NewExecutor(5 * time.Second)
+
+	bearer := &auth.AuthPreset{Name: "api", Type: "bearer", Token: "secret"}
+	if _, err := executor.Execute(&Request{Method: "GET", URL: origin.URL + "/from", Auth: bearer}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAuth != "" {
+		t.Errorf("expected Authorization to be stripped, got %q", gotAuth)
+	}
+
+	custom := &auth.AuthPreset{Name: "key", Type: "custom", Header: "X-Api-Key", Value: "secret"}
+	if _, err := executor.Execute(&Request{Method: "GET", URL: origin.URL + "/from", Auth: custom}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotKey != "" {
+		t.Errorf("expected X-Api-Key to be stripped, got %q", gotKey)
+	}
+
+	same := newRedirectServer(t, http.StatusFound, "/to")
+	resp, err := executor.Execute(&Request{Method: "GET", URL: same.URL + "/from", Auth: bearer})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(string(resp.Body), "Bearer secret") {
+		t.Errorf("expected Authorization on a same-host hop, got %q", resp.Body)
+	}
+}
+
+// wireLog records what a WireObserver is shown
+type wireLog []string
+
+func (l *wireLog) Request(r *http.Request)   { *l = append(*l, "> "+r.Method+" "+r.URL.Path) }
+func (l *wireLog) Response(r *http.Response) { *l = append(*l, "< "+r.Status) }
+
+func TestWireObserverSeesRedirectHops(t *testing.T) {
+	server := newRedirectServer(t, http.StatusFound, "/to")
+	executor := NewExecutor(5 * time.Second)
+
+	var log wireLog
+	if _, err := executor.Execute(&Request{Method: "GET", URL: server.URL + "/from", Wire: &log}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "> GET /from|< 302 Found|> GET /to|< 200 OK"
+	if got := strings.Join(log, "|"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// An unfollowed redirect is the final response, shown once
+	log = nil
+	if _, err := executor.Execute(&Request{Method: "GET", URL: server.URL + "/from", NoFollow: true, Wire: &log}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(log, "|"); got != "> GET /from|< 302 Found" {
+		t.Errorf("got %s without following", got)
+	}
+}
@@ -0,0 +1,192 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Retry defaults, used when a RetryPolicy leaves a field empty
+const (
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultRetryMaxBackoff = 30 * time.Second
+)
+
+// DefaultRetryStatuses are the status codes retried by default
+var DefaultRetryStatuses = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// idempotentMethods are retried when a policy doesn't list methods
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"}
+
+// sleep and jitter are replaced in tests
+var (
+	sleep  = time.Sleep
+	jitter = func(d time.Duration) time.Duration {
+		// Equal jitter: half the delay, plus a random part of the other half
+		half := d / 2
+		return half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+)
+
+// RetryPolicy decides whether a failed attempt is sent again
+type RetryPolicy struct {
+	Retries           int           // Attempts after the first one
+	Statuses          []int         // Status codes to retry; DefaultRetryStatuses if empty
+	SkipNetworkErrors bool          // Don't retry connection failures and timeouts
+	Methods           []string      // Methods to retry; idempotent methods if empty
+	Backoff           time.Duration // Delay before the first retry, doubled each time
+	MaxBackoff        time.Duration // Upper bound on the delay
+}
+
+// Attempt is the outcome of one try at sending a request
+type Attempt struct {
+	StatusCode int
+	Status     string
+	Error      string        // Set when no response was received
+	Duration   time.Duration // Time until the response headers arrived
+	Wait       time.Duration // Delay before the next attempt; 0 for the last
+}
+
+// attemptFunc sends the request once
+type attemptFunc func() (*http.Response, error)
+
+// run calls attempt until it succeeds, the outcome isn't retryable or the
+// retries are used up, and records every attempt. Responses that are
+// retried are drained and closed.
+func (p *RetryPolicy) run(method string, attempt attemptFunc) (*http.Response, []Attempt, error) {
+	var attempts []Attempt
+	for n := 0; ; n++ {
+		start := time.Now()
+		httpResp, err := attempt()
+		record := Attempt{Duration: time.Since(start)}
+		if err != nil {
+			record.Error = err.Error()
+		} else {
+			record.StatusCode = httpResp.StatusCode
+			record.Status = httpResp.Status
+		}
+
+		if p == nil || n >= p.Retries || !p.allowsMethod(method) || !p.retryable(httpResp, err) {
+			attempts = append(attempts, record)
+			if err != nil && n > 0 {
+				err = fmt.Errorf("%w (after %d attempts)", err, n+1)
+			}
+			return httpResp, attempts, err
+		}
+
+		record.Wait = p.delay(n, httpResp)
+		attempts = append(attempts, record)
+		if httpResp != nil {
+			io.Copy(io.Discard, io.LimitReader(httpResp.Body, 64<<10))
+			httpResp.Body.Close()
+		}
+		sleep(record.Wait)
+	}
+}
+
+// allowsMethod reports whether requests with method may be retried
+func (p *RetryPolicy) allowsMethod(method string) bool {
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = idempotentMethods
+	}
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether an attempt's outcome is worth retrying
+func (p *RetryPolicy) retryable(httpResp *http.Response, err error) bool {
+	if err != nil {
+		return !p.SkipNetworkErrors && isNetworkError(err)
+	}
+	statuses := p.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+	for _, status := range statuses {
+		if httpResp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before retry n+1: Retry-After on a 429 or
+// 503, otherwise jittered exponential backoff, both capped at MaxBackoff
+func (p *RetryPolicy) delay(n int, httpResp *http.Response) time.Duration {
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+
+	if httpResp != nil && (httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode == http.StatusServiceUnavailable) {
+		if wait, ok := parseRetryAfter(httpResp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(wait, maxBackoff)
+		}
+	}
+
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	wait := backoff
+	for i := 0; i < n && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return jitter(min(wait, maxBackoff))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+// isNetworkError reports whether err means the request didn't get a
+// response, as opposed to being refused by policy such as TLS verification
+// or the redirect limit
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,187 @@
+package request
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withInstantRetries records retry delays instead of sleeping and turns
+// off jitter
+func withInstantRetries(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	oldSleep, oldJitter := sleep, jitter
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	jitter = func(d time.Duration) time.Duration { return d }
+	t.Cleanup(func() { sleep, jitter = oldSleep, oldJitter })
+	return &waits
+}
+
+// newFlakyServer fails with status for the first failures requests
+func newFlakyServer(t *testing.T, failures, status int, header http.Header) (*httptest.Server, *int) {
+	t.Helper()
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+// TestRetryStatus tests that retryable statuses are retried with
+// exponential backoff and every attempt is recorded
+func TestRetryStatus(t *testing.T) {
+	waits := withInstantRetries(t)
+	server, hits := newFlakyServer(t, 2, http.StatusBadGateway, nil)
+
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &RetryPolicy{Retries: 3, Backoff: 100 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || *hits != 3 {
+		t.Errorf("expected success on the third attempt, got %d after %d", resp.StatusCode, *hits)
+	}
+	if len(resp.Attempts) != 3 || resp.Attempts[0].StatusCode != http.StatusBadGateway || resp.Attempts[2].StatusCode != http.StatusOK {
+		t.Errorf("unexpected attempts %+v", resp.Attempts)
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(*waits) != 2 || (*waits)[0] != expected[0] || (*waits)[1] != expected[1] {
+		t.Errorf("expected backoff %v, got %v", expected, *waits)
+	}
+	if resp.Attempts[0].Wait != expected[0] || resp.Attempts[2].Wait != 0 {
+		t.Errorf("expected waits on the attempts, got %+v", resp.Attempts)
+	}
+}
+
+// TestRetryGivesUp tests that the last response is returned when retries
+// run out
+func TestRetryGivesUp(t *testing.T) {
+	withInstantRetries(t)
+	server, hits := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{Method: "GET", URL: server.URL, Retry: &RetryPolicy{Retries: 2}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || *hits != 3 || len(resp.Attempts) != 3 {
+		t.Errorf("expected 3 attempts ending in 503, got %d after %d", resp.StatusCode, *hits)
+	}
+}
+
+// TestRetryIdempotentOnly tests that POST isn't retried unless listed
+func TestRetryIdempotentOnly(t *testing.T) {
+	withInstantRetries(t)
+	server, hits := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	executor := NewExecutor(5 * time.Second)
+
+	resp, err := executor.Execute(&Request{Method: "POST", URL: server.URL, Body: "{}", Retry: &RetryPolicy{Retries: 2}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || *hits != 1 {
+		t.Errorf("expected POST to be sent once, got %d after %d", resp.StatusCode, *hits)
+	}
+
+	resp, err = executor.Execute(&Request{
+		Method: "POST",
+		URL:    server.URL,
+		Body:   "{}",
+		Retry:  &RetryPolicy{Retries: 2, Methods: []string{"post"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected POST to be retried when allowed, got %d", resp.StatusCode)
+	}
+}
+
+// TestRetryAfter tests that Retry-After on a 429 replaces the backoff and
+// is capped at MaxBackoff
+func TestRetryAfter(t *testing.T) {
+	waits := withInstantRetries(t)
+	server, _ := newFlakyServer(t, 2, http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}})
+
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &RetryPolicy{Retries: 2, Backoff: time.Millisecond, MaxBackoff: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected success, got %d", resp.StatusCode)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 5*time.Second {
+		t.Errorf("expected Retry-After capped at 5s, got %v", *waits)
+	}
+}
+
+// TestRetryNetworkError tests that refused connections are retried unless
+// network errors are skipped
+func TestRetryNetworkError(t *testing.T) {
+	withInstantRetries(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	executor := NewExecutor(5 * time.Second)
+	_, err = executor.Execute(&Request{Method: "GET", URL: "http://" + addr, Retry: &RetryPolicy{Retries: 2}})
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("expected 3 failed attempts, got %v", err)
+	}
+
+	_, err = executor.Execute(&Request{Method: "GET", URL: "http://" + addr, Retry: &RetryPolicy{Retries: 2, SkipNetworkErrors: true}})
+	if err == nil || strings.Contains(err.Error(), "attempts") {
+		t.Errorf("expected a single failed attempt, got %v", err)
+	}
+}
+
+// TestParseRetryAfter tests both Retry-After forms
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Sun, 01 Mar 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 01 Mar 2026 11:00:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q: got %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
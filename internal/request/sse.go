@@ -0,0 +1,218 @@
+package request
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Content types of responses that are rendered as they arrive
+const (
+	ContentTypeEventStream = "text/event-stream"
+	ContentTypeNDJSON      = "application/x-ndjson"
+)
+
+// DefaultRetry is the reconnection delay used until the server sends a retry field
+const DefaultRetry = 3 * time.Second
+
+// DefaultMaxReconnects is the number of consecutive failed reconnection
+// attempts after which an event stream gives up
+const DefaultMaxReconnects = 5
+
+// IsStreamContentType reports whether a Content-Type or Accept value names a
+// streaming format (server-sent events or newline-delimited JSON)
+func IsStreamContentType(value string) bool {
+	for _, part := range strings.Split(value, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case ContentTypeEventStream, ContentTypeNDJSON, "application/jsonl", "application/json-seq":
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a single server-sent event
+type Event struct {
+	ID    string // last event ID, which carries over from earlier events
+	Event string // event type; empty means "message"
+	Data  string
+}
+
+// EventReader parses server-sent events from a text/event-stream body
+type EventReader struct {
+	r      *bufio.Reader
+	lastID string
+	retry  time.Duration
+	start  bool
+}
+
+// NewEventReader creates a reader over an event stream
+func NewEventReader(r io.Reader) *EventReader {
+	return &EventReader{r: bufio.NewReader(r), start: true}
+}
+
+// Next returns the next event. It returns io.EOF when the stream ends
+// cleanly; an event cut off by the end of the stream is discarded.
+func (e *EventReader) Next() (*Event, error) {
+	var data strings.Builder
+	var eventType string
+	hasData := false
+
+	for {
+		line, err := e.r.ReadString('\n')
+		if err != nil {
+			// A final line without a newline never completes an event
+			return nil, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if e.start {
+			line = strings.TrimPrefix(line, "\ufeff")
+			e.start = false
+		}
+
+		if line == "" {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			return &Event{
+				ID:    e.lastID,
+				Event: eventType,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+			}, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				e.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				e.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// LastEventID returns the most recent event ID sent by the server
+func (e *EventReader) LastEventID() string {
+	return e.lastID
+}
+
+// EventStream reads server-sent events from a response and reconnects with a
+// Last-Event-ID header when the connection drops before the stream ends
+type EventStream struct {
+	executor      *Executor
+	req           *Request
+	body          io.ReadCloser
+	reader        *EventReader
+	retry         time.Duration
+	failures      int
+	MaxReconnects int
+}
+
+// NewEventStream wraps the body of a response to req that was opened with executor
+func NewEventStream(executor *Executor, req *Request, body io.ReadCloser) *EventStream {
+	return &EventStream{
+		executor:      executor,
+		req:           req,
+		body:          body,
+		reader:        NewEventReader(body),
+		retry:         DefaultRetry,
+		MaxReconnects: DefaultMaxReconnects,
+	}
+}
+
+// Next returns the next event, reconnecting as needed. It returns io.EOF
+// when the server closes the stream cleanly.
+func (s *EventStream) Next() (*Event, error) {
+	for {
+		event, err := s.reader.Next()
+		if err == nil {
+			s.failures = 0
+			return event, nil
+		}
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+
+		if err := s.reconnect(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reconnect reopens the stream after the server's retry delay, resuming
+// from the last event ID seen
+func (s *EventStream) reconnect(cause error) error {
+	if s.reader.retry > 0 {
+		s.retry = s.reader.retry
+	}
+	lastID := s.reader.LastEventID()
+	s.body.Close()
+
+	for {
+		if s.failures >= s.MaxReconnects {
+			return fmt.Errorf("event stream lost after %d reconnection attempts: %w", s.failures, cause)
+		}
+		s.failures++
+		time.Sleep(s.retry)
+
+		req := *s.req
+		req.Headers = make(map[string]string, len(s.req.Headers)+1)
+		for key, val := range s.req.Headers {
+			req.Headers[key] = val
+		}
+		if lastID != "" {
+			req.Headers["Last-Event-ID"] = lastID
+		}
+
+		resp, body, err := s.executor.Open(&req)
+		if err != nil {
+			cause = err
+			continue
+		}
+
+		// A 204 tells the client to stop reconnecting
+		if resp.StatusCode == http.StatusNoContent {
+			body.Close()
+			return io.EOF
+		}
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(http.Header(resp.Headers).Get("Content-Type"), ContentTypeEventStream) {
+			body.Close()
+			return fmt.Errorf("event stream reconnect failed: %s", resp.Status)
+		}
+
+		s.body = body
+		s.reader = NewEventReader(body)
+		s.reader.lastID = lastID
+		return nil
+	}
+}
+
+// Close closes the current connection
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
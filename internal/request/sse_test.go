@@ -0,0 +1,152 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventReader(t *testing.T) {
+	stream := "\ufeff: comment\n" +
+		"id: 1\n" +
+		"event: update\n" +
+		"data: {\"a\":1}\n" +
+		"\n" +
+		"data: line one\r\n" +
+		"data:line two\r\n" +
+		"\r\n" +
+		"retry: 250\n" +
+		"id: 7\n" +
+		"\n" +
+		"data: after id\n" +
+		"\n" +
+		"data: incomplete"
+
+	reader := NewEventReader(strings.NewReader(stream))
+
+	expected := []Event{
+		{ID: "1", Event: "update", Data: `{"a":1}`},
+		{ID: "1", Data: "line one\nline two"},
+		{ID: "7", Data: "after id"},
+	}
+	for i, want := range expected {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatalf("event %d: unexpected error: %v", i, err)
+		}
+		if *got != want {
+			t.Errorf("event %d: got %+v, want %+v", i, *got, want)
+		}
+	}
+
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF for incomplete event, got %v", err)
+	}
+	if reader.retry != 250*time.Millisecond {
+		t.Errorf("retry: got %v, want 250ms", reader.retry)
+	}
+}
+
+func TestIsStreamContentType(t *testing.T) {
+	tests := map[string]bool{
+		"text/event-stream":                   true,
+		"text/event-stream; charset=utf-8":    true,
+		"application/x-ndjson":                true,
+		"application/json, text/event-stream": true,
+		"application/json":                    false,
+		"":                                    false,
+	}
+	for value, expected := range tests {
+		if got := IsStreamContentType(value); got != expected {
+			t.Errorf("IsStreamContentType(%q): got %v, want %v", value, got, expected)
+		}
+	}
+}
+
+// TestEventStreamReconnect tests resuming a dropped stream with Last-Event-ID
+func TestEventStreamReconnect(t *testing.T) {
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		if r.Header.Get("Last-Event-ID") == "" {
+			_, _ = io.WriteString(w, "retry: 10\nid: 1\ndata: first\n\n")
+			w.(http.Flusher).Flush()
+			// Drop the connection mid-stream
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		_, _ = io.WriteString(w, "id: 2\ndata: second\n\n")
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(time.Second)
+	req := &Request{Method: "GET", URL: server.URL, Headers: map[string]string{}}
+	_, body, err := executor.Open(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stream := NewEventStream(executor, req, body)
+	defer stream.Close()
+
+	var data []string
+	for {
+		event, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data = append(data, event.Data)
+	}
+
+	if strings.Join(data, ",") != "first,second" {
+		t.Errorf("events: got %v", data)
+	}
+	if len(lastEventIDs) != 2 || lastEventIDs[1] != "1" {
+		t.Errorf("Last-Event-ID headers: got %q", lastEventIDs)
+	}
+	if _, ok := req.Headers["Last-Event-ID"]; ok {
+		t.Error("reconnect should not modify the original request headers")
+	}
+}
+
+// TestEventStreamGivesUp tests that reconnection stops after MaxReconnects
+func TestEventStreamGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "retry: 1\ndata: x\n\n")
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+
+	executor := NewExecutor(time.Second)
+	req := &Request{Method: "GET", URL: server.URL}
+	_, body, err := executor.Open(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stream := NewEventStream(executor, req, body)
+	stream.MaxReconnects = 2
+
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server.Close()
+
+	if _, err := stream.Next(); err == nil || err == io.EOF {
+		t.Errorf("expected reconnection error, got %v", err)
+	}
+}
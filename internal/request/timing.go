@@ -0,0 +1,124 @@
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks down the final request of an exchange, after any redirects
+// or retries. Phases that didn't happen, such as DNS and connecting on a
+// reused connection, are zero.
+type Timing struct {
+	DNS        time.Duration // Resolving the host name
+	Connect    time.Duration // TCP connect, to the proxy when there is one
+	TLS        time.Duration // TLS handshake
+	Send       time.Duration // Connection ready until the request was written
+	TTFB       time.Duration // Request written until the first response byte
+	Transfer   time.Duration // First response byte until the body was read
+	Total      time.Duration // Waiting for a connection until the body was read
+	RemoteAddr string        // Address of the server or proxy
+	Reused     bool          // The connection came from the pool
+}
+
+// timingCollector records httptrace events. Every connection lookup starts
+// over, so redirected and replayed requests report their final hop.
+type timingCollector struct {
+	mu     sync.Mutex
+	events traceEvents
+}
+
+// traceEvents are the timestamps of one connection lookup and request
+type traceEvents struct {
+	getConn, gotConn         time.Time
+	dnsStart, dnsDone        time.Time
+	connectStart, connectEnd time.Time
+	tlsStart, tlsDone        time.Time
+	wroteRequest, firstByte  time.Time
+	remoteAddr               string
+	reused                   bool
+}
+
+// withTrace returns ctx with the collector's trace attached
+func (c *timingCollector) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.events = traceEvents{getConn: time.Now()}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { c.mark(&c.events.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { c.mark(&c.events.dnsDone) },
+		ConnectStart: func(string, string) {
+			// Dual-stack dialing may start several attempts; keep the first
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.events.connectStart.IsZero() {
+				c.events.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				c.mark(&c.events.connectEnd)
+			}
+		},
+		TLSHandshakeStart: func() { c.mark(&c.events.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { c.mark(&c.events.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.events.gotConn = time.Now()
+			c.events.reused = info.Reused
+			if info.Conn != nil {
+				c.events.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { c.mark(&c.events.wroteRequest) },
+		GotFirstResponseByte: func() { c.mark(&c.events.firstByte) },
+	})
+}
+
+// mark sets a timestamp to now
+func (c *timingCollector) mark(t *time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*t = time.Now()
+}
+
+// timing returns the breakdown, with the body fully read at done. A zero
+// done leaves the transfer out, for bodies that are still streaming.
+func (c *timingCollector) timing(done time.Time) *Timing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ev := c.events
+	if ev.getConn.IsZero() {
+		return nil
+	}
+	t := &Timing{
+		DNS:        between(ev.dnsStart, ev.dnsDone),
+		Connect:    between(ev.connectStart, ev.connectEnd),
+		TLS:        between(ev.tlsStart, ev.tlsDone),
+		Send:       between(ev.gotConn, ev.wroteRequest),
+		TTFB:       between(ev.wroteRequest, ev.firstByte),
+		Transfer:   between(ev.firstByte, done),
+		RemoteAddr: ev.remoteAddr,
+		Reused:     ev.reused,
+	}
+	end := done
+	if end.IsZero() {
+		end = ev.firstByte
+	}
+	t.Total = between(ev.getConn, end)
+	return t
+}
+
+// between returns the time from start to end, or 0 if either is missing
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,60 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExecutorTiming(t *testing.T) {
+	server, caFile := newTLSServer(t, t.TempDir(), nil)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+	executor := NewExecutor(5 * time.Second)
+	req := &Request{Method: "GET", URL: server.URL, TLS: &TLSOptions{CAFiles: []string{caFile}}}
+
+	resp, err := executor.Execute(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	timing := resp.Timing
+	if timing == nil {
+		t.Fatal("expected timing")
+	}
+	if timing.Connect <= 0 || timing.TLS <= 0 {
+		t.Errorf("expected connect and TLS phases on a new connection, got %+v", timing)
+	}
+	if timing.TTFB < 20*time.Millisecond || timing.Transfer < 10*time.Millisecond {
+		t.Errorf("expected the server's delays in TTFB and transfer, got %+v", timing)
+	}
+	if timing.Total < timing.Connect+timing.TLS+timing.TTFB+timing.Transfer {
+		t.Errorf("expected the total to cover every phase, got %+v", timing)
+	}
+	if timing.RemoteAddr != server.Listener.Addr().String() || timing.Reused {
+		t.Errorf("expected a new connection to %s, got %+v", server.Listener.Addr(), timing)
+	}
+}
+
+func TestExecutorTimingReusedConnection(t *testing.T) {
+	server := newEchoServer(t)
+	executor := NewExecutor(5 * time.Second)
+	// A private pool, so the first request's connection is the one reused
+	executor.client.Transport = &http.Transport{}
+
+	if _, err := executor.Execute(&Request{Method: "GET", URL: server.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := executor.Execute(&Request{Method: "GET", URL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !resp.Timing.Reused || resp.Timing.Connect != 0 || resp.Timing.DNS != 0 {
+		t.Errorf("expected a reused connection without DNS or connect, got %+v", resp.Timing)
+	}
+}
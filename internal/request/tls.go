@@ -0,0 +1,166 @@
+package request
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gosh/internal/auth"
+)
+
+// TLSOptions configures certificates and verification for HTTPS requests
+type TLSOptions struct {
+	CertFile     string   // Client certificate: PEM, or a .p12/.pfx bundle
+	KeyFile      string   // Client key, when not in CertFile
+	CertPassword string   // Decrypts a PKCS#12 bundle
+	CAFiles      []string // CA bundles trusted in addition to the system roots
+	Insecure     bool     // Skip server certificate verification
+	MinVersion   string   // "1.0", "1.1", "1.2" or "1.3"
+	ServerName   string   // Overrides SNI and the name the certificate is checked against
+}
+
+// tlsVersions maps version names to crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2", "tls1.2" or "TLSv1.3"
+func ParseTLSVersion(version string) (uint16, error) {
+	name := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(version), "tls"), "v")
+	v, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version: %s (expected 1.0, 1.1, 1.2 or 1.3)", version)
+	}
+	return v, nil
+}
+
+// tlsConfig builds the TLS configuration for a request from its options and
+// an mtls auth preset. It returns nil when the defaults apply.
+func tlsConfig(opts *TLSOptions, preset *auth.AuthPreset) (*tls.Config, error) {
+	if opts == nil {
+		opts = &TLSOptions{}
+	}
+	var presetCert *tls.Certificate
+	var caFiles []string
+	if preset != nil {
+		cert, err := preset.ClientCertificate()
+		if err != nil {
+			return nil, err
+		}
+		presetCert = cert
+		caFiles = preset.CAFiles()
+	}
+	caFiles = append(caFiles, opts.CAFiles...)
+
+	if opts.CertFile == "" && presetCert == nil && len(caFiles) == 0 &&
+		!opts.Insecure && opts.MinVersion == "" && opts.ServerName == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: opts.Insecure,
+		ServerName:         opts.ServerName,
+	}
+
+	if opts.MinVersion != "" {
+		v, err := ParseTLSVersion(opts.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = v
+	}
+
+	// A certificate given for the request wins over the preset's
+	switch {
+	case opts.CertFile != "":
+		cert, err := auth.LoadClientCertificate(opts.CertFile, opts.KeyFile, opts.CertPassword)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{*cert}
+	case presetCert != nil:
+		config.Certificates = []tls.Certificate{*presetCert}
+	}
+
+	if len(caFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range caFiles {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no PEM certificates in CA file %s", file)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// NewTransport returns a transport with the given TLS and proxy settings,
+// or nil when the default transport will do. preset may add a client
+// certificate, as mtls presets do.
+func NewTransport(tlsOpts *TLSOptions, proxy *ProxyOptions, preset *auth.AuthPreset) (*http.Transport, error) {
+	config, err := tlsConfig(tlsOpts, preset)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil && proxy == nil {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	if proxy != nil {
+		if transport.Proxy, err = proxyFunc(proxy); err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+// TokenTransport adapts a request's transport for an auth preset's token
+// endpoint, which is usually another host, so an SNI override is dropped.
+// It returns nil for the default transport.
+func TokenTransport(transport *http.Transport) http.RoundTripper {
+	if transport == nil {
+		return nil
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.ServerName == "" {
+		return transport
+	}
+	tokenTransport := transport.Clone()
+	tokenTransport.TLSClientConfig.ServerName = ""
+	return tokenTransport
+}
+
+// clientFor returns the client for a request, with its own transport when
+// the request needs non-default TLS or proxy settings. The auth preset
+// fetches tokens through the same proxy and TLS settings.
+func (e *Executor) clientFor(req *Request) (*http.Client, error) {
+	transport, err := NewTransport(req.TLS, req.Proxy, req.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	client := *e.client
+	client.CheckRedirect = checkRedirect(req)
+	if transport != nil {
+		client.Transport = transport
+	}
+	if req.Auth != nil {
+		req.Auth.SetTransport(TokenTransport(transport))
+	}
+	return &client, nil
+}
@@ -0,0 +1,231 @@
+package request
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gosh/internal/auth"
+)
+
+// writePEMFile writes PEM blocks to a file in dir
+func writePEMFile(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newClientCert creates a self-signed client certificate, returning it
+// parsed and as PEM certificate and key files
+func newClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gosh-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := writePEMFile(t, dir, "client.pem", &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyFile := writePEMFile(t, dir, "client-key.pem", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, certFile, keyFile
+}
+
+// newTLSServer starts an HTTPS server that reports the client certificate,
+// and writes its certificate as a CA file
+func newTLSServer(t *testing.T, dir string, configure func(*tls.Config)) (*httptest.Server, string) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := "none"
+		if len(r.TLS.PeerCertificates) > 0 {
+			client = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		fmt.Fprintf(w, "client=%s", client)
+	}))
+	server.TLS = &tls.Config{}
+	if configure != nil {
+		configure(server.TLS)
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := writePEMFile(t, dir, "server-ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return server, caFile
+}
+
+func TestExecutorTLSVerification(t *testing.T) {
+	dir := t.TempDir()
+	server, caFile := newTLSServer(t, dir, nil)
+	executor := NewExecutor(5 * time.Second)
+
+	tests := []struct {
+		name    string
+		tls     *TLSOptions
+		wantErr bool
+	}{
+		{"untrusted by default", nil, true},
+		{"insecure", &TLSOptions{Insecure: true}, false},
+		{"extra CA", &TLSOptions{CAFiles: []string{caFile}}, false},
+		{"server name in certificate", &TLSOptions{CAFiles: []string{caFile}, ServerName: "example.com"}, false},
+		{"server name not in certificate", &TLSOptions{CAFiles: []string{caFile}, ServerName: "other.test"}, true},
+	}
+
+	for _, tt := range tests {
+		resp, err := executor.Execute(&Request{Method: "GET", URL: server.URL, TLS: tt.tls})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+			continue
+		}
+		if string(resp.Body) != "client=none" {
+			t.Errorf("%s: unexpected body %q", tt.name, resp.Body)
+		}
+	}
+}
+
+func TestExecutorTLSMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	server, caFile := newTLSServer(t, dir, func(c *tls.Config) { c.MaxVersion = tls.VersionTLS12 })
+	executor := NewExecutor(5 * time.Second)
+
+	if _, err := executor.Execute(&Request{Method: "GET", URL: server.URL, TLS: &TLSOptions{CAFiles: []string{caFile}, MinVersion: "1.2"}}); err != nil {
+		t.Errorf("expected TLS 1.2 to connect, got %v", err)
+	}
+	if _, err := executor.Execute(&Request{Method: "GET", URL: server.URL, TLS: &TLSOptions{CAFiles: []string{caFile}, MinVersion: "1.3"}}); err == nil {
+		t.Error("expected error when the server doesn't support the minimum version")
+	}
+}
+
+func TestExecutorClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := newClientCert(t, dir)
+	server, caFile := newTLSServer(t, dir, func(c *tls.Config) {
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		c.ClientCAs = x509.NewCertPool()
+		c.ClientCAs.AddCert(clientCert)
+	})
+	executor := NewExecutor(5 * time.Second)
+
+	if _, err := executor.Execute(&Request{Method: "GET", URL: server.URL, TLS: &TLSOptions{CAFiles: []string{caFile}}}); err == nil {
+		t.Error("expected error without a client certificate")
+	}
+
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    server.URL,
+		TLS:    &TLSOptions{CertFile: certFile, KeyFile: keyFile, CAFiles: []string{caFile}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(resp.Body) != "client=gosh-client" {
+		t.Errorf("expected the client certificate, got %q", resp.Body)
+	}
+
+	// The same certificate from an mtls preset, with its CA
+	resp, err = executor.Execute(&Request{
+		Method: "GET",
+		URL:    server.URL,
+		Auth:   &auth.AuthPreset{Type: "mtls", CertFile: certFile, KeyFile: keyFile, CAFile: caFile},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(resp.Body) != "client=gosh-client" {
+		t.Errorf("expected the preset's client certificate, got %q", resp.Body)
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	for input, expected := range map[string]uint16{"1.2": tls.VersionTLS12, "TLSv1.3": tls.VersionTLS13, "tls1.0": tls.VersionTLS10} {
+		if v, err := ParseTLSVersion(input); err != nil || v != expected {
+			t.Errorf("%s: expected %x, got %x (%v)", input, expected, v, err)
+		}
+	}
+	if _, err := ParseTLSVersion("2.0"); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+// TestExecutorOAuth2TokenTransport tests that token requests use the
+// request's CA bundle and proxy
+func TestExecutorOAuth2TokenTransport(t *testing.T) {
+	dir := t.TempDir()
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"tok-1","token_type":"bearer"}`)
+	}))
+	t.Cleanup(tokenServer.Close)
+	caFile := writePEMFile(t, dir, "token-ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: tokenServer.Certificate().Raw})
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(api.Close)
+	proxy, proxied := newHTTPProxy(t, "", "")
+
+	preset := &auth.AuthPreset{Name: "api", Type: "oauth2", TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}
+	executor := NewExecutor(5 * time.Second)
+	resp, err := executor.Execute(&Request{
+		Method: "GET",
+		URL:    api.URL,
+		Auth:   preset,
+		TLS:    &TLSOptions{CAFiles: []string{caFile}, ServerName: "api.internal"},
+		Proxy:  &ProxyOptions{URL: proxy.URL},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Body) != "Bearer tok-1" {
+		t.Errorf("expected the fetched token, got %q", resp.Body)
+	}
+	// The token request is tunnelled, then the API request is forwarded
+	if got := atomic.LoadInt32(proxied); got != 2 {
+		t.Errorf("expected both requests through the proxy, got %d", got)
+	}
+}
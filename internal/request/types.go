@@ -1,27 +1,72 @@
 package request
 
 import (
-	"github.com/gosh/internal/auth"
+	"net/http"
 	"time"
+
+	"github.com/gosh/internal/auth"
 )
 
 // Request holds HTTP request details
 type Request struct {
-	Method      string
-	URL         string
-	Headers     map[string]string
-	QueryParams map[string]string
-	Body        string
-	Timeout     time.Duration
-	Auth        *auth.AuthPreset
+	Method       string
+	URL          string
+	Headers      map[string]string
+	QueryParams  map[string]string
+	Body         string
+	Items        []Item // Data items encoded into the body
+	Form         bool   // Encode items as form fields instead of JSON
+	Multipart    bool   // Stream items as multipart/form-data
+	Timeout      time.Duration
+	Auth         *auth.AuthPreset
+	TLS          *TLSOptions   // Client certificates, CAs and verification; nil for defaults
+	NoFollow     bool          // Return redirect responses instead of following them
+	MaxRedirects int           // Redirects to follow before giving up; 0 for DefaultMaxRedirects
+	Retry        *RetryPolicy  // Retries for failed attempts; nil sends once
+	Proxy        *ProxyOptions // Proxy to route through; nil uses HTTP_PROXY and friends
+	Wire         WireObserver  // Sees every request and response as sent; nil for none
+}
+
+// WireObserver is shown each request just before it is sent and each
+// response once its headers arrive, including redirect hops, retried
+// attempts and replies to auth challenges. Implementations read request
+// bodies through GetBody, if at all, and must not read response bodies.
+type WireObserver interface {
+	Request(*http.Request)
+	Response(*http.Response)
 }
 
 // Response holds the HTTP response
 type Response struct {
 	StatusCode int
 	Status     string
+	Proto      string
 	Headers    map[string][]string
 	Body       []byte
 	Duration   time.Duration
 	Size       int // Size in bytes
+	StartedAt  time.Time
+	Request    *SentRequest
+	Redirects  []Redirect // Hops followed before this response, in order
+	Attempts   []Attempt  // Every attempt, the last one produced this response
+	Timing     *Timing    // Phase breakdown of the final request; nil if unavailable
+}
+
+// Redirect is one redirect response that was followed
+type Redirect struct {
+	Method     string // Method of the request that was redirected
+	URL        string // URL of the request that was redirected
+	StatusCode int
+	Status     string
+	Location   string
+}
+
+// SentRequest records the request as it was sent, after items, query
+// parameters and authentication were applied
+type SentRequest struct {
+	Method  string
+	URL     string
+	Proto   string
+	Headers map[string][]string
+	Body    []byte // nil when the body was streamed, as with --multipart
 }
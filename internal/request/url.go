@@ -0,0 +1,48 @@
+package request
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// absoluteURL matches a URL that starts with a scheme
+var absoluteURL = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+// hostPort matches a host and port without a scheme, e.g. localhost:8080/users
+var hostPort = regexp.MustCompile(`^[^/?#:]+:[0-9]+(?:[/?#]|$)`)
+
+// IsRelativeURL reports whether rawURL is a path without scheme and host.
+// A "://" later on, as in "/login?next=http://x", doesn't make it absolute.
+func IsRelativeURL(rawURL string) bool {
+	return !absoluteURL.MatchString(rawURL)
+}
+
+// JoinURL resolves a relative request path against a base URL.
+// Absolute URLs are returned unchanged. Unlike RFC 3986 resolution, the
+// base path is always kept, so "/users" against "https://host/v1" yields
+// "https://host/v1/users".
+func JoinURL(baseURL, rawURL string) (string, error) {
+	if !IsRelativeURL(rawURL) {
+		return rawURL, nil
+	}
+	if hostPort.MatchString(rawURL) {
+		return "", fmt.Errorf("URL %q has no scheme; use http://%s or a path starting with /", rawURL, rawURL)
+	}
+	if baseURL == "" {
+		return "", fmt.Errorf("relative URL %q requires baseUrl in .gosh.yaml", rawURL)
+	}
+	if IsRelativeURL(baseURL) {
+		return "", fmt.Errorf("baseUrl must be an absolute URL: %s", baseURL)
+	}
+
+	base := strings.TrimRight(baseURL, "/")
+	path := strings.TrimLeft(rawURL, "/")
+	if path == "" {
+		return base, nil
+	}
+	if strings.HasPrefix(path, "?") {
+		return base + path, nil
+	}
+	return base + "/" + path, nil
+}
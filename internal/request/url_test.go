@@ -0,0 +1,81 @@
+package request
+
+import (
+	"testing"
+)
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		base     string
+		path     string
+		expected string
+	}{
+		{"https://api.example.com", "/users", "https://api.example.com/users"},
+		{"https://api.example.com/", "/users", "https://api.example.com/users"},
+		{"https://api.example.com/v1", "/users/{id}", "https://api.example.com/v1/users/{id}"},
+		{"https://api.example.com/v1", "users", "https://api.example.com/v1/users"},
+		{"https://api.example.com/v1", "/", "https://api.example.com/v1"},
+		{"https://api.example.com", "?page=2", "https://api.example.com?page=2"},
+		{"https://api.example.com", "https://other.example.com/x", "https://other.example.com/x"},
+		{"", "https://other.example.com/x", "https://other.example.com/x"},
+		// A URL in the query string doesn't make the path absolute
+		{"https://api.example.com", "/login?next=http://x", "https://api.example.com/login?next=http://x"},
+		{"https://api.example.com", "?redirect=https://other.example.com/cb", "https://api.example.com?redirect=https://other.example.com/cb"},
+		{"https://api.example.com", "/proxy/https://other.example.com", "https://api.example.com/proxy/https://other.example.com"},
+		{"", "HTTP://Other.example.com/x", "HTTP://Other.example.com/x"},
+		{"", "svc+https://other.example.com/x", "svc+https://other.example.com/x"},
+		{"https://api.example.com", "/users:search", "https://api.example.com/users:search"},
+	}
+
+	for _, tt := range tests {
+		result, err := JoinURL(tt.base, tt.path)
+		if err != nil {
+			t.Fatalf("JoinURL(%q, %q): unexpected error: %v", tt.base, tt.path, err)
+		}
+		if result != tt.expected {
+			t.Errorf("JoinURL(%q, %q): got %q, want %q", tt.base, tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestJoinURLMissingBase(t *testing.T) {
+	if _, err := JoinURL("", "/users"); err == nil {
+		t.Error("expected error for relative URL without base")
+	}
+}
+
+func TestJoinURLRelativeBase(t *testing.T) {
+	if _, err := JoinURL("api.example.com", "/users"); err == nil {
+		t.Error("expected error for base URL without scheme")
+	}
+}
+
+func TestJoinURLHostPort(t *testing.T) {
+	for _, path := range []string{"localhost:8080", "localhost:8080/users", "api.example.com:443?x=1", "10.0.0.1:3000#top"} {
+		if _, err := JoinURL("https://api.example.com", path); err == nil {
+			t.Errorf("JoinURL(%q): expected error for host:port without a scheme", path)
+		}
+	}
+}
+
+func TestIsRelativeURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		relative bool
+	}{
+		{"/users", true},
+		{"users?next=http://x", true},
+		{"?next=https://other.example.com", true},
+		{"localhost:8080/users", true},
+		{"://missing-scheme", true},
+		{"1http://x", true},
+		{"http://x", false},
+		{"https://api.example.com/users?next=/x", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRelativeURL(tt.url); got != tt.relative {
+			t.Errorf("IsRelativeURL(%q): got %v, want %v", tt.url, got, tt.relative)
+		}
+	}
+}
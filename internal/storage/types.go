@@ -10,6 +10,10 @@ type SavedCall struct {
 	Headers     map[string]string `yaml:"headers"`
 	QueryParams map[string]string `yaml:"queryParams"`
 	Body        string            `yaml:"body"`
+	Items       []string          `yaml:"items,omitempty"`     // Data items in CLI syntax (name=value, age:=42)
+	Form        bool              `yaml:"form,omitempty"`      // Encode items as form fields
+	Multipart   bool              `yaml:"multipart,omitempty"` // Stream items as multipart/form-data
+	Auth        string            `yaml:"auth,omitempty"`      // Auth preset name, resolved when recalled
 	Description string            `yaml:"description"`
 	CreatedAt   string            `yaml:"createdAt"`
 }
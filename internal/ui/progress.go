@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// progressBarWidth is the number of cells in the progress bar
+const progressBarWidth = 30
+
+// progressMsg carries the total number of bytes written so far
+type progressMsg int64
+
+// progressDoneMsg signals that the transfer has finished
+type progressDoneMsg struct{}
+
+// ProgressModel is a bubbletea model rendering transfer progress
+type ProgressModel struct {
+	title   string
+	total   int64 // -1 when the size is unknown
+	written int64
+	done    bool
+}
+
+// Init initializes the model
+func (m *ProgressModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (m *ProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case progressMsg:
+		m.written = int64(msg)
+	case progressDoneMsg:
+		m.done = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// View renders the model
+func (m *ProgressModel) View() string {
+	if m.total <= 0 {
+		return fmt.Sprintf("%s %s\n", m.title, FormatBytes(m.written))
+	}
+
+	written := m.written
+	if written > m.total {
+		written = m.total
+	}
+	filled := int(written * progressBarWidth / m.total)
+	percent := int(written * 100 / m.total)
+
+	return fmt.Sprintf("%s [%s%s] %3d%% %s / %s\n",
+		m.title,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		percent,
+		FormatBytes(written),
+		FormatBytes(m.total),
+	)
+}
+
+// Progress reports bytes written to a bubbletea progress bar.
+// It implements io.Writer so it can be combined with io.MultiWriter.
+type Progress struct {
+	program *tea.Program
+	written int64
+	done    chan struct{}
+}
+
+// StartProgress renders a progress bar to out until Finish is called.
+// total is the expected size (-1 if unknown) and offset the bytes already
+// present, for resumed transfers.
+func StartProgress(out io.Writer, title string, total, offset int64) *Progress {
+	model := &ProgressModel{title: title, total: total, written: offset}
+	program := tea.NewProgram(model,
+		tea.WithOutput(out),
+		tea.WithInput(nil),
+		tea.WithoutSignalHandler(),
+	)
+
+	p := &Progress{
+		program: program,
+		written: offset,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		_, _ = program.Run()
+		close(p.done)
+	}()
+	return p
+}
+
+// Write records transferred bytes
+func (p *Progress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	p.program.Send(progressMsg(p.written))
+	return len(b), nil
+}
+
+// Finish renders the final state and stops the progress bar
+func (p *Progress) Finish() {
+	p.program.Send(progressDoneMsg{})
+	<-p.done
+}
+
+// FormatBytes formats a byte count using binary units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestProgressModelView tests rendering with a known total
+func TestProgressModelView(t *testing.T) {
+	model := &ProgressModel{title: "file.bin", total: 2048, written: 1024}
+
+	view := model.View()
+	if !strings.Contains(view, "file.bin") {
+		t.Error("expected title in view")
+	}
+	if !strings.Contains(view, " 50%") {
+		t.Errorf("expected 50%% in view, got %q", view)
+	}
+	if !strings.Contains(view, "1.0 KiB / 2.0 KiB") {
+		t.Errorf("expected sizes in view, got %q", view)
+	}
+}
+
+// TestProgressModelViewUnknownTotal tests rendering without a known size
+func TestProgressModelViewUnknownTotal(t *testing.T) {
+	model := &ProgressModel{title: "stream", total: -1, written: 10}
+
+	view := model.View()
+	if strings.Contains(view, "%") {
+		t.Errorf("expected no percentage without a total, got %q", view)
+	}
+	if !strings.Contains(view, "10 B") {
+		t.Errorf("expected bytes written in view, got %q", view)
+	}
+}
+
+// TestProgressModelUpdate tests progress and done messages
+func TestProgressModelUpdate(t *testing.T) {
+	model := &ProgressModel{title: "file", total: 100}
+
+	_, cmd := model.Update(progressMsg(40))
+	if model.written != 40 || cmd != nil {
+		t.Errorf("expected written=40 and no command, got %d", model.written)
+	}
+
+	_, cmd = model.Update(progressDoneMsg{})
+	if !model.done || cmd == nil {
+		t.Error("expected done and a quit command")
+	}
+}
+
+// TestProgressWriteAndFinish tests running the progress program to completion
+func TestProgressWriteAndFinish(t *testing.T) {
+	progress := StartProgress(io.Discard, "file", 10, 0)
+	if _, err := progress.Write([]byte("12345")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	progress.Finish()
+
+	if progress.written != 5 {
+		t.Errorf("expected 5 bytes written, got %d", progress.written)
+	}
+}
+
+// TestFormatBytes tests human-readable sizes
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                  "0 B",
+		1023:               "1023 B",
+		1024:               "1.0 KiB",
+		1536:               "1.5 KiB",
+		5 * 1024 * 1024:    "5.0 MiB",
+		3 * 1024 * 1 << 30: "3.0 TiB",
+	}
+	for n, expected := range tests {
+		if got := FormatBytes(n); got != expected {
+			t.Errorf("FormatBytes(%d): got %q, want %q", n, got, expected)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
 )
 
 // PromptModel is a simple text input model for bubbletea
@@ -60,6 +61,43 @@ func PromptForVariable(varName string) (string, error) {
 	return strings.TrimSpace(input), nil
 }
 
+// PromptPassword prompts on stderr for a secret without echoing it. Piped
+// input is read as a plain line.
+func PromptPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		input, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(input), nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil && input == "" {
+		return "", err
+	}
+	return strings.TrimRight(input, "\r\n"), nil
+}
+
+// Confirm asks a yes/no question on stderr and reads the answer from
+// stdin. Anything but "y" or "yes" is a no.
+func Confirm(prompt string) (bool, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil && input == "" {
+		return false, err
+	}
+	answer := strings.ToLower(strings.TrimSpace(input))
+	return answer == "y" || answer == "yes", nil
+}
+
 // PromptInteractively prompts for multiple variables using bubbletea
 func PromptInteractively(variables []string) (map[string]string, error) {
 	result := make(map[string]string)
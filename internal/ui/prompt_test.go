@@ -481,3 +481,25 @@ func TestPromptForVariableSpecialCharacters(t *testing.T) {
 func contains(str, substr string) bool {
 	return bytes.Contains([]byte(str), []byte(substr))
 }
+
+// TestPromptPasswordPiped tests reading a secret from piped stdin
+func TestPromptPasswordPiped(t *testing.T) {
+	inR, inW, _ := os.Pipe()
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+	os.Stdin = inR
+
+	go func() {
+		_, _ = inW.WriteString(" pass phrase \n")
+		inW.Close()
+	}()
+
+	secret, err := PromptPassword("Passphrase: ")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Whitespace is part of the secret
+	if secret != " pass phrase " {
+		t.Errorf("expected ' pass phrase ', got %q", secret)
+	}
+}